@@ -12,3 +12,8 @@ require (
 	github.com/larry868/rgb v1.1.0
 	github.com/larry868/timeline/v2 v2.5.0
 )
+
+require (
+	golang.org/x/image v0.24.0
+	golang.org/x/text v0.22.0 // indirect
+)
@@ -0,0 +1,91 @@
+package stockchart
+
+import (
+	"math"
+	"time"
+
+	"github.com/larry868/datarange"
+)
+
+// decimate merges the consecutive data points falling into the same pixel column,
+// so the number of data points to draw is bounded by the width of the drawing area.
+//
+// origin is the time at the left of the drawing area, and xfactor the number of pixels per nanosecond.
+// Merged data points get the Open of the first one, the Close of the last one, the highest High, the lowest Low and the sum of Volumes.
+// They're not linked to the DataList.
+//
+// items is returned unchanged if there's nothing to merge.
+func decimate(items []*DataStock, origin time.Time, xfactor float64) []*DataStock {
+	return decimateSpan(items, origin, xfactor, func(from time.Time, to time.Time) time.Duration {
+		return to.Sub(from)
+	})
+}
+
+// decimateSpan is like decimate along an x axis where span returns the distance between two times, like Layer.xSpan.
+// xfactor is the number of pixels per nanosecond of span.
+func decimateSpan(items []*DataStock, origin time.Time, xfactor float64, span func(from time.Time, to time.Time) time.Duration) []*DataStock {
+	column := func(item *DataStock) float64 {
+		return math.Floor(xfactor * float64(span(origin, item.From)))
+	}
+	mergeable := func(item *DataStock) bool {
+		return !item.IsInfinite() && item.Duration().Duration > 0
+	}
+
+	// look for the first data points to merge
+	first := -1
+	for i := 1; i < len(items); i++ {
+		if mergeable(items[i-1]) && mergeable(items[i]) && column(items[i-1]) == column(items[i]) {
+			first = i - 1
+			break
+		}
+	}
+	if first < 0 {
+		return items
+	}
+
+	capacity := first + int(xfactor*float64(span(items[first].From, items[len(items)-1].To))) + 2
+	decimated := make([]*DataStock, first, max(first, capacity))
+	copy(decimated, items[:first])
+	var merged *DataStock
+	var mergedcol float64
+	for _, item := range items[first:] {
+		if !mergeable(item) {
+			decimated = append(decimated, item)
+			merged = nil
+			continue
+		}
+		col := column(item)
+		if merged != nil && col == mergedcol {
+			merged.To = item.To
+			merged.Close = item.Close
+			merged.High = math.Max(merged.High, item.High)
+			merged.Low = minNotZero(merged.Low, item.Low)
+			merged.Volume += item.Volume
+			if merged.HasPatterns == 0 {
+				merged.HasPatterns = item.HasPatterns
+			}
+			continue
+		}
+		merged = &DataStock{
+			Label:       item.Label,
+			TimeSlice:   item.TimeSlice,
+			Open:        item.Open,
+			Low:         item.Low,
+			High:        item.High,
+			Close:       item.Close,
+			Volume:      item.Volume,
+			HasPatterns: item.HasPatterns}
+		mergedcol = col
+		decimated = append(decimated, merged)
+	}
+	return decimated
+}
+
+// decimatedVolumeRange returns the volume boundaries of decimated data points
+func decimatedVolumeRange(items []*DataStock) datarange.DataRange {
+	e := noExtremes
+	for _, item := range items {
+		e = e.merge(makeExtremes(item))
+	}
+	return datarange.Make(e.vlow, math.Max(0, e.vhigh), 0, "")
+}
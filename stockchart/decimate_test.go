@@ -0,0 +1,60 @@
+package stockchart
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecimate(t *testing.T) {
+	series := testSeries(1000)
+	items := series.Window(series.TimeSlice())
+	origin := series.Tail.From
+
+	// wide enough, nothing to merge
+	xfactor := 2000 / float64(series.TimeSlice().Duration().Duration)
+	if get := decimate(items, origin, xfactor); len(get) != 1000 || get[0] != series.Tail {
+		t.Fatalf("decimate fails: want the items unchanged, get %d items", len(get))
+	}
+
+	// 10 candles per pixel
+	xfactor = 100 / float64(series.TimeSlice().Duration().Duration)
+	get := decimate(items, origin, xfactor)
+	if len(get) != 100 {
+		t.Fatalf("decimate fails: want 100 items, get %d", len(get))
+	}
+	for col, merged := range get {
+		group := items[col*10 : col*10+10]
+		var volume float64
+		low, high := group[0].Low, group[0].High
+		for _, item := range group {
+			volume += item.Volume
+			low = min(low, item.Low)
+			high = max(high, item.High)
+		}
+		if !merged.From.Equal(group[0].From) || !merged.To.Equal(group[9].To) || merged.Duration().Duration != 10*time.Hour {
+			t.Fatalf("decimate column %d fails: get %s", col, merged.TimeSlice)
+		}
+		if merged.Open != group[0].Open || merged.Close != group[9].Close || merged.Low != low || merged.High != high || merged.Volume != volume {
+			t.Fatalf("decimate column %d fails: get %s", col, merged)
+		}
+	}
+
+	// the series is unchanged
+	if series.Size() != 1000 || series.Tail.Volume != 100 {
+		t.Errorf("decimate fails: the series has changed")
+	}
+}
+
+func TestDecimateDrawCalls(t *testing.T) {
+	chart, _ := newTestChart(testSeriesPrecision(20000, time.Minute))
+
+	// at most a body and a wick per pixel column of the chart, plus a bar per column
+	width := chart.layers[4].ClipArea.Width
+	if get := chart.recorder(4).Count("FillRect"); get > 4*width {
+		t.Errorf("chart layer fails: want at most %d FillRect, get %d", 4*width, get)
+	}
+	// a point per column, plus the closing of the area
+	if get := chart.recorder(1).Count("LineTo"); get > width+5 {
+		t.Errorf("navbar layer fails: want at most %d LineTo, get %d", width+5, get)
+	}
+}
@@ -5,9 +5,6 @@ import (
 	"math"
 	"time"
 
-	"github.com/gowebapi/webapi/core/js"
-	"github.com/gowebapi/webapi/html/canvas"
-	"github.com/gowebapi/webapi/html/htmlevent"
 	"github.com/larry868/rgb"
 	timeline "github.com/larry868/timeline/v2"
 )
@@ -27,14 +24,16 @@ type Drawing struct {
 	// optional functions to be defined by upper drawings
 	DrawArea func(clipArea Rect) Rect
 
-	OnMouseDown  func(xy Point, event *htmlevent.MouseEvent)
-	OnMouseUp    func(xy Point, event *htmlevent.MouseEvent)
-	OnMouseMove  func(xy Point, event *htmlevent.MouseEvent)
-	OnMouseEnter func(xy Point, event *htmlevent.MouseEvent)
-	OnMouseLeave func(xy Point, event *htmlevent.MouseEvent)
-	OnWheel      func(event *htmlevent.WheelEvent)
-	OnClick      func(xy Point, event *htmlevent.MouseEvent)
+	OnMouseDown  func(xy Point, event *MouseEvent)
+	OnMouseUp    func(xy Point, event *MouseEvent)
+	OnMouseMove  func(xy Point, event *MouseEvent)
+	OnMouseEnter func(xy Point, event *MouseEvent)
+	OnMouseLeave func(xy Point, event *MouseEvent)
+	OnWheel      func(event *WheelEvent)
+	OnClick      func(xy Point, event *MouseEvent)
 	NeedRedraw   func() bool
+	OnChangeData func(data *DataStock)                                   // data of the MainSeries has been appended or updated in place
+	YExtremes    func(ts timeline.TimeSlice) (low float64, high float64) // the extremes within ts to include in the y autoscale of the chart, NaN if none
 }
 
 func (drawing Drawing) hasNonEmptySeries() bool {
@@ -65,7 +64,7 @@ func (drawing *Drawing) ResetSubSeries(series *DataList, redrawNow bool) {
 	drawing.series = series
 
 	if redrawNow {
-		drawing.Redraw()
+		drawing.Invalidate()
 	}
 }
 
@@ -82,8 +81,8 @@ func (drawing *Drawing) DrawTextBox(txt string, xy Point, align Align, backgroun
 	// size and position
 	tm := drawing.Ctx2D.MeasureText(txt)
 	bgbox := Rect{
-		Width:  int(tm.Width()) + 2*(margin+border+padding),
-		Height: int(tm.ActualBoundingBoxAscent()+tm.ActualBoundingBoxDescent()) + 2*(margin+border+padding)}
+		Width:  int(tm.Width) + 2*(margin+border+padding),
+		Height: int(tm.Ascent+tm.Descent) + 2*(margin+border+padding)}
 
 	// pixel perfect
 	halfpix := 0.0
@@ -93,34 +92,34 @@ func (drawing *Drawing) DrawTextBox(txt string, xy Point, align Align, backgroun
 
 	// X axis
 	if (align & AlignStart) > 0 {
-		drawing.Ctx2D.SetTextAlign(canvas.StartCanvasTextAlign)
+		drawing.Ctx2D.SetTextAlign(TA_Start)
 		bgbox.O.X = xy.X
 		postxt.X = margin + border + padding
 
 	} else if (align & AlignEnd) > 0 {
-		drawing.Ctx2D.SetTextAlign(canvas.EndCanvasTextAlign)
+		drawing.Ctx2D.SetTextAlign(TA_End)
 		bgbox.O.X = xy.X - bgbox.Width - 1
 		postxt.X = bgbox.Width + 1 - (+margin + border + padding)
 
 	} else {
-		drawing.Ctx2D.SetTextAlign(canvas.CenterCanvasTextAlign)
+		drawing.Ctx2D.SetTextAlign(TA_Center)
 		bgbox.O.X = xy.X - bgbox.Width/2
 		postxt.X = bgbox.Width / 2
 	}
 
 	// Y axis
 	if (align & AlignTop) > 0 {
-		drawing.Ctx2D.SetTextBaseline(canvas.TopCanvasTextBaseline)
+		drawing.Ctx2D.SetTextBaseline(TB_Top)
 		bgbox.O.Y = xy.Y
 		postxt.Y = margin + border + padding
 
 	} else if (align & AlignBottom) > 0 {
-		drawing.Ctx2D.SetTextBaseline(canvas.BottomCanvasTextBaseline)
+		drawing.Ctx2D.SetTextBaseline(TB_Bottom)
 		bgbox.O.Y = xy.Y + 1 - int(2.0*halfpix) - bgbox.Height
 		postxt.Y = +bgbox.Height - (margin + border + padding)
 
 	} else {
-		drawing.Ctx2D.SetTextBaseline(canvas.MiddleCanvasTextBaseline)
+		drawing.Ctx2D.SetTextBaseline(TB_Middle)
 		bgbox.O.Y = xy.Y - bgbox.Height/2
 		postxt.Y = bgbox.Height / 2
 	}
@@ -134,28 +133,29 @@ func (drawing *Drawing) DrawTextBox(txt string, xy Point, align Align, backgroun
 	txtbox := bgbox.Shrink(margin+border/2.0, margin+border/2.0)
 
 	// draw the box and its frame
-	drawing.Ctx2D.SetFillStyle(&canvas.Union{Value: js.ValueOf(rgb.None.Hexa())})
+	drawing.Ctx2D.SetFillStyle(rgb.None)
 	drawing.Ctx2D.FillRect(float64(bgbox.O.X), float64(bgbox.O.Y), float64(bgbox.Width), float64(bgbox.Height))
-	drawing.Ctx2D.SetFillStyle(&canvas.Union{Value: js.ValueOf(backgroundcolor.Hexa())})
+	drawing.Ctx2D.SetFillStyle(backgroundcolor)
 	drawing.Ctx2D.FillRect(float64(txtbox.O.X)-halfpix, float64(txtbox.O.Y)-halfpix, float64(txtbox.Width), float64(txtbox.Height))
 	if border > 0 {
-		drawing.Ctx2D.SetStrokeStyle(&canvas.Union{Value: js.ValueOf(textcolor.Hexa())})
+		drawing.Ctx2D.SetStrokeStyle(textcolor)
 		drawing.Ctx2D.SetLineWidth(float64(border))
 		drawing.Ctx2D.StrokeRect(float64(txtbox.O.X)-halfpix, float64(txtbox.O.Y)-halfpix, float64(txtbox.Width), float64(txtbox.Height))
 	}
 
 	// draw the text
-	drawing.Ctx2D.SetFillStyle(&canvas.Union{Value: js.ValueOf(textcolor.Hexa())})
-	drawing.Ctx2D.FillText(txt, float64(postxt.X), float64(postxt.Y), nil)
+	drawing.Ctx2D.SetFillStyle(textcolor)
+	drawing.Ctx2D.FillText(txt, float64(postxt.X), float64(postxt.Y))
 
 	return bgbox
 }
 
-// return the x position of a specific time withing the drawing area and accoring to the xAxisRange
+// return the x position of a specific time withing the drawing area and accoring to the xAxisRange.
+// With a trading calendar, the position is driven by the trading time.
 func (drawing *Drawing) xTime(at time.Time) (xpos float64) {
 
-	dx := float64(at.Sub(drawing.xAxisRange.From))
-	drange := float64(drawing.xAxisRange.Duration().Duration)
+	dx := float64(drawing.xSpan(drawing.xAxisRange.From, at))
+	drange := float64(drawing.xSpan(drawing.xAxisRange.From, drawing.xAxisRange.To))
 	f := math.Round(float64(drawing.drawArea.Width) * dx / drange)
 	return float64(drawing.drawArea.O.X) + f
 }
@@ -168,7 +168,7 @@ func (drawing *Drawing) DrawVLine(at time.Time, color rgb.Color, full bool) (xpo
 
 		xpos = drawing.xTime(at)
 
-		drawing.Ctx2D.SetStrokeStyle(&canvas.Union{Value: js.ValueOf(color.Hexa())})
+		drawing.Ctx2D.SetStrokeStyle(color)
 		drawing.Ctx2D.SetLineWidth(1)
 		drawing.Ctx2D.SetLineDash([]float64{})
 
@@ -187,3 +187,100 @@ func (drawing *Drawing) DrawVLine(at time.Time, color rgb.Color, full bool) (xpo
 
 	return xpos
 }
+
+// drawLine draws the line joining the values at the middle of items, clipped to the drawing area.
+// The line is interrupted on NaN values, and only the last value of items sharing the same pixel column is drawn.
+// yvalue returns the y position of a value.
+func (drawing *Drawing) drawLine(items []*DataStock, values []float64, color rgb.Color, width float64, yvalue func(v float64) float64) {
+	drawing.Ctx2D.Save()
+	drawing.Ctx2D.BeginPath()
+	drawing.Ctx2D.Rect(float64(drawing.drawArea.O.X), float64(drawing.drawArea.O.Y), float64(drawing.drawArea.Width), float64(drawing.drawArea.Height))
+	drawing.Ctx2D.Clip()
+
+	drawing.Ctx2D.SetStrokeStyle(color)
+	drawing.Ctx2D.SetLineWidth(width)
+	drawing.Ctx2D.SetLineDash([]float64{})
+	drawing.Ctx2D.SetLineJoin(LJ_Round)
+	drawing.Ctx2D.BeginPath()
+	started := false
+	for i, item := range items {
+		v := values[i]
+		if math.IsNaN(v) || item.IsInfinite() {
+			started = false
+			continue
+		}
+		x := drawing.xTime(item.Middle())
+		if i+1 < len(items) && !math.IsNaN(values[i+1]) && drawing.xTime(items[i+1].Middle()) == x {
+			continue
+		}
+		if !started {
+			drawing.Ctx2D.MoveTo(x, yvalue(v))
+			started = true
+		} else {
+			drawing.Ctx2D.LineTo(x, yvalue(v))
+		}
+	}
+	drawing.Ctx2D.Stroke()
+	drawing.Ctx2D.Restore()
+}
+
+// drawLevel draws a dashed horizontal line across the drawing area at the y position of value.
+// yvalue returns the y position of a value.
+func (drawing *Drawing) drawLevel(value float64, color rgb.Color, yvalue func(v float64) float64) {
+	y := math.Round(yvalue(value)) + 0.5
+	if y < float64(drawing.drawArea.O.Y) || y > float64(drawing.drawArea.End().Y) {
+		return
+	}
+	drawing.Ctx2D.SetStrokeStyle(color)
+	drawing.Ctx2D.SetLineWidth(1)
+	drawing.Ctx2D.SetLineDash([]float64{3, 3})
+	drawing.Ctx2D.BeginPath()
+	drawing.Ctx2D.MoveTo(float64(drawing.drawArea.O.X), y)
+	drawing.Ctx2D.LineTo(float64(drawing.drawArea.End().X), y)
+	drawing.Ctx2D.Stroke()
+	drawing.Ctx2D.SetLineDash([]float64{})
+}
+
+// drawBand fills the area between the upper and the lower values at the middle of items, clipped to the drawing area.
+// The band is interrupted on NaN values, and only the last values of items sharing the same pixel column are drawn.
+// yvalue returns the y position of a value.
+func (drawing *Drawing) drawBand(items []*DataStock, upper []float64, lower []float64, color rgb.Color, yvalue func(v float64) float64) {
+	drawing.Ctx2D.Save()
+	drawing.Ctx2D.BeginPath()
+	drawing.Ctx2D.Rect(float64(drawing.drawArea.O.X), float64(drawing.drawArea.O.Y), float64(drawing.drawArea.Width), float64(drawing.drawArea.Height))
+	drawing.Ctx2D.Clip()
+	drawing.Ctx2D.SetFillStyle(color)
+
+	type point struct{ x, yupper, ylower float64 }
+	fill := func(segment []point) {
+		if len(segment) < 2 {
+			return
+		}
+		drawing.Ctx2D.BeginPath()
+		drawing.Ctx2D.MoveTo(segment[0].x, segment[0].yupper)
+		for _, p := range segment[1:] {
+			drawing.Ctx2D.LineTo(p.x, p.yupper)
+		}
+		for i := len(segment) - 1; i >= 0; i-- {
+			drawing.Ctx2D.LineTo(segment[i].x, segment[i].ylower)
+		}
+		drawing.Ctx2D.ClosePath()
+		drawing.Ctx2D.Fill()
+	}
+
+	var segment []point
+	for i, item := range items {
+		if math.IsNaN(upper[i]) || math.IsNaN(lower[i]) || item.IsInfinite() {
+			fill(segment)
+			segment = segment[:0]
+			continue
+		}
+		x := drawing.xTime(item.Middle())
+		if n := len(segment); n > 0 && segment[n-1].x == x {
+			segment = segment[:n-1]
+		}
+		segment = append(segment, point{x: x, yupper: yvalue(upper[i]), ylower: yvalue(lower[i])})
+	}
+	fill(segment)
+	drawing.Ctx2D.Restore()
+}
@@ -0,0 +1,189 @@
+package stockchart
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/larry868/rgb"
+	timeline "github.com/larry868/timeline/v2"
+)
+
+// BandKind is the kind of a band indicator
+type BandKind int
+
+const (
+	BK_Bollinger BandKind = 0 // the simple moving average, plus and minus a multiple of the standard deviation
+	BK_Keltner   BandKind = 1 // the exponential moving average, plus and minus a multiple of the average true range over the same period
+)
+
+func (kind BandKind) String() string {
+	if kind == BK_Keltner {
+		return "Keltner"
+	}
+	return "Bollinger"
+}
+
+// the lines of a band indicator
+const (
+	bandUpper  = 0
+	bandMiddle = 1
+	bandLower  = 2
+	bandATR    = 3 // the average true range of the Keltner channel
+)
+
+// Drawing band indicators like Bollinger Bands and Keltner Channels, within the yAxisRange of the chart or of its pane.
+// The upper, middle and lower lines are drawn over a translucent fill between the upper and the lower lines.
+type DrawingBands struct {
+	Drawing
+
+	Kind       BandKind    // Bollinger or Keltner
+	Period     int         // the number of data points of the moving average
+	Multiplier float64     // the multiple of the standard deviation or of the average true range
+	Source     PriceSource // the price of the moving average and of the standard deviation
+	LineWidth  float64     // the width of the lines, in pixels
+	AutoScale  bool        // include the extremes of the bands in the y autoscale of the chart, so the bands are never clipped
+
+	bands    *indicator // the computed bands
+	computed [4]float64 // the kind, the period, the multiplier and the source of the computed bands
+
+	lastSelectedTimeslice timeline.TimeSlice
+}
+
+// Drawing factory
+func NewDrawingBands(series *DataList, kind BandKind, period int, multiplier float64, color rgb.Color) *DrawingBands {
+	drawing := new(DrawingBands)
+	drawing.Name = fmt.Sprintf("%s%d", kind, period)
+	drawing.series = series
+	drawing.MainColor = color
+	drawing.Kind = kind
+	drawing.Period = period
+	drawing.Multiplier = multiplier
+	drawing.LineWidth = 1
+
+	drawing.bands = newIndicator(4, func(ind *indicator, i int) {
+		drawing.compute(ind, i)
+	})
+
+	drawing.Drawing.OnRedraw = func() {
+		drawing.lastSelectedTimeslice = drawing.chart.selectedTimeSlice
+		drawing.onRedraw()
+	}
+	drawing.Drawing.NeedRedraw = func() bool {
+		return drawing.lastSelectedTimeslice.Compare(drawing.chart.selectedTimeSlice) != timeline.EQUAL
+	}
+	drawing.Drawing.OnChangeData = func(data *DataStock) {
+		drawing.bands.invalidate(data)
+	}
+	drawing.Drawing.YExtremes = func(ts timeline.TimeSlice) (low float64, high float64) {
+		return drawing.extremes(ts)
+	}
+	return drawing
+}
+
+// compute sets the bands at i
+func (drawing *DrawingBands) compute(ind *indicator, i int) {
+	price := func(j int) float64 { return drawing.Source.Price(ind.items[j]) }
+	switch drawing.Kind {
+	case BK_Keltner:
+		var prev *DataStock
+		if i > 0 {
+			prev = ind.items[i-1]
+		}
+		// Wilder's smoothing of the true range, seeded with its simple average
+		atr := ind.prev(bandATR, i)
+		tr := trueRange(prev, ind.items[i])
+		if math.IsNaN(atr) {
+			atr = movingAverage(MA_SMA, drawing.Period, func(j int) float64 {
+				if j == 0 {
+					return trueRange(nil, ind.items[0])
+				}
+				return trueRange(ind.items[j-1], ind.items[j])
+			}, i, math.NaN())
+		} else {
+			atr = (atr*float64(drawing.Period-1) + tr) / float64(drawing.Period)
+		}
+		middle := movingAverage(MA_EMA, drawing.Period, price, i, ind.prev(bandMiddle, i))
+		ind.lines[bandATR][i] = atr
+		ind.lines[bandMiddle][i] = middle
+		ind.lines[bandUpper][i] = middle + drawing.Multiplier*atr
+		ind.lines[bandLower][i] = middle - drawing.Multiplier*atr
+
+	default:
+		middle := movingAverage(MA_SMA, drawing.Period, price, i, math.NaN())
+		sd := standardDeviation(drawing.Period, price, i, middle)
+		ind.lines[bandMiddle][i] = middle
+		ind.lines[bandUpper][i] = middle + drawing.Multiplier*sd
+		ind.lines[bandLower][i] = middle - drawing.Multiplier*sd
+	}
+}
+
+// Values returns the data points of the series and their upper, middle and lower bands, NaN if undefined.
+// The bands are computed only for the data points appended or changed since the last call.
+func (drawing *DrawingBands) Values() (items []*DataStock, upper []float64, middle []float64, lower []float64) {
+	if params := [4]float64{float64(drawing.Kind), float64(drawing.Period), drawing.Multiplier, float64(drawing.Source)}; params != drawing.computed {
+		drawing.computed = params
+		drawing.bands.reset()
+	}
+	drawing.bands.update(drawing.series)
+	return drawing.bands.items, drawing.bands.lines[bandUpper], drawing.bands.lines[bandMiddle], drawing.bands.lines[bandLower]
+}
+
+// extremes returns the lowest lower band and the highest upper band within ts, NaN if not AutoScale or none
+func (drawing *DrawingBands) extremes(ts timeline.TimeSlice) (low float64, high float64) {
+	low, high = math.NaN(), math.NaN()
+	if !drawing.AutoScale {
+		return low, high
+	}
+	_, upper, _, lower := drawing.Values()
+	first, last := drawing.bands.bounds(drawing.series.Window(ts))
+	for i := first; i < last; i++ {
+		if math.IsNaN(upper[i]) || math.IsNaN(lower[i]) {
+			continue
+		}
+		if math.IsNaN(low) || lower[i] < low {
+			low = lower[i]
+		}
+		if math.IsNaN(high) || upper[i] > high {
+			high = upper[i]
+		}
+	}
+	return low, high
+}
+
+// onRedraw draws the bands of the data points inside the xAxisRange.
+// The layer should have been cleared before.
+func (drawing *DrawingBands) onRedraw() {
+	items, upper, middle, lower := drawing.Values()
+	yrange := *drawing.yAxis()
+	if yrange.Delta() == 0 {
+		return
+	}
+	yfactor := float64(drawing.drawArea.Height) / yrange.Delta()
+	ybottom := float64(drawing.drawArea.End().Y)
+	yvalue := func(v float64) float64 {
+		return ybottom - yfactor*(v-yrange.Low())
+	}
+
+	// one data point more on each side to draw the bands to the borders
+	first, last := drawing.bands.bounds(drawing.series.Window(*drawing.xAxisRange))
+	if first == last {
+		return
+	}
+	first, last = max(0, first-1), min(len(items), last+1)
+	items = items[first:last]
+	drawing.drawBand(items, upper[first:last], lower[first:last], drawing.MainColor.Opacify(0.1), yvalue)
+	drawing.drawLine(items, upper[first:last], drawing.MainColor, drawing.LineWidth, yvalue)
+	drawing.drawLine(items, middle[first:last], drawing.MainColor.Opacify(0.6), drawing.LineWidth, yvalue)
+	drawing.drawLine(items, lower[first:last], drawing.MainColor, drawing.LineWidth, yvalue)
+}
+
+// AddBands adds a band indicator of the MainSeries on the chart layer, drawn before the candles.
+// If autoscale, the y axis of the chart includes the extremes of the bands.
+// The bands are recomputed incrementally when data are appended or updated.
+func (pchart *StockChart) AddBands(kind BandKind, period int, multiplier float64, color rgb.Color, autoscale bool) *DrawingBands {
+	drawing := NewDrawingBands(&pchart.MainSeries, kind, period, multiplier, color)
+	drawing.AutoScale = autoscale
+	pchart.AddSubChart(4, &drawing.Drawing)
+	pchart.Redraw()
+	return drawing
+}
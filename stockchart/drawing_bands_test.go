@@ -0,0 +1,85 @@
+package stockchart
+
+import (
+	"math"
+	"testing"
+
+	"github.com/larry868/rgb"
+	timeline "github.com/larry868/timeline/v2"
+)
+
+func TestBollingerBands(t *testing.T) {
+	series := testSeries(30)
+	bb := NewDrawingBands(&series, BK_Bollinger, 4, 2, rgb.Blue)
+	items, upper, middle, lower := bb.Values()
+	if len(items) != 30 || !math.IsNaN(middle[2]) {
+		t.Fatalf("Values fails: %d items, middle[2]=%v", len(items), middle[2])
+	}
+	for i := 3; i < len(items); i++ {
+		var sum, sum2 float64
+		for _, item := range items[i-3 : i+1] {
+			sum += item.Close
+			sum2 += item.Close * item.Close
+		}
+		mean := sum / 4
+		sd := math.Sqrt(sum2/4 - mean*mean)
+		if !almostEqual(middle[i], mean) || math.Abs(upper[i]-(mean+2*sd)) > 1e-6 || math.Abs(lower[i]-(mean-2*sd)) > 1e-6 {
+			t.Fatalf("Bollinger at %d fails: get %v %v %v, want %v±%v", i, upper[i], middle[i], lower[i], mean, 2*sd)
+		}
+	}
+}
+
+func TestKeltnerChannel(t *testing.T) {
+	series := testSeries(30)
+	kc := NewDrawingBands(&series, BK_Keltner, 5, 1.5, rgb.Blue)
+	items, upper, middle, lower := kc.Values()
+	if !math.IsNaN(middle[3]) || math.IsNaN(middle[4]) {
+		t.Fatalf("Keltner fails: middle[3]=%v middle[4]=%v", middle[3], middle[4])
+	}
+
+	// the ATR is seeded with the simple average of the true ranges, then smoothed
+	atr := trueRange(nil, items[0])
+	for i := 1; i < 5; i++ {
+		atr += trueRange(items[i-1], items[i])
+	}
+	atr /= 5
+	for i := 4; i < len(items); i++ {
+		if i > 4 {
+			atr = (atr*4 + trueRange(items[i-1], items[i])) / 5
+		}
+		if !almostEqual(upper[i]-middle[i], 1.5*atr) || !almostEqual(middle[i]-lower[i], 1.5*atr) {
+			t.Fatalf("Keltner at %d fails: get %v %v %v, want ATR %v", i, upper[i], middle[i], lower[i], atr)
+		}
+	}
+}
+
+func TestBandsAutoScale(t *testing.T) {
+	chart, h := newTestChart(testSeries(100))
+	h.flushFrames()
+	plain := chart.yAxisRange
+
+	bb := chart.AddBands(BK_Bollinger, 20, 6, rgb.Blue, false)
+	h.flushFrames()
+	if !chart.yAxisRange.Equal(plain) {
+		t.Errorf("AddBands without autoscale fails: the y range changes from %v to %v", plain, chart.yAxisRange)
+	}
+	if chart.recorder(4).Count("Fill") == 0 {
+		t.Errorf("AddBands fails: the band is not filled")
+	}
+
+	bb.AutoScale = true
+	chart.RedrawOnlyNeeds()
+	h.flushFrames()
+	low, high := bb.extremes(chart.selectedTimeSlice)
+	if math.IsNaN(low) || chart.yAxisRange.Low() > low || chart.yAxisRange.High() < high {
+		t.Errorf("AddBands with autoscale fails: y range %v does not include %v-%v", chart.yAxisRange, low, high)
+	}
+	if chart.yAxisRange.Equal(plain) {
+		t.Errorf("AddBands with autoscale fails: the y range is unchanged")
+	}
+
+	// outside of the series
+	if low, high := bb.extremes(timeline.TimeSlice{}); !math.IsNaN(low) || !math.IsNaN(high) {
+		t.Errorf("extremes fails: get %v-%v", low, high)
+	}
+}
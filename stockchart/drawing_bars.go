@@ -3,8 +3,7 @@ package stockchart
 import (
 	"math"
 
-	"github.com/gowebapi/webapi/core/js"
-	"github.com/gowebapi/webapi/html/canvas"
+	"github.com/larry868/datarange"
 	"github.com/larry868/rgb"
 	timeline "github.com/larry868/timeline/v2"
 )
@@ -31,31 +30,71 @@ func NewDrawingBars(series *DataList) *DrawingBars {
 	drawing.Drawing.NeedRedraw = func() bool {
 		return drawing.lastSelectedTimeslice.Compare(drawing.chart.selectedTimeSlice) != timeline.EQUAL
 	}
+
+	// in a pane, the y scale shows the volumes
+	drawing.Drawing.YExtremes = func(ts timeline.TimeSlice) (low float64, high float64) {
+		if drawing.Layer == nil || drawing.pane == nil {
+			return math.NaN(), math.NaN()
+		}
+		_, yrange := drawing.volumes(ts)
+		return 0, yrange.High()
+	}
 	return drawing
 }
 
+// volumes returns the data points within ts, bars narrower than a pixel being merged, and the range of their volumes
+func (drawing DrawingBars) volumes(ts timeline.TimeSlice) ([]*DataStock, datarange.DataRange) {
+	xfactor := float64(drawing.drawArea.Width) / float64(drawing.xSpan(ts.From, ts.To))
+	window := drawing.series.Window(ts)
+	items := decimateSpan(window, ts.From, xfactor, drawing.xSpan)
+	yrange := drawing.series.VolumeDataRange(&ts, 0)
+	if len(items) != len(window) {
+		yrange = decimatedVolumeRange(items)
+	}
+	return items, yrange
+}
+
 // OnRedraw redraws all bars inside the xAxisRange of the OHLC series
 // The layer should have been cleared before.
 func (drawing DrawingBars) onRedraw() {
 
-	// get xfactor & yfactor according to time selection
-	yrange := drawing.series.VolumeDataRange(drawing.xAxisRange, 0)
-	if yrange.Delta() == 0 {
+	// bars narrower than a pixel are merged, summing their volumes
+	items, yrange := drawing.volumes(*drawing.xAxisRange)
+	if drawing.pane != nil {
+		yrange = *drawing.yAxis()
+	} else {
+		// volumes bars start from zero
 		yrange.ResetBoundaries(0, yrange.High())
 	}
-	xfactor := float64(drawing.drawArea.Width) / float64(drawing.xAxisRange.Duration().Duration)
-	yfactor := float64(drawing.drawArea.Height) / yrange.Delta()
 
-	// Debug(DBG_REDRAW, "%q OnRedraw drawarea:%s, xAxisRange:%v, yrange:%v, xfactor:%f yfactor:%f", drawing.Name, drawing.drawArea, drawing.xAxisRange.String(), yrange.String(), xfactor, yfactor)
+	// Debug(DBG_REDRAW, "%q OnRedraw drawarea:%s, xAxisRange:%v, yrange:%v", drawing.Name, drawing.drawArea, drawing.xAxisRange.String(), yrange.String())
+
+	barcolor := rgb.Gray.Lighten(0.7)
+	drawing.drawBars(items, yrange, func(i int) (float64, rgb.Color) {
+		return items[i].Volume, barcolor
+	})
+}
+
+// drawBars draws a bar for every items inside the xAxisRange, from the zero baseline to its value within yrange.
+// Positive values are drawn above the baseline, negatives ones below. The baseline is bounded by the drawing area.
+// bar returns the value and the color of the bar of items[i].
+func (drawing *Drawing) drawBars(items []*DataStock, yrange datarange.DataRange, bar func(i int) (value float64, color rgb.Color)) {
+	if yrange.Delta() == 0 {
+		return
+	}
+
+	// get xfactor & yfactor according to time selection
+	xfactor := float64(drawing.drawArea.Width) / float64(drawing.xSpan(drawing.xAxisRange.From, drawing.xAxisRange.To))
+	yfactor := float64(drawing.drawArea.Height) / yrange.Delta()
+	base := math.Max(yrange.Low(), math.Min(0, yrange.High()))
+	ybase := drawing.drawArea.O.Y + drawing.drawArea.Height - int(yfactor*(base-yrange.Low()))
 
 	// scan all points
 	var rbar *Rect
-	item := drawing.series.Tail
-	for item != nil {
+	for i, item := range items {
 		// skip items before xAxisRange boundary or without duration
 		d := float64(item.Duration().Duration)
 		if item.To.Before(drawing.xAxisRange.From) || item.IsInfinite() || d == 0.0 {
-			item = item.Next
 			continue
 		}
 
@@ -65,15 +104,18 @@ func (drawing DrawingBars) onRedraw() {
 		}
 
 		// choose the color
-		barcolor := rgb.Gray.Lighten(0.7)
-		drawing.Ctx2D.SetFillStyle(&canvas.Union{Value: js.ValueOf(barcolor.Hexa())})
+		value, barcolor := bar(i)
+		if math.IsNaN(value) {
+			continue
+		}
+		drawing.Ctx2D.SetFillStyle(barcolor)
 
 		// build the BAR rect, inside the drawing areaa
 		rbar = new(Rect)
 
 		// x axis: time
-		rbar.O.X = drawing.drawArea.O.X + int(math.Round(xfactor*float64(item.TimeSlice.From.Sub(drawing.xAxisRange.From))))
-		rbar.Width = imax(1, int(math.Round(xfactor*d)))
+		rbar.O.X = drawing.drawArea.O.X + int(math.Round(xfactor*float64(drawing.xSpan(drawing.xAxisRange.From, item.TimeSlice.From))))
+		rbar.Width = imax(1, int(math.Round(xfactor*float64(drawing.xSpan(item.From, item.To)))))
 
 		// add padding between bars
 		// TODO:
@@ -81,22 +123,18 @@ func (drawing DrawingBars) onRedraw() {
 		rbar.O.X += xpadding
 		rbar.Width -= 2 * xpadding
 
-		// y axis: value
+		// y axis: value, from the baseline
 		// need to reverse the bar in canvas coordinates
-		rbar.O.Y = drawing.drawArea.O.Y + drawing.drawArea.Height - int(yfactor*yrange.Low())
-		rbar.Height = -int(yfactor * item.Volume)
+		rbar.O.Y = ybase
+		rbar.Height = -int(yfactor * (value - base))
 		rbar.FlipPositive()
 
 		// skip bars outside the drawing area
 		if rbar = drawing.drawArea.And(*rbar); rbar == nil {
-			item = item.Next
 			continue
 		}
 
 		// draw bar
 		drawing.Ctx2D.FillRect(float64(rbar.O.X), float64(rbar.O.Y), float64(rbar.Width), float64(rbar.Height))
-
-		// scan next item
-		item = item.Next
 	}
 }
@@ -3,8 +3,6 @@ package stockchart
 import (
 	"math"
 
-	"github.com/gowebapi/webapi/core/js"
-	"github.com/gowebapi/webapi/html/canvas"
 	"github.com/larry868/rgb"
 	bootstrapcolor "github.com/larry868/rgb/bootstrapcolor.go"
 	timeline "github.com/larry868/timeline/v2"
@@ -24,6 +22,7 @@ type DrawingCandles struct {
 	Drawing
 
 	DrawStyle
+	GapStyle GapStyle // how gaps between candles are rendered, GS_None by default
 
 	lastSelectedTimeslice timeline.TimeSlice
 	lastSelectedData      *DataStock
@@ -60,7 +59,7 @@ func NewDrawingCandles(series *DataList, drawstyle DrawStyle) *DrawingCandles {
 func (drawing *DrawingCandles) onRedraw() {
 	// get xfactor & yfactor according to time selection
 	yrange := drawing.chart.yAxisRange
-	xfactor := float64(drawing.drawArea.Width) / float64(drawing.xAxisRange.Duration().Duration)
+	xfactor := float64(drawing.drawArea.Width) / float64(drawing.xSpan(drawing.xAxisRange.From, drawing.xAxisRange.To))
 	yfactor := float64(drawing.drawArea.Height) / yrange.Delta()
 
 	// Debug(DBG_REDRAW, "%q OnRedraw drawarea:%s, xfactor:%f yfactor:%f style:%v", drawing.Name, drawing.drawArea, xfactor, yfactor, drawing.DrawStyle)
@@ -79,19 +78,25 @@ func (drawing *DrawingCandles) onRedraw() {
 	const ypat = 10.0
 
 	// scan all points forward !
+	// candles narrower than a pixel are merged
 	drbottomf64 := float64(drawing.drawArea.O.Y + drawing.drawArea.Height)
-	item := drawing.series.Tail
-	for item != nil {
+	items := decimateSpan(drawing.series.Window(*drawing.xAxisRange), drawing.xAxisRange.From, xfactor, drawing.xSpan)
+
+	// gaps are drawn behind candles
+	drawing.drawGaps(items, drawing.GapStyle, drawing.MainColor, func(price float64) float64 {
+		return drbottomf64 - yfactor*(price-yrange.Low())
+	})
+
+	for _, item := range items {
 		// skip items before xAxisRange boundary or without duration
 		// skip items after xAxisRange boundary.
 		// Do not break because series are not always sorted chronologicaly
 		if item.IsInfinite() || item.Duration().Duration == 0 || item.To.Before(drawing.xAxisRange.From) || item.From.After(drawing.xAxisRange.To) {
-			item = item.Next
 			continue
 		}
 
 		// candle width, in px
-		wcf64 = fmax(1.0, math.Round(xfactor*float64(item.Duration().Duration)))
+		wcf64 = fmax(1.0, math.Round(xfactor*float64(drawing.xSpan(item.From, item.To))))
 
 		// choose the color
 		candleColor := item.CandleColor()
@@ -107,7 +112,7 @@ func (drawing *DrawingCandles) onRedraw() {
 		case DS_Bar:
 			// colorfull
 			drawing.Ctx2D.SetLineWidth(0)
-			drawing.Ctx2D.SetFillStyle(&canvas.Union{Value: js.ValueOf(candleColor.Hexa())})
+			drawing.Ctx2D.SetFillStyle(candleColor)
 
 			// width & xpos
 			wcf64 = 1.0
@@ -130,7 +135,7 @@ func (drawing *DrawingCandles) onRedraw() {
 		case DS_Stick:
 			// colorfull
 			drawing.Ctx2D.SetLineWidth(0)
-			drawing.Ctx2D.SetFillStyle(&canvas.Union{Value: js.ValueOf(candleColor.Hexa())})
+			drawing.Ctx2D.SetFillStyle(candleColor)
 
 			// width, padding & xpos
 			xpaddingf64 := fmax(0.5, wcf64/15)
@@ -166,7 +171,7 @@ func (drawing *DrawingCandles) onRedraw() {
 		case DS_Area:
 			// transparent
 			drawing.Ctx2D.SetLineWidth(0)
-			drawing.Ctx2D.SetFillStyle(&canvas.Union{Value: js.ValueOf(candleColor.Opacify(0.1).Hexa())})
+			drawing.Ctx2D.SetFillStyle(candleColor.Opacify(0.1))
 
 			// width & xpos
 			xcf64 = drawing.xTime(item.From)
@@ -199,7 +204,7 @@ func (drawing *DrawingCandles) onRedraw() {
 
 		case DS_Frame:
 			// transparent dash
-			drawing.Ctx2D.SetStrokeStyle(&canvas.Union{Value: js.ValueOf(candleColor.Opacify(0.5).Hexa())})
+			drawing.Ctx2D.SetStrokeStyle(candleColor.Opacify(0.5))
 			drawing.Ctx2D.SetLineDash([]float64{5.0, 5.0})
 			drawing.Ctx2D.SetLineWidth(1)
 
@@ -236,12 +241,9 @@ func (drawing *DrawingCandles) onRedraw() {
 		// draw has patterns
 		if item.HasPatterns > 0 {
 			drawing.Ctx2D.SetLineWidth(1)
-			drawing.Ctx2D.SetStrokeStyle(&canvas.Union{Value: js.ValueOf(patternColor.Hexa())})
+			drawing.Ctx2D.SetStrokeStyle(patternColor)
 			drawing.Ctx2D.StrokeRect(float64(int(xpatf64))-0.5, float64(int(ypatf64))-0.5, float64(int(wpatf64)), float64(int(hpatf64)))
 		}
-
-		// scan next item
-		item = item.Next
 	}
 
 	// draw the label of the series
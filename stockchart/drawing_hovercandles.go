@@ -5,14 +5,14 @@ import (
 
 	// "github.com/gowebapi/webapi/core/js"
 	// "github.com/gowebapi/webapi/html/canvas"
-	"github.com/gowebapi/webapi/html/htmlevent"
 	"github.com/larry868/rgb"
 	timeline "github.com/larry868/timeline/v2"
 )
 
 type DrawingHoverCandles struct {
 	Drawing
-	hoverData *DataStock // the data hovered
+	hoverData       *DataStock // the data hovered
+	isCursorPointer bool       // is the cursor the pointer one, over a button of the layer ?
 }
 
 func NewDrawingHoverCandles(series *DataList) *DrawingHoverCandles {
@@ -21,25 +21,35 @@ func NewDrawingHoverCandles(series *DataList) *DrawingHoverCandles {
 	drawing.series = series
 	drawing.MainColor = rgb.Black.Lighten(0.5)
 
-	drawing.Drawing.OnMouseMove = func(xy Point, event *htmlevent.MouseEvent) {
+	drawing.Drawing.OnMouseMove = func(xy Point, event *MouseEvent) {
 		drawing.onMouseMove(xy, event)
 	}
-	drawing.Drawing.OnClick = func(xy Point, event *htmlevent.MouseEvent) {
+	drawing.Drawing.OnClick = func(xy Point, event *MouseEvent) {
 		drawing.onClick(xy, event)
 	}
-	drawing.Drawing.OnMouseLeave = func(xy Point, event *htmlevent.MouseEvent) {
+	drawing.Drawing.OnMouseLeave = func(xy Point, event *MouseEvent) {
 		drawing.hoverData = nil
-		drawing.Clear()
+		drawing.Layer.Redraw()
 	}
 	return drawing
 }
 
 // draw the line over the candle where the mouse is
-func (drawing *DrawingHoverCandles) onMouseMove(xy Point, event *htmlevent.MouseEvent) {
+func (drawing *DrawingHoverCandles) onMouseMove(xy Point, event *MouseEvent) {
+
+	// change the cursor over the buttons of the layer
+	if onButton := drawing.chart.isOnButton(xy); onButton != drawing.isCursorPointer {
+		drawing.isCursorPointer = onButton
+		if onButton {
+			drawing.setCursor("pointer")
+		} else {
+			drawing.setCursor("auto")
+		}
+	}
 
 	// get the candle
 	trate := drawing.drawArea.XRate(xy.X)
-	postime := drawing.xAxisRange.WhatTime(trate)
+	postime := drawing.xWhatTime(trate)
 	hoverData := drawing.series.GetDataAt(postime)
 	if postime.IsZero() || hoverData == nil {
 		return
@@ -51,14 +61,14 @@ func (drawing *DrawingHoverCandles) onMouseMove(xy Point, event *htmlevent.Mouse
 	}
 	drawing.hoverData = hoverData
 
-	// remove previous line
-	drawing.Clear()
+	// remove previous line, keeping other drawings of the layer
+	drawing.Layer.Redraw()
 
 	// draw a line at the middle of the selected candle
 	middletime := hoverData.TimeSlice.Middle()
 	// xtimerate := drawing.xAxisRange.Progress(middletime)
 	// xpos := drawing.drawArea.O.X + int(float64(drawing.drawArea.Width)*xtimerate)
-	// drawing.Ctx2D.SetFillStyle(&canvas.Union{Value: js.ValueOf(drawing.MainColor.Hexa())})
+	// drawing.Ctx2D.SetFillStyle(drawing.MainColor)
 	// drawing.Ctx2D.FillRect(float64(xpos), float64(drawing.drawArea.O.Y), 1, float64(drawing.drawArea.Height))
 	drawing.DrawVLine(middletime, drawing.MainColor, true)
 
@@ -69,7 +79,7 @@ func (drawing *DrawingHoverCandles) onMouseMove(xy Point, event *htmlevent.Mouse
 	} else {
 		middletime = middletime.UTC()
 	}
-	xtimerate := drawing.xAxisRange.Progress(middletime)
+	xtimerate := drawing.xProgress(middletime)
 	xpos := drawing.drawArea.O.X + int(float64(drawing.drawArea.Width)*xtimerate)
 	strtime := middletime.Format(strdtefmt)
 	drawing.Ctx2D.SetFont(`12px 'Roboto', sans-serif`)
@@ -77,16 +87,21 @@ func (drawing *DrawingHoverCandles) onMouseMove(xy Point, event *htmlevent.Mouse
 }
 
 // select a candle
-func (drawing *DrawingHoverCandles) onClick(xy Point, event *htmlevent.MouseEvent) {
+func (drawing *DrawingHoverCandles) onClick(xy Point, event *MouseEvent) {
+
+	// buttons are over the candles
+	if drawing.chart.isOnButton(xy) {
+		return
+	}
 
-	if event.ShiftKey() {
+	if event.ShiftKey {
 		drawing.chart.selectedData = nil
 		return
 	}
 
 	// get the candle
 	trate := drawing.drawArea.XRate(xy.X)
-	postime := drawing.xAxisRange.WhatTime(trate)
+	postime := drawing.xWhatTime(trate)
 	drawing.chart.selectedData = drawing.series.GetDataAt(postime)
 	if postime.IsZero() || drawing.chart.selectedData == nil {
 		Debug(DBG_EVENT, "%q OnClick xy:%v ==> no data found at this position", drawing.Name, xy)
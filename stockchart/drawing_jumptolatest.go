@@ -0,0 +1,51 @@
+package stockchart
+
+import (
+	"github.com/larry868/rgb"
+	bootstrapcolor "github.com/larry868/rgb/bootstrapcolor.go"
+)
+
+// DrawingJumpToLatest is a button appearing at the bottom right of the chart when the head of the series is out of view.
+// Clicking it moves the selected time slice to the head, and the chart follows new data again.
+type DrawingJumpToLatest struct {
+	Drawing
+	visible bool // is the button drawn ?
+}
+
+func NewDrawingJumpToLatest(series *DataList) *DrawingJumpToLatest {
+	drawing := new(DrawingJumpToLatest)
+	drawing.Name = "jumptolatest"
+	drawing.series = series
+	drawing.MainColor = bootstrapcolor.Blue
+
+	drawing.Drawing.OnRedraw = func() {
+		drawing.onRedraw()
+	}
+	drawing.Drawing.NeedRedraw = func() bool {
+		// redraw only when the button must appear or disappear
+		return drawing.visible == drawing.chart.IsHeadInView()
+	}
+	drawing.Drawing.OnClick = func(xy Point, event *MouseEvent) {
+		drawing.onClick(xy)
+	}
+	return drawing
+}
+
+func (drawing *DrawingJumpToLatest) onRedraw() {
+	drawing.visible = !drawing.chart.IsHeadInView()
+	drawing.chart.latestButton = Rect{}
+	if !drawing.visible {
+		return
+	}
+	drawing.Ctx2D.SetFont(`bold 12px 'Roboto', sans-serif`)
+	xy := Point{X: drawing.drawArea.End().X - 10, Y: drawing.drawArea.End().Y - 40}
+	drawing.chart.latestButton = drawing.DrawTextBox("latest »", xy, AlignEnd|AlignBottom, rgb.White.Opacify(0.9), drawing.MainColor, 0, 1, 4)
+}
+
+// move the selection to the head, the chart is updated by the event dispatcher
+func (drawing *DrawingJumpToLatest) onClick(xy Point) {
+	if !drawing.visible || !xy.IsIn(drawing.chart.latestButton) {
+		return
+	}
+	drawing.chart.setSelTimeSlice(drawing.chart.latestTimeSlice())
+}
@@ -0,0 +1,155 @@
+package stockchart
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/larry868/rgb"
+	timeline "github.com/larry868/timeline/v2"
+)
+
+// the lines of the MACD
+const (
+	macdFast      = 0 // the fast EMA
+	macdSlow      = 1 // the slow EMA
+	macdLine      = 2 // the fast EMA minus the slow EMA
+	macdSignal    = 3 // the EMA of the MACD line
+	macdHistogram = 4 // the MACD line minus the signal line
+)
+
+// Drawing the MACD, the moving average convergence divergence, usually in its own pane.
+// The histogram is drawn as bars around a zero baseline, below the MACD line and the signal line.
+type DrawingMACD struct {
+	Drawing
+
+	Fast         int         // the period of the fast EMA
+	Slow         int         // the period of the slow EMA
+	SignalPeriod int         // the period of the EMA of the MACD line giving the signal line
+	Source       PriceSource // the price of the EMAs
+	SignalColor  rgb.Color   // the color of the signal line
+	UpColor      rgb.Color   // the color of the positive bars of the histogram
+	DownColor    rgb.Color   // the color of the negative bars of the histogram
+	LineWidth    float64     // the width of the lines, in pixels
+
+	macd     *indicator // the computed MACD
+	computed [4]int     // the periods and the source of the computed MACD
+
+	lastSelectedTimeslice timeline.TimeSlice
+}
+
+// Drawing factory
+func NewDrawingMACD(series *DataList, fast int, slow int, signal int, color rgb.Color) *DrawingMACD {
+	drawing := new(DrawingMACD)
+	drawing.Name = fmt.Sprintf("MACD%d-%d-%d", fast, slow, signal)
+	drawing.series = series
+	drawing.MainColor = color
+	drawing.Fast = fast
+	drawing.Slow = slow
+	drawing.SignalPeriod = signal
+	drawing.SignalColor = rgb.Red
+	drawing.UpColor = greenCandle.Opacify(0.5)
+	drawing.DownColor = redCandle.Opacify(0.5)
+	drawing.LineWidth = 1.5
+
+	drawing.macd = newIndicator(5, func(ind *indicator, i int) {
+		price := func(j int) float64 { return drawing.Source.Price(ind.items[j]) }
+		fast := movingAverage(MA_EMA, drawing.Fast, price, i, ind.prev(macdFast, i))
+		slow := movingAverage(MA_EMA, drawing.Slow, price, i, ind.prev(macdSlow, i))
+		ind.lines[macdFast][i] = fast
+		ind.lines[macdSlow][i] = slow
+		ind.lines[macdLine][i] = fast - slow
+		signal := movingAverage(MA_EMA, drawing.SignalPeriod, func(j int) float64 { return ind.lines[macdLine][j] }, i, ind.prev(macdSignal, i))
+		ind.lines[macdSignal][i] = signal
+		ind.lines[macdHistogram][i] = fast - slow - signal
+	})
+
+	drawing.Drawing.OnRedraw = func() {
+		drawing.lastSelectedTimeslice = drawing.chart.selectedTimeSlice
+		drawing.onRedraw()
+	}
+	drawing.Drawing.NeedRedraw = func() bool {
+		return drawing.lastSelectedTimeslice.Compare(drawing.chart.selectedTimeSlice) != timeline.EQUAL
+	}
+	drawing.Drawing.OnChangeData = func(data *DataStock) {
+		drawing.macd.invalidate(data)
+	}
+	drawing.Drawing.YExtremes = func(ts timeline.TimeSlice) (low float64, high float64) {
+		return drawing.extremes(ts)
+	}
+	return drawing
+}
+
+// Values returns the data points of the series and their MACD line, signal line and histogram, NaN if undefined.
+// The MACD is computed only for the data points appended or changed since the last call.
+func (drawing *DrawingMACD) Values() (items []*DataStock, macd []float64, signal []float64, histogram []float64) {
+	if params := [4]int{drawing.Fast, drawing.Slow, drawing.SignalPeriod, int(drawing.Source)}; params != drawing.computed {
+		drawing.computed = params
+		drawing.macd.reset()
+	}
+	drawing.macd.update(drawing.series)
+	return drawing.macd.items, drawing.macd.lines[macdLine], drawing.macd.lines[macdSignal], drawing.macd.lines[macdHistogram]
+}
+
+// extremes returns the lowest and the highest values of the lines and of the histogram within ts, including the zero baseline.
+// Returns NaN if none.
+func (drawing *DrawingMACD) extremes(ts timeline.TimeSlice) (low float64, high float64) {
+	low, high = math.NaN(), math.NaN()
+	_, macd, signal, histogram := drawing.Values()
+	first, last := drawing.macd.bounds(drawing.series.Window(ts))
+	for i := first; i < last; i++ {
+		for _, v := range []float64{macd[i], signal[i], histogram[i]} {
+			if math.IsNaN(v) {
+				continue
+			}
+			if math.IsNaN(low) {
+				low, high = 0, 0
+			}
+			low, high = math.Min(low, v), math.Max(high, v)
+		}
+	}
+	return low, high
+}
+
+// onRedraw draws the zero baseline, the histogram, and the MACD and signal lines of the data points inside the xAxisRange.
+// The layer should have been cleared before.
+func (drawing *DrawingMACD) onRedraw() {
+	items, macd, signal, histogram := drawing.Values()
+	yrange := *drawing.yAxis()
+	if yrange.Delta() == 0 {
+		return
+	}
+	yfactor := float64(drawing.drawArea.Height) / yrange.Delta()
+	ybottom := float64(drawing.drawArea.End().Y)
+	yvalue := func(v float64) float64 {
+		return ybottom - yfactor*(v-yrange.Low())
+	}
+
+	drawing.drawLevel(0, drawing.MainColor.Opacify(0.5), yvalue)
+
+	// one data point more on each side to draw the lines to the borders
+	first, last := drawing.macd.bounds(drawing.series.Window(*drawing.xAxisRange))
+	if first == last {
+		return
+	}
+	first, last = max(0, first-1), min(len(items), last+1)
+	drawing.drawBars(items[first:last], yrange, func(i int) (float64, rgb.Color) {
+		value := histogram[first+i]
+		if value < 0 {
+			return value, drawing.DownColor
+		}
+		return value, drawing.UpColor
+	})
+	drawing.drawLine(items[first:last], signal[first:last], drawing.SignalColor, drawing.LineWidth, yvalue)
+	drawing.drawLine(items[first:last], macd[first:last], drawing.MainColor, drawing.LineWidth, yvalue)
+}
+
+// AddMACD adds a new pane below the chart, showing the MACD of the close of the MainSeries,
+// with the periods of the fast EMA, of the slow EMA and of the signal line, 12, 26 and 9 usually.
+// It is recomputed incrementally when data are appended or updated.
+func (pchart *StockChart) AddMACD(fast int, slow int, signal int, color rgb.Color) *DrawingMACD {
+	drawing := NewDrawingMACD(&pchart.MainSeries, fast, slow, signal, color)
+	if pane := pchart.AddPane("MACD", 0); pane != nil {
+		pane.AddDrawing(&drawing.Drawing)
+	}
+	return drawing
+}
@@ -0,0 +1,112 @@
+package stockchart
+
+import (
+	"math"
+	"testing"
+
+	"github.com/larry868/datarange"
+	"github.com/larry868/rgb"
+	timeline "github.com/larry868/timeline/v2"
+)
+
+func TestMACD(t *testing.T) {
+	series := testSeries(60)
+	macd := NewDrawingMACD(&series, 3, 6, 4, rgb.Blue)
+	items, line, signal, histogram := macd.Values()
+	if !math.IsNaN(line[4]) || math.IsNaN(line[5]) || !math.IsNaN(signal[7]) || math.IsNaN(signal[8]) {
+		t.Fatalf("Values fails: line[4]=%v line[5]=%v signal[7]=%v signal[8]=%v", line[4], line[5], signal[7], signal[8])
+	}
+
+	// the EMAs are seeded with the simple averages of their first period
+	ema := func(period int, values []float64) []float64 {
+		out := make([]float64, len(values))
+		prev := math.NaN()
+		for i := range values {
+			prev = movingAverage(MA_EMA, period, func(j int) float64 { return values[j] }, i, prev)
+			out[i] = prev
+		}
+		return out
+	}
+	closes := make([]float64, len(items))
+	for i, item := range items {
+		closes[i] = item.Close
+	}
+	fast, slow := ema(3, closes), ema(6, closes)
+	wantline := make([]float64, len(items))
+	for i := range items {
+		wantline[i] = fast[i] - slow[i]
+	}
+	wantsignal := ema(4, wantline)
+	for i := range items {
+		if !almostEqual(line[i], wantline[i]) || !almostEqual(signal[i], wantsignal[i]) || !almostEqual(histogram[i], wantline[i]-wantsignal[i]) {
+			t.Fatalf("MACD at %d fails: get %v %v %v, want %v %v", i, line[i], signal[i], histogram[i], wantline[i], wantsignal[i])
+		}
+	}
+
+	// the extremes include the zero baseline
+	low, high := macd.extremes(series.TimeSlice())
+	if low > 0 || high < 0 || math.IsNaN(low) {
+		t.Errorf("extremes fails: get %v-%v", low, high)
+	}
+	if low, high := macd.extremes(timeline.TimeSlice{}); !math.IsNaN(low) || !math.IsNaN(high) {
+		t.Errorf("extremes fails: get %v-%v", low, high)
+	}
+}
+
+func TestMACDPane(t *testing.T) {
+	chart, h := newTestChart(testSeries(100))
+	macd := chart.AddMACD(12, 26, 9, rgb.Blue)
+	h.flushFrames()
+	pane := chart.Panes()[0]
+	low, high := macd.extremes(chart.selectedTimeSlice)
+	if pane.yAxisRange.Low() > low || pane.yAxisRange.High() < high {
+		t.Errorf("AddMACD fails: the y range %v does not include %v-%v", pane.yAxisRange, low, high)
+	}
+
+	// the histogram has bars of both colors, above and below the baseline
+	_, _, _, histogram := macd.Values()
+	var ups, downs int
+	for _, v := range histogram {
+		switch {
+		case v > 0:
+			ups++
+		case v < 0:
+			downs++
+		}
+	}
+	var upbars, downbars int
+	for _, call := range chart.recorder(6).Filter("SetFillStyle") {
+		switch call.Args[0] {
+		case macd.UpColor:
+			upbars++
+		case macd.DownColor:
+			downbars++
+		}
+	}
+	if ups == 0 || downs == 0 || upbars == 0 || downbars == 0 {
+		t.Errorf("AddMACD fails: %d/%d positive/negative values, %d/%d bars drawn", ups, downs, upbars, downbars)
+	}
+}
+
+func TestDrawBarsBaseline(t *testing.T) {
+	chart, h := newTestChart(testSeries(10))
+	h.flushFrames()
+	drawing := Drawing{Layer: chart.layers[4], drawArea: Rect{O: Point{X: 0, Y: 0}, Width: 100, Height: 100}}
+	chart.recorder(4).Reset()
+	items := chart.MainSeries.window(nil)
+	values := []float64{-5, 5}
+
+	// one bar below and one bar above the baseline at 50
+	yrange := datarange.Make(-10, 10, 0, "")
+	drawing.drawBars(items[:2], yrange, func(i int) (float64, rgb.Color) { return values[i], rgb.Black })
+	rects := chart.recorder(4).Filter("FillRect")
+	if len(rects) != 2 {
+		t.Fatalf("drawBars fails: %d bars drawn", len(rects))
+	}
+	if y, hh := rects[0].Args[1], rects[0].Args[3]; y != 50.0 || hh != 25.0 {
+		t.Errorf("drawBars fails: the negative bar is at %v, height %v", y, hh)
+	}
+	if y, hh := rects[1].Args[1], rects[1].Args[3]; y != 25.0 || hh != 25.0 {
+		t.Errorf("drawBars fails: the positive bar is at %v, height %v", y, hh)
+	}
+}
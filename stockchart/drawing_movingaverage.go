@@ -0,0 +1,94 @@
+package stockchart
+
+import (
+	"fmt"
+
+	"github.com/larry868/rgb"
+	timeline "github.com/larry868/timeline/v2"
+)
+
+// Drawing a moving average of a series of candles, within the yAxisRange of the chart or of its pane
+type DrawingMovingAverage struct {
+	Drawing
+
+	Kind      MAKind      // SMA, EMA or WMA
+	Period    int         // the number of data points averaged
+	Source    PriceSource // the price averaged
+	LineWidth float64     // the width of the line, in pixels
+
+	ma       *indicator // the computed moving average
+	computed [3]int     // the kind, the period and the source of the computed moving average
+
+	lastSelectedTimeslice timeline.TimeSlice
+}
+
+// Drawing factory
+func NewDrawingMovingAverage(series *DataList, kind MAKind, period int, source PriceSource, color rgb.Color) *DrawingMovingAverage {
+	drawing := new(DrawingMovingAverage)
+	drawing.Name = fmt.Sprintf("%s%d", kind, period)
+	drawing.series = series
+	drawing.MainColor = color
+	drawing.Kind = kind
+	drawing.Period = period
+	drawing.Source = source
+	drawing.LineWidth = 1.5
+
+	drawing.ma = newIndicator(1, func(ind *indicator, i int) {
+		value := func(j int) float64 { return drawing.Source.Price(ind.items[j]) }
+		ind.lines[0][i] = movingAverage(drawing.Kind, drawing.Period, value, i, ind.prev(0, i))
+	})
+
+	drawing.Drawing.OnRedraw = func() {
+		drawing.lastSelectedTimeslice = drawing.chart.selectedTimeSlice
+		drawing.onRedraw()
+	}
+	drawing.Drawing.NeedRedraw = func() bool {
+		return drawing.lastSelectedTimeslice.Compare(drawing.chart.selectedTimeSlice) != timeline.EQUAL
+	}
+	drawing.Drawing.OnChangeData = func(data *DataStock) {
+		drawing.ma.invalidate(data)
+	}
+	return drawing
+}
+
+// Values returns the data points of the series and their moving average, NaN if undefined.
+// The moving average is computed only for the data points appended or changed since the last call.
+func (drawing *DrawingMovingAverage) Values() ([]*DataStock, []float64) {
+	if params := [3]int{int(drawing.Kind), drawing.Period, int(drawing.Source)}; params != drawing.computed {
+		drawing.computed = params
+		drawing.ma.reset()
+	}
+	drawing.ma.update(drawing.series)
+	return drawing.ma.items, drawing.ma.lines[0]
+}
+
+// onRedraw draws the moving average of the data points inside the xAxisRange.
+// The layer should have been cleared before.
+func (drawing *DrawingMovingAverage) onRedraw() {
+	items, values := drawing.Values()
+	yrange := *drawing.yAxis()
+	if yrange.Delta() == 0 {
+		return
+	}
+	yfactor := float64(drawing.drawArea.Height) / yrange.Delta()
+	ybottom := float64(drawing.drawArea.End().Y)
+
+	// one data point more on each side to draw the line to the borders
+	first, last := drawing.ma.bounds(drawing.series.Window(*drawing.xAxisRange))
+	if first == last {
+		return
+	}
+	first, last = max(0, first-1), min(len(items), last+1)
+	drawing.drawLine(items[first:last], values[first:last], drawing.MainColor, drawing.LineWidth, func(v float64) float64 {
+		return ybottom - yfactor*(v-yrange.Low())
+	})
+}
+
+// AddMovingAverage adds a moving average of the MainSeries on the chart layer, drawn before the candles.
+// It is recomputed incrementally when data are appended or updated.
+func (pchart *StockChart) AddMovingAverage(kind MAKind, period int, source PriceSource, color rgb.Color) *DrawingMovingAverage {
+	drawing := NewDrawingMovingAverage(&pchart.MainSeries, kind, period, source, color)
+	pchart.AddSubChart(4, &drawing.Drawing)
+	pchart.Redraw()
+	return drawing
+}
@@ -0,0 +1,222 @@
+package stockchart
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/larry868/rgb"
+	timeline "github.com/larry868/timeline/v2"
+)
+
+// OscillatorKind is the kind of an oscillator bounded between 0 and 100
+type OscillatorKind int
+
+const (
+	OK_RSI        OscillatorKind = 0 // the relative strength index, with Wilder's smoothing of the gains and the losses
+	OK_Stochastic OscillatorKind = 1 // the stochastic oscillator, the smoothed %K and its moving average %D
+)
+
+func (kind OscillatorKind) String() string {
+	if kind == OK_Stochastic {
+		return "Stoch"
+	}
+	return "RSI"
+}
+
+// the lines of an oscillator
+const (
+	oscMain   = 0 // the RSI, or the %K of the stochastic
+	oscSignal = 1 // the %D of the stochastic
+	oscGain   = 2 // the average gain of the RSI, or the raw %K of the stochastic
+	oscLoss   = 3 // the average loss of the RSI
+)
+
+// Drawing an oscillator like the RSI or the stochastic, on a fixed 0-100 scale, usually in its own pane.
+// The overbought and oversold levels are drawn as guide lines, over the shaded zones beyond them.
+type DrawingOscillator struct {
+	Drawing
+
+	Kind         OscillatorKind // RSI or Stochastic
+	Period       int            // the number of data points of the RSI, or of the highest high and the lowest low of the stochastic
+	Smooth       int            // the period of the simple moving average smoothing the raw %K of the stochastic, 1 for a fast stochastic
+	SignalPeriod int            // the period of the simple moving average of %K giving %D
+	Source       PriceSource    // the price of the RSI
+	Overbought   float64        // the overbought level, the zone above is shaded
+	Oversold     float64        // the oversold level, the zone below is shaded
+	ShowZones    bool           // shade the overbought and the oversold zones
+	SignalColor  rgb.Color      // the color of %D
+	LineWidth    float64        // the width of the lines, in pixels
+
+	osc      *indicator // the computed oscillator
+	computed [5]int     // the kind, the periods and the source of the computed oscillator
+
+	lastSelectedTimeslice timeline.TimeSlice
+}
+
+// Drawing factory, with the usual levels: 70/30 for the RSI, 80/20 for the stochastic.
+// The stochastic is a slow one, its raw %K is smoothed over 3 data points, and %D is the average of %K over 3 data points.
+func NewDrawingOscillator(series *DataList, kind OscillatorKind, period int, color rgb.Color) *DrawingOscillator {
+	drawing := new(DrawingOscillator)
+	drawing.Name = fmt.Sprintf("%s%d", kind, period)
+	drawing.series = series
+	drawing.MainColor = color
+	drawing.Kind = kind
+	drawing.Period = period
+	drawing.Smooth = 3
+	drawing.SignalPeriod = 3
+	drawing.Overbought, drawing.Oversold = 70, 30
+	if kind == OK_Stochastic {
+		drawing.Overbought, drawing.Oversold = 80, 20
+	}
+	drawing.ShowZones = true
+	drawing.SignalColor = rgb.Red
+	drawing.LineWidth = 1.5
+
+	drawing.osc = newIndicator(4, func(ind *indicator, i int) {
+		drawing.compute(ind, i)
+	})
+
+	drawing.Drawing.OnRedraw = func() {
+		drawing.lastSelectedTimeslice = drawing.chart.selectedTimeSlice
+		drawing.onRedraw()
+	}
+	drawing.Drawing.NeedRedraw = func() bool {
+		return drawing.lastSelectedTimeslice.Compare(drawing.chart.selectedTimeSlice) != timeline.EQUAL
+	}
+	drawing.Drawing.OnChangeData = func(data *DataStock) {
+		drawing.osc.invalidate(data)
+	}
+	drawing.Drawing.YExtremes = func(ts timeline.TimeSlice) (low float64, high float64) {
+		return 0, 100
+	}
+	return drawing
+}
+
+// compute sets the oscillator at i
+func (drawing *DrawingOscillator) compute(ind *indicator, i int) {
+	switch drawing.Kind {
+	case OK_Stochastic:
+		raw := math.NaN()
+		if drawing.Period > 0 && i >= drawing.Period-1 {
+			high, low := math.Inf(-1), math.Inf(1)
+			for _, item := range ind.items[i-drawing.Period+1 : i+1] {
+				high, low = math.Max(high, item.High), math.Min(low, item.Low)
+			}
+			raw = 50
+			if high > low {
+				raw = 100 * (ind.items[i].Close - low) / (high - low)
+			}
+		}
+		ind.lines[oscGain][i] = raw
+		ind.lines[oscMain][i] = movingAverage(MA_SMA, max(1, drawing.Smooth), func(j int) float64 { return ind.lines[oscGain][j] }, i, math.NaN())
+		ind.lines[oscSignal][i] = movingAverage(MA_SMA, drawing.SignalPeriod, func(j int) float64 { return ind.lines[oscMain][j] }, i, math.NaN())
+
+	default:
+		// the gains and the losses from the previous data point, undefined for the first one
+		change := func(j int) float64 {
+			if j == 0 {
+				return math.NaN()
+			}
+			return drawing.Source.Price(ind.items[j]) - drawing.Source.Price(ind.items[j-1])
+		}
+		gain := func(j int) float64 { return math.Max(0, change(j)) }
+		loss := func(j int) float64 { return math.Max(0, -change(j)) }
+
+		// Wilder's smoothing, seeded with the simple averages of the first period
+		avggain, avgloss := ind.prev(oscGain, i), ind.prev(oscLoss, i)
+		if math.IsNaN(avggain) || math.IsNaN(avgloss) {
+			avggain = movingAverage(MA_SMA, drawing.Period, gain, i, math.NaN())
+			avgloss = movingAverage(MA_SMA, drawing.Period, loss, i, math.NaN())
+		} else {
+			n := float64(drawing.Period)
+			avggain = (avggain*(n-1) + gain(i)) / n
+			avgloss = (avgloss*(n-1) + loss(i)) / n
+		}
+		ind.lines[oscGain][i] = avggain
+		ind.lines[oscLoss][i] = avgloss
+		switch {
+		case math.IsNaN(avggain) || math.IsNaN(avgloss):
+			ind.lines[oscMain][i] = math.NaN()
+		case avgloss == 0 && avggain == 0:
+			ind.lines[oscMain][i] = 50
+		case avgloss == 0:
+			ind.lines[oscMain][i] = 100
+		default:
+			ind.lines[oscMain][i] = 100 - 100/(1+avggain/avgloss)
+		}
+	}
+}
+
+// Values returns the data points of the series and their oscillator, NaN if undefined.
+// signal is %D for the stochastic, nil for the RSI.
+// The oscillator is computed only for the data points appended or changed since the last call.
+func (drawing *DrawingOscillator) Values() (items []*DataStock, values []float64, signal []float64) {
+	if params := [5]int{int(drawing.Kind), drawing.Period, drawing.Smooth, drawing.SignalPeriod, int(drawing.Source)}; params != drawing.computed {
+		drawing.computed = params
+		drawing.osc.reset()
+	}
+	drawing.osc.update(drawing.series)
+	if drawing.Kind == OK_Stochastic {
+		signal = drawing.osc.lines[oscSignal]
+	}
+	return drawing.osc.items, drawing.osc.lines[oscMain], signal
+}
+
+// onRedraw draws the zones, the levels and the oscillator of the data points inside the xAxisRange.
+// The layer should have been cleared before.
+func (drawing *DrawingOscillator) onRedraw() {
+	items, values, signal := drawing.Values()
+	yrange := *drawing.yAxis()
+	if yrange.Delta() == 0 {
+		return
+	}
+	yfactor := float64(drawing.drawArea.Height) / yrange.Delta()
+	ybottom := float64(drawing.drawArea.End().Y)
+	yvalue := func(v float64) float64 {
+		return ybottom - yfactor*(v-yrange.Low())
+	}
+
+	// the overbought and the oversold zones, then their levels
+	if drawing.ShowZones {
+		x, w := float64(drawing.drawArea.O.X), float64(drawing.drawArea.Width)
+		drawing.Ctx2D.SetFillStyle(drawing.MainColor.Opacify(0.08))
+		drawing.Ctx2D.FillRect(x, yvalue(100), w, yvalue(drawing.Overbought)-yvalue(100))
+		drawing.Ctx2D.FillRect(x, yvalue(drawing.Oversold), w, yvalue(0)-yvalue(drawing.Oversold))
+	}
+	drawing.drawLevel(drawing.Overbought, drawing.MainColor.Opacify(0.5), yvalue)
+	drawing.drawLevel(drawing.Oversold, drawing.MainColor.Opacify(0.5), yvalue)
+
+	// one data point more on each side to draw the lines to the borders
+	first, last := drawing.osc.bounds(drawing.series.Window(*drawing.xAxisRange))
+	if first == last {
+		return
+	}
+	first, last = max(0, first-1), min(len(items), last+1)
+	if signal != nil {
+		drawing.drawLine(items[first:last], signal[first:last], drawing.SignalColor, drawing.LineWidth, yvalue)
+	}
+	drawing.drawLine(items[first:last], values[first:last], drawing.MainColor, drawing.LineWidth, yvalue)
+}
+
+// AddRSI adds a new pane below the chart, showing the RSI of the close of the MainSeries over period, 14 usually.
+// It is recomputed incrementally when data are appended or updated.
+func (pchart *StockChart) AddRSI(period int, color rgb.Color) *DrawingOscillator {
+	drawing := NewDrawingOscillator(&pchart.MainSeries, OK_RSI, period, color)
+	if pane := pchart.AddPane(drawing.Name, 0); pane != nil {
+		pane.AddDrawing(&drawing.Drawing)
+	}
+	return drawing
+}
+
+// AddStochastic adds a new pane below the chart, showing the stochastic of the MainSeries:
+// the highest high and the lowest low over period, 14 usually, %K smoothed over smooth and %D averaged over signal, 3 usually.
+// It is recomputed incrementally when data are appended or updated.
+func (pchart *StockChart) AddStochastic(period int, smooth int, signal int, color rgb.Color) *DrawingOscillator {
+	drawing := NewDrawingOscillator(&pchart.MainSeries, OK_Stochastic, period, color)
+	drawing.Smooth = smooth
+	drawing.SignalPeriod = signal
+	if pane := pchart.AddPane(drawing.Name, 0); pane != nil {
+		pane.AddDrawing(&drawing.Drawing)
+	}
+	return drawing
+}
@@ -0,0 +1,107 @@
+package stockchart
+
+import (
+	"math"
+	"testing"
+
+	"github.com/larry868/rgb"
+)
+
+func TestRSI(t *testing.T) {
+	series := testSeries(40)
+	rsi := NewDrawingOscillator(&series, OK_RSI, 5, rgb.Blue)
+	items, values, signal := rsi.Values()
+	if signal != nil || !math.IsNaN(values[4]) || math.IsNaN(values[5]) {
+		t.Fatalf("Values fails: values[4]=%v values[5]=%v", values[4], values[5])
+	}
+
+	// Wilder's smoothing, seeded with the simple averages of the first period
+	var gain, loss float64
+	for i := 1; i < len(items); i++ {
+		change := items[i].Close - items[i-1].Close
+		g, l := math.Max(0, change), math.Max(0, -change)
+		switch {
+		case i < 5:
+			gain, loss = gain+g, loss+l
+			continue
+		case i == 5:
+			gain, loss = (gain+g)/5, (loss+l)/5
+		default:
+			gain, loss = (gain*4+g)/5, (loss*4+l)/5
+		}
+		if want := 100 - 100/(1+gain/loss); !almostEqual(values[i], want) {
+			t.Fatalf("RSI at %d fails: want %v, get %v", i, want, values[i])
+		}
+	}
+}
+
+func TestStochastic(t *testing.T) {
+	series := testSeries(40)
+	stoch := NewDrawingOscillator(&series, OK_Stochastic, 5, rgb.Blue)
+	items, k, d := stoch.Values()
+	if stoch.Overbought != 80 || stoch.Oversold != 20 {
+		t.Errorf("NewDrawingOscillator fails: levels %v/%v", stoch.Overbought, stoch.Oversold)
+	}
+
+	raw := func(i int) float64 {
+		high, low := math.Inf(-1), math.Inf(1)
+		for _, item := range items[i-4 : i+1] {
+			high, low = math.Max(high, item.High), math.Min(low, item.Low)
+		}
+		return 100 * (items[i].Close - low) / (high - low)
+	}
+	if !math.IsNaN(k[5]) || math.IsNaN(k[6]) || !math.IsNaN(d[7]) || math.IsNaN(d[8]) {
+		t.Fatalf("Values fails: k[5]=%v k[6]=%v d[7]=%v d[8]=%v", k[5], k[6], d[7], d[8])
+	}
+	for i := 8; i < len(items); i++ {
+		wantk := (raw(i) + raw(i-1) + raw(i-2)) / 3
+		if !almostEqual(k[i], wantk) || !almostEqual(d[i], (k[i]+k[i-1]+k[i-2])/3) {
+			t.Fatalf("Stochastic at %d fails: get %%K=%v %%D=%v, want %%K=%v", i, k[i], d[i], wantk)
+		}
+		if k[i] < 0 || k[i] > 100 {
+			t.Fatalf("Stochastic at %d out of bounds: %v", i, k[i])
+		}
+	}
+}
+
+func TestOscillatorPanes(t *testing.T) {
+	chart, h := newTestChart(testSeries(100))
+	h.flushFrames()
+	main := chart.yAxisRange
+
+	rsi := chart.AddRSI(14, rgb.Blue)
+	chart.AddStochastic(14, 3, 3, rgb.Green)
+	h.flushFrames()
+	if len(chart.Panes()) != 2 || chart.Panes()[0].Name != "RSI14" || chart.Panes()[1].Name != "Stoch14" {
+		t.Fatalf("AddRSI and AddStochastic fail: %d panes", len(chart.Panes()))
+	}
+	if !chart.yAxisRange.Equal(main) {
+		t.Errorf("AddRSI fails: the y range of the chart changes from %v to %v", main, chart.yAxisRange)
+	}
+	for _, pane := range chart.Panes() {
+		if pane.yAxisRange.Low() != 0 || pane.yAxisRange.High() != 100 {
+			t.Errorf("pane %q fails: get y range %v, want 0-100", pane.Name, pane.yAxisRange)
+		}
+	}
+
+	// the guide lines are dashed, the zones are shaded
+	if chart.recorder(6).Count("SetLineDash") < 2 || chart.recorder(6).Count("FillRect") == 0 {
+		t.Errorf("AddRSI fails: the levels are not drawn")
+	}
+	found := false
+	for _, call := range chart.recorder(6).Filter("SetStrokeStyle") {
+		if call.Args[0] == rgb.Blue {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("AddRSI fails: the line is not drawn")
+	}
+
+	// live update
+	chart.UpdateHead(chart.MainSeries.Head.Close*2, 0)
+	h.flushFrames()
+	if _, values, _ := rsi.Values(); values[99] <= values[98] {
+		t.Errorf("UpdateHead fails: the RSI does not rise, %v then %v", values[98], values[99])
+	}
+}
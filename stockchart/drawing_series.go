@@ -1,14 +1,13 @@
 package stockchart
 
 import (
-	"github.com/gowebapi/webapi/core/js"
-	"github.com/gowebapi/webapi/html/canvas"
 	bootstrapcolor "github.com/larry868/rgb/bootstrapcolor.go"
 )
 
 type DrawingSeries struct {
 	Drawing
-	fFillArea bool // fullfil the area or draw only the line
+	fFillArea bool     // fullfil the area or draw only the line
+	GapStyle  GapStyle // how gaps are rendered, GS_None by default: the line bridges them
 
 	lastSelectedData *DataStock
 }
@@ -35,42 +34,54 @@ func NewDrawingSeries(series *DataList, fFillArea bool) *DrawingSeries {
 func (drawing *DrawingSeries) onRedraw() {
 
 	// get xfactor & yfactor according to time selection
-	xfactor := float64(drawing.drawArea.Width) / float64(drawing.xAxisRange.Duration().Duration)
+	xfactor := float64(drawing.drawArea.Width) / float64(drawing.xSpan(drawing.xAxisRange.From, drawing.xAxisRange.To))
 
 	yrange := drawing.series.DataRange(drawing.xAxisRange, 10)
 	yfactor := float64(drawing.drawArea.Height) / yrange.Delta()
 
 	// Debug(DBG_REDRAW, "%q drawarea:%s, xAxisRange:%v, xfactor:%f yfactor:%f", drawing.Name, drawing.drawArea, drawing.xAxisRange.String(), xfactor, yfactor)
 
+	// gaps are drawn behind the line, interrupted by breaks
+	items := decimateSpan(drawing.series.Window(*drawing.xAxisRange), drawing.xAxisRange.From, xfactor, drawing.xSpan)
+	if drawing.GapStyle == GS_Band {
+		drawing.drawGaps(items, GS_Band, drawing.MainColor, nil)
+	}
+
 	// setup drawing tools
-	drawing.Ctx2D.SetStrokeStyle(&canvas.Union{Value: js.ValueOf(drawing.MainColor.Hexa())})
+	drawing.Ctx2D.SetStrokeStyle(drawing.MainColor)
 	if drawing.fFillArea {
 		drawing.Ctx2D.SetLineWidth(3)
 	} else {
 		drawing.Ctx2D.SetLineWidth(2)
 	}
-	drawing.Ctx2D.SetLineJoin(canvas.RoundCanvasLineJoin)
-	drawing.Ctx2D.SetFillStyle(&canvas.Union{Value: js.ValueOf(drawing.MainColor.Lighten(0.8).Hexa())})
+	drawing.Ctx2D.SetLineJoin(LJ_Round)
+	drawing.Ctx2D.SetFillStyle(drawing.MainColor.Lighten(0.8))
 
 	// scan all points
 	var x0, xclose int
+	var prev *DataStock
 	first := true
-	item := drawing.series.Tail
-	for item != nil {
+	// points within the same pixel column are merged
+	for _, item := range items {
 		// skip items out of range
 		if item.TimeSlice.From.Before(drawing.xAxisRange.From) {
-			// scan next item
-			item = item.Next
 			continue
 		}
 		if item.TimeSlice.To.After(drawing.xAxisRange.To) {
 			break
 		}
 
+		// interrupt the path on gaps
+		if !first && drawing.GapStyle == GS_Break && drawing.isAxisGap(prev, item) {
+			drawing.closePath(x0, xclose)
+			first = true
+		}
+		prev = item
+
 		// draw the path
 		if first {
 			first = false
-			xopen := drawing.drawArea.O.X + int(xfactor*float64(item.TimeSlice.From.Sub(drawing.xAxisRange.From)))
+			xopen := drawing.drawArea.O.X + int(xfactor*float64(drawing.xSpan(drawing.xAxisRange.From, item.TimeSlice.From)))
 			yopen := drawing.drawArea.O.Y + drawing.drawArea.Height - int(yfactor*(item.Open-yrange.Low()))
 			drawing.Ctx2D.MoveTo(float64(xopen), float64(yopen))
 			drawing.Ctx2D.BeginPath()
@@ -78,14 +89,21 @@ func (drawing *DrawingSeries) onRedraw() {
 			x0 = xopen
 		}
 
-		xclose = drawing.drawArea.O.X + int(xfactor*float64(item.TimeSlice.To.Sub(drawing.xAxisRange.From)))
+		xclose = drawing.drawArea.O.X + int(xfactor*float64(drawing.xSpan(drawing.xAxisRange.From, item.TimeSlice.To)))
 		yclose := drawing.drawArea.O.Y + drawing.drawArea.Height - int(yfactor*(item.Close-yrange.Low()))
 		drawing.Ctx2D.LineTo(float64(xclose), float64(yclose))
+	}
+	drawing.closePath(x0, xclose)
 
-		// scan next item
-		item = item.Next
+	// draw selected data if any
+	if drawing.chart.selectedData != nil {
+		tsemiddle := drawing.chart.selectedData.Middle()
+		drawing.DrawVLine(tsemiddle, drawing.MainColor, true)
 	}
+}
 
+// closePath draws the top line of the current path, then the area from x0 to xclose
+func (drawing *DrawingSeries) closePath(x0 int, xclose int) {
 	// draw the top line
 	drawing.Ctx2D.Stroke()
 
@@ -94,13 +112,6 @@ func (drawing *DrawingSeries) onRedraw() {
 		drawing.Ctx2D.LineTo(float64(xclose), float64(drawing.drawArea.End().Y))
 		drawing.Ctx2D.LineTo(float64(x0), float64(drawing.drawArea.End().Y))
 		drawing.Ctx2D.ClosePath()
-		fillrule := canvas.NonzeroCanvasFillRule
-		drawing.Ctx2D.Fill(&fillrule)
-	}
-
-	// draw selected data if any
-	if drawing.chart.selectedData != nil {
-		tsemiddle := drawing.chart.selectedData.Middle()
-		drawing.DrawVLine(tsemiddle, drawing.MainColor, true)
+		drawing.Ctx2D.Fill()
 	}
 }
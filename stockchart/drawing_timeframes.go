@@ -0,0 +1,72 @@
+package stockchart
+
+import (
+	"github.com/larry868/rgb"
+	bootstrapcolor "github.com/larry868/rgb/bootstrapcolor.go"
+)
+
+// DrawingTimeframes is the selector of the timeframes of the chart, at the top right of the chart.
+// It's drawn only if the chart has timeframes and shows its selector, see SetTimeframes.
+type DrawingTimeframes struct {
+	Drawing
+}
+
+func NewDrawingTimeframes(series *DataList) *DrawingTimeframes {
+	drawing := new(DrawingTimeframes)
+	drawing.Name = "timeframes"
+	drawing.series = series
+	drawing.MainColor = bootstrapcolor.Blue
+
+	drawing.Drawing.OnRedraw = func() {
+		drawing.onRedraw()
+	}
+	drawing.Drawing.OnClick = func(xy Point, event *MouseEvent) {
+		drawing.onClick(xy)
+	}
+	return drawing
+}
+
+// draw a button per timeframe, from the right to the left, the current one is highlighted
+func (drawing *DrawingTimeframes) onRedraw() {
+	chart := drawing.chart
+	chart.timeframeButtons = nil
+	if !chart.showTimeframes || len(chart.timeframes) == 0 {
+		return
+	}
+	chart.timeframeButtons = make([]Rect, len(chart.timeframes))
+	drawing.Ctx2D.SetFont(`12px 'Roboto', sans-serif`)
+	xy := Point{X: drawing.drawArea.End().X - 10, Y: drawing.drawArea.O.Y + 5}
+	for i := len(chart.timeframes) - 1; i >= 0; i-- {
+		tf := &chart.timeframes[i]
+		bgcolor, textcolor := rgb.White.Opacify(0.9), drawing.MainColor
+		if tf == chart.timeframe {
+			bgcolor, textcolor = drawing.MainColor, rgb.White
+		}
+		button := drawing.DrawTextBox(tf.Label, xy, AlignEnd|AlignTop, bgcolor, textcolor, 0, 1, 4)
+		chart.timeframeButtons[i] = button
+		xy.X = button.O.X - 4
+	}
+}
+
+// switch the timeframe, the chart is fully redrawn.
+// Changes of the selection are notified by the event dispatcher.
+func (drawing *DrawingTimeframes) onClick(xy Point) {
+	chart := drawing.chart
+	for i, button := range chart.timeframeButtons {
+		if !xy.IsIn(button) {
+			continue
+		}
+		tf := chart.timeframes[i]
+		if chart.timeframe != nil && tf.Precision == chart.timeframe.Precision {
+			return
+		}
+		if err := chart.DoChangeTimeframe(tf.Precision, false); err != nil {
+			Debug(DBG_EVENT, "%q OnClick: %s", drawing.Name, err)
+			return
+		}
+		if chart.NotifyChangeTimeframe != nil {
+			chart.NotifyChangeTimeframe(tf)
+		}
+		return
+	}
+}
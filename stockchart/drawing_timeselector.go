@@ -3,10 +3,6 @@ package stockchart
 import (
 	"time"
 
-	"github.com/gowebapi/webapi/core/js"
-	"github.com/gowebapi/webapi/css/typedom"
-	"github.com/gowebapi/webapi/html/canvas"
-	"github.com/gowebapi/webapi/html/htmlevent"
 	bootstrapcolor "github.com/larry868/rgb/bootstrapcolor.go"
 	timeline "github.com/larry868/timeline/v2"
 )
@@ -45,21 +41,21 @@ func NewDrawingTimeSelector(series *DataList) *DrawingTimeSelector {
 		// Debug(DBG_REDRAW, "%q OnRedraw drawarea:%s, xAxisRange:%v", drawing.Name, drawing.drawArea, drawing.xAxisRange.String())
 		drawing.onRedraw()
 	}
-	drawing.Drawing.OnMouseDown = func(xy Point, event *htmlevent.MouseEvent) {
+	drawing.Drawing.OnMouseDown = func(xy Point, event *MouseEvent) {
 		// Debug(DBG_EVENT, "%q OnMouseDown xy:%v, frombutton:%v, tobutton:%v", drawing.Name, xy, drawing.buttonFrom, drawing.buttonTo)
 		drawing.onMouseDown(xy, event)
 	}
-	drawing.Drawing.OnMouseUp = func(xy Point, event *htmlevent.MouseEvent) {
+	drawing.Drawing.OnMouseUp = func(xy Point, event *MouseEvent) {
 		drawing.onMouseUp(xy, event)
 	}
-	drawing.Drawing.OnMouseMove = func(xy Point, event *htmlevent.MouseEvent) {
+	drawing.Drawing.OnMouseMove = func(xy Point, event *MouseEvent) {
 		//	Debug(DBG_EVENT, fmt.Sprintf("%q OnMouseMove xy:%v", drawing.Name, xy))
 		drawing.onMouseMove(xy, event)
 	}
-	drawing.Drawing.OnMouseLeave = func(xy Point, event *htmlevent.MouseEvent) {
+	drawing.Drawing.OnMouseLeave = func(xy Point, event *MouseEvent) {
 		drawing.onMouseUp(xy, event)
 	}
-	drawing.Drawing.OnWheel = func(event *htmlevent.WheelEvent) {
+	drawing.Drawing.OnWheel = func(event *WheelEvent) {
 		drawing.onWheel(event)
 	}
 	drawing.Drawing.NeedRedraw = func() bool {
@@ -78,24 +74,24 @@ func (drawing *DrawingTimeSelector) onRedraw() {
 	ycenter := float64(drawing.drawArea.O.Y) + float64(drawing.drawArea.Height)/2.0
 
 	// draw the left selector
-	xleftrate := drawing.xAxisRange.Progress(drawing.dragtimeSelection.From)
+	xleftrate := drawing.xProgress(drawing.dragtimeSelection.From)
 	xposleft := float64(drawing.drawArea.O.X) + float64(drawing.drawArea.Width)*xleftrate
-	drawing.Ctx2D.SetFillStyle(&canvas.Union{Value: js.ValueOf(drawing.MainColor.Opacify(0.4).Hexa())})
+	drawing.Ctx2D.SetFillStyle(drawing.MainColor.Opacify(0.4))
 	drawing.Ctx2D.FillRect(float64(drawing.drawArea.O.X), float64(drawing.drawArea.O.Y), xposleft, float64(drawing.drawArea.Height))
 	moveButton(drawing, &drawing.buttonFrom, xposleft, ycenter)
 
 	// draw the right selector
-	xrightrate := drawing.xAxisRange.Progress(drawing.dragtimeSelection.To)
+	xrightrate := drawing.xProgress(drawing.dragtimeSelection.To)
 	xposright := float64(drawing.drawArea.O.X) + float64(drawing.drawArea.Width)*xrightrate
-	drawing.Ctx2D.SetFillStyle(&canvas.Union{Value: js.ValueOf(drawing.MainColor.Opacify(0.4).Hexa())})
+	drawing.Ctx2D.SetFillStyle(drawing.MainColor.Opacify(0.4))
 	drawing.Ctx2D.FillRect(xposright, float64(drawing.drawArea.O.Y), float64(drawing.drawArea.Width)-xposright, float64(drawing.drawArea.Height))
 	moveButton(drawing, &drawing.buttonTo, xposright, ycenter)
 }
 
 // moveButton utility
 func moveButton(layer *DrawingTimeSelector, button *Rect, xcenter float64, ycenter float64) {
-	layer.Ctx2D.SetFillStyle(&canvas.Union{Value: js.ValueOf(bootstrapcolor.Gray.Hexa())})
-	layer.Ctx2D.SetStrokeStyle(&canvas.Union{Value: js.ValueOf(bootstrapcolor.Gray.Hexa())})
+	layer.Ctx2D.SetFillStyle(bootstrapcolor.Gray)
+	layer.Ctx2D.SetStrokeStyle(bootstrapcolor.Gray)
 	layer.Ctx2D.SetLineWidth(1)
 	x0 := xcenter - float64(button.Width)/2
 	y0 := ycenter - float64(button.Height)/2
@@ -106,7 +102,7 @@ func moveButton(layer *DrawingTimeSelector, button *Rect, xcenter float64, ycent
 }
 
 // OnMouseDown starts dragging
-func (drawing *DrawingTimeSelector) onMouseDown(xy Point, event *htmlevent.MouseEvent) {
+func (drawing *DrawingTimeSelector) onMouseDown(xy Point, event *MouseEvent) {
 
 	// if already dragging and reenter into the canvas
 	if drawing.dragFrom || drawing.dragTo || drawing.dragShift {
@@ -124,10 +120,10 @@ func (drawing *DrawingTimeSelector) onMouseDown(xy Point, event *htmlevent.Mouse
 		drawing.dragShift = true
 
 		if !drawing.isCursorGrab {
-			drawing.Ctx2D.Canvas().AttributeStyleMap().Set("cursor", &typedom.Union{Value: js.ValueOf(`grab`)})
+			drawing.setCursor(`grab`)
 			drawing.isCursorGrab = true
 			xrate := drawing.drawArea.XRate(xy.X)
-			postime := drawing.xAxisRange.WhatTime(xrate)
+			postime := drawing.xWhatTime(xrate)
 			postime = drawing.xAxisRange.Bound(postime)
 			drawing.dragShiftlasttime = postime
 		}
@@ -137,7 +133,7 @@ func (drawing *DrawingTimeSelector) onMouseDown(xy Point, event *htmlevent.Mouse
 // OnMouseUp Stops Dragging and update the chart.timeSelection.
 //
 // If the timeselection changes then the event dispatcher will call OnChangeTimeSelection on all drawings of all layers.
-func (drawing *DrawingTimeSelector) onMouseUp(xy Point, event *htmlevent.MouseEvent) {
+func (drawing *DrawingTimeSelector) onMouseUp(xy Point, event *MouseEvent) {
 
 	// update the chart time selection
 	drawing.chart.selectedTimeSlice = drawing.dragtimeSelection
@@ -151,7 +147,7 @@ func (drawing *DrawingTimeSelector) onMouseUp(xy Point, event *htmlevent.MouseEv
 
 	// reset the cursor if needed
 	if drawing.isCursorGrab {
-		drawing.Ctx2D.Canvas().AttributeStyleMap().Set("cursor", &typedom.Union{Value: js.ValueOf(`auto`)})
+		drawing.setCursor(`auto`)
 		drawing.isCursorGrab = false
 		drawing.dragShiftlasttime = time.Time{}
 	}
@@ -161,17 +157,17 @@ func (drawing *DrawingTimeSelector) onMouseUp(xy Point, event *htmlevent.MouseEv
 //
 // If the local timeselection changes then redraw this drawing only
 // the event dispatcher won't call DoChange as the chart selection has not changed
-func (drawing *DrawingTimeSelector) onMouseMove(xy Point, event *htmlevent.MouseEvent) {
+func (drawing *DrawingTimeSelector) onMouseMove(xy Point, event *MouseEvent) {
 
 	// change cursor if we start overing a button
 	if (xy.IsIn(drawing.buttonFrom) || xy.IsIn(drawing.buttonTo)) && !drawing.isCursorResize {
-		drawing.Ctx2D.Canvas().AttributeStyleMap().Set("cursor", &typedom.Union{Value: js.ValueOf(`col-resize`)})
+		drawing.setCursor(`col-resize`)
 		drawing.isCursorResize = true
 	}
 
 	// change cursor if we leave a button
 	if (!xy.IsIn(drawing.buttonFrom) && !xy.IsIn(drawing.buttonTo)) && drawing.isCursorResize {
-		drawing.Ctx2D.Canvas().AttributeStyleMap().Set("cursor", &typedom.Union{Value: js.ValueOf(`auto`)})
+		drawing.setCursor(`auto`)
 		drawing.isCursorResize = false
 	}
 
@@ -180,7 +176,7 @@ func (drawing *DrawingTimeSelector) onMouseMove(xy Point, event *htmlevent.Mouse
 		// change the boundary of the selector
 		// get and bound the position of the cursor within xAxisRange
 		xrate := drawing.drawArea.XRate(xy.X)
-		postime := drawing.xAxisRange.WhatTime(xrate)
+		postime := drawing.xWhatTime(xrate)
 		postime = drawing.xAxisRange.Bound(postime)
 
 		if drawing.dragFrom {
@@ -198,9 +194,8 @@ func (drawing *DrawingTimeSelector) onMouseMove(xy Point, event *htmlevent.Mouse
 		} else if drawing.dragShift {
 			// shift the selection according to the position of the grab selector
 			if !postime.Equal(drawing.dragShiftlasttime) {
-				shiftdur := postime.Sub(drawing.dragShiftlasttime)
+				drawing.xShiftIn(&drawing.dragtimeSelection, drawing.dragShiftlasttime, postime)
 				drawing.dragShiftlasttime = postime
-				drawing.dragtimeSelection.ShiftIn(shiftdur, *drawing.xAxisRange)
 				fRedraw = true
 			}
 		}
@@ -214,10 +209,10 @@ func (drawing *DrawingTimeSelector) onMouseMove(xy Point, event *htmlevent.Mouse
 }
 
 // OnWheel manage zoom and shifting the time selection
-func (drawing *DrawingTimeSelector) onWheel(event *htmlevent.WheelEvent) {
+func (drawing *DrawingTimeSelector) onWheel(event *WheelEvent) {
 	// get the wheel move
 	dir := time.Duration(0)
-	dy := event.DeltaY()
+	dy := event.DeltaY
 	if dy > 0 {
 		dir = -1
 	} else if dy < 0 {
@@ -229,9 +224,9 @@ func (drawing *DrawingTimeSelector) onWheel(event *htmlevent.WheelEvent) {
 
 	// define a good timestep: 20% of the current duration
 	timeStep := drawing.dragtimeSelection.Duration().Adjust(0.2).Duration
-	// Debug(DBG_EVENT, "%q OnWheel, shiftKey:%v, dy:%f, timeStep:%v", drawing.Name, event.ShiftKey(), dy, timeStep)
+	// Debug(DBG_EVENT, "%q OnWheel, shiftKey:%v, dy:%f, timeStep:%v", drawing.Name, event.ShiftKey, dy, timeStep)
 
-	if !event.ShiftKey() {
+	if !event.ShiftKey {
 		// shift mode, shift to the future or to the past according to dir
 		drawing.dragtimeSelection.ShiftIn(timeStep*dir, *drawing.xAxisRange)
 	} else {
@@ -3,8 +3,6 @@ package stockchart
 import (
 	"math"
 
-	"github.com/gowebapi/webapi/core/js"
-	"github.com/gowebapi/webapi/html/canvas"
 	"github.com/larry868/rgb"
 	bootstrapcolor "github.com/larry868/rgb/bootstrapcolor.go"
 	timeline "github.com/larry868/timeline/v2"
@@ -36,7 +34,7 @@ func NewDrawingVLines(series *DataList, timeSelDependant bool) *DrawingVLines {
 // OnRedraw DrawingXGrid
 func (drawing DrawingVLines) onRedraw() {
 	// get xy factors
-	xfactor := float64(drawing.drawArea.Width) / float64(drawing.xAxisRange.Duration().Duration)
+	xfactor := float64(drawing.drawArea.Width) / float64(drawing.xSpan(drawing.xAxisRange.From, drawing.xAxisRange.To))
 
 	// Debug(DBG_REDRAW, "%q OnRedraw drawarea:%s, xfactor:%f yfactor:%f style:%v", drawing.Name, drawing.drawArea, xfactor, yfactor, drawing.DrawStyle)
 	// Debug(DBG_REDRAW, "%q OnRedraw serie:%v seltime:%s, yrange;%s", drawing.Name, drawing.series.String(), drawing.xAxisRange, yrange)
@@ -44,11 +42,11 @@ func (drawing DrawingVLines) onRedraw() {
 
 	// drawing style
 	drawing.Ctx2D.SetLineWidth(1)
-	drawing.Ctx2D.SetLineCap(canvas.ButtCanvasLineCap)
-	drawing.Ctx2D.SetLineJoin(canvas.MiterCanvasLineJoin)
+	drawing.Ctx2D.SetLineCap(LC_Butt)
+	drawing.Ctx2D.SetLineJoin(LJ_Miter)
 	drawing.Ctx2D.SetLineDash([]float64{})
-	drawing.Ctx2D.SetStrokeStyle(&canvas.Union{Value: js.ValueOf(drawing.MainColor.Hexa())})
-	drawing.Ctx2D.SetFillStyle(&canvas.Union{Value: js.ValueOf(drawing.MainColor.Hexa())})
+	drawing.Ctx2D.SetStrokeStyle(drawing.MainColor)
+	drawing.Ctx2D.SetFillStyle(drawing.MainColor)
 	drawing.Ctx2D.BeginPath()
 
 	var wcf64, xcf64, ycf64 float64
@@ -56,13 +54,11 @@ func (drawing DrawingVLines) onRedraw() {
 
 	// scan all points forward !
 	drbottomf64 := float64(drawing.drawArea.O.Y + drawing.drawArea.Height)
-	item := drawing.series.Tail
-	for item != nil {
+	for _, item := range drawing.series.Window(*drawing.xAxisRange) {
 		// skip items before xAxisRange boundary or without duration.
 		// skip items after xAxisRange boundary.
 		// Do not break because series are not always sorted chronologicaly
 		if item.IsInfinite() || item.Duration().Duration == 0 || item.To.Before(drawing.xAxisRange.From) || item.From.After(drawing.xAxisRange.To) {
-			item = item.Next
 			continue
 		}
 
@@ -74,7 +70,7 @@ func (drawing DrawingVLines) onRedraw() {
 		}
 
 		// candle size a pos
-		wcf64 = fmax(1.0, math.Round(xfactor*float64(item.Duration().Duration)))
+		wcf64 = fmax(1.0, math.Round(xfactor*float64(drawing.xSpan(item.From, item.To))))
 		xcf64 = drawing.xTime(item.From)
 		ycf64 = drbottomf64 + 5 - 10*overlap
 
@@ -88,9 +84,7 @@ func (drawing DrawingVLines) onRedraw() {
 			drawing.DrawTextBox(item.Label, Point{X: int(xcf64), Y: int(ycf64 - 1)}, AlignStart|AlignBottom, rgb.White.Opacify(0.5), drawing.MainColor, 0, 0, 0)
 		}
 
-		// scan next item
 		drawnts = append(drawnts, item.TimeSlice)
-		item = item.Next
 	}
 	drawing.Ctx2D.Stroke()
 
@@ -3,15 +3,14 @@ package stockchart
 import (
 	"time"
 
-	"github.com/gowebapi/webapi/core/js"
-	"github.com/gowebapi/webapi/html/canvas"
 	"github.com/larry868/rgb"
 	timeline "github.com/larry868/timeline/v2"
 )
 
 type DrawingXGrid struct {
 	Drawing
-	fFullGrid bool // draw grids otherwise only labels
+	fFullGrid  bool // draw grids otherwise only labels
+	fLinesOnly bool // draw only the grid lines, without the time labels
 
 	lastlocalZone         bool
 	lastSelectedTimeslice timeline.TimeSlice
@@ -42,6 +41,10 @@ func (drawing DrawingXGrid) onRedraw() {
 	// define the grid scale
 	const minxstepwidth = 100.0
 	maxscans := float64(drawing.drawArea.Width) / minxstepwidth
+	if cal := drawing.tradingCalendar(); cal != nil {
+		// the non-trading periods are skipped, so more steps are needed
+		maxscans *= float64(drawing.xAxisRange.Duration().Duration) / float64(drawing.xSpan(drawing.xAxisRange.From, drawing.xAxisRange.To))
+	}
 	maskmain := drawing.xAxisRange.GetScanMask(uint(maxscans))
 
 	// Debug(DBG_REDRAW, "%q OnRedraw drawarea:%s, xAxisRange:%v, maskmain=%v", drawing.Name, drawing.drawArea, drawing.xAxisRange.String(), maskmain)
@@ -51,8 +54,8 @@ func (drawing DrawingXGrid) onRedraw() {
 	}
 
 	// setup default text drawing properties
-	drawing.Ctx2D.SetTextAlign(canvas.StartCanvasTextAlign)
-	drawing.Ctx2D.SetTextBaseline(canvas.BottomCanvasTextBaseline)
+	drawing.Ctx2D.SetTextAlign(TA_Start)
+	drawing.Ctx2D.SetTextBaseline(TB_Bottom)
 	drawing.Ctx2D.SetFont(`10px 'Roboto', sans-serif`)
 
 	// set fillstyle for the grid lines
@@ -66,7 +69,7 @@ func (drawing DrawingXGrid) onRedraw() {
 	// draw the second grid, before the main grid because it can overlay
 	if maskmain > timeline.MASK_SHORTEST {
 
-		drawing.Ctx2D.SetFillStyle(&canvas.Union{Value: js.ValueOf(gSecondColor.Hexa())})
+		drawing.Ctx2D.SetFillStyle(gSecondColor)
 
 		fh := -float64(drawing.drawArea.Height)
 		if !drawing.fFullGrid {
@@ -74,51 +77,29 @@ func (drawing DrawingXGrid) onRedraw() {
 		}
 
 		// scan the full xAxisRange every sub-level mask step
-		var xtime time.Time
-		for drawing.xAxisRange.Scan(&xtime, maskmain-1, true); !xtime.IsZero(); drawing.xAxisRange.Scan(&xtime, maskmain-1, false) {
-
-			if drawing.chart.localZone {
-				xtime = xtime.Local()
-			} else {
-				xtime = xtime.UTC()
-			}
-
-			// calculate xpos. if the timeslice is a single date then draw a single bar at the middle
-			xtimerate := drawing.xAxisRange.Progress(xtime)
-			xpos := drawing.drawArea.O.X + int(float64(drawing.drawArea.Width)*xtimerate)
-
+		for _, tick := range drawing.ticks(maskmain - 1) {
 			// draw the grid
-			drawing.Ctx2D.FillRect(float64(xpos), float64(drawing.drawArea.O.Y+drawing.drawArea.Height), 1.0, fh)
+			drawing.Ctx2D.FillRect(float64(tick.xpos), float64(drawing.drawArea.O.Y+drawing.drawArea.Height), 1.0, fh)
 		}
 	}
 
 	// draw the main grid
 	lastlabelend := 0
-	var xtime, lastxtime time.Time
-	for drawing.xAxisRange.Scan(&xtime, maskmain, true); !xtime.IsZero(); drawing.xAxisRange.Scan(&xtime, maskmain, false) {
-
-		if drawing.chart.localZone {
-			xtime = xtime.Local()
-		} else {
-			xtime = xtime.UTC()
-		}
-
-		// calculate xpos.
-		// if the timeslice is a single date then draw a single bar at the middle
-		xtimerate := drawing.xAxisRange.Progress(xtime)
-		xpos := drawing.drawArea.O.X + int(float64(drawing.drawArea.Width)*xtimerate)
+	var lastxtime time.Time
+	for _, tick := range drawing.ticks(maskmain) {
+		xtime, xpos := tick.at, tick.xpos
 
 		// draw the main grid line
-		drawing.Ctx2D.SetFillStyle(&canvas.Union{Value: js.ValueOf(gMainColor.Hexa())})
+		drawing.Ctx2D.SetFillStyle(gMainColor)
 		drawing.Ctx2D.FillRect(float64(xpos), float64(drawing.drawArea.O.Y+drawing.drawArea.Height), 1.0, -float64(drawing.drawArea.Height))
 
 		// draw time label if not overlapping last label
-		if (xpos + 2) > lastlabelend {
+		if !drawing.fLinesOnly && (xpos+2) > lastlabelend {
 			strdtefmt := maskmain.GetTimeFormat(xtime, lastxtime)
 			label := xtime.Format(strdtefmt)
-			drawing.Ctx2D.SetFillStyle(&canvas.Union{Value: js.ValueOf(gLabelColor.Hexa())})
-			drawing.Ctx2D.FillText(label, float64(xpos+2), float64(drawing.drawArea.End().Y)-1, nil)
-			lastlabelend = xpos + 2 + int(drawing.Ctx2D.MeasureText(label).Width())
+			drawing.Ctx2D.SetFillStyle(gLabelColor)
+			drawing.Ctx2D.FillText(label, float64(xpos+2), float64(drawing.drawArea.End().Y)-1)
+			lastlabelend = xpos + 2 + int(drawing.Ctx2D.MeasureText(label).Width)
 		}
 
 		// scan next
@@ -129,7 +110,7 @@ func (drawing DrawingXGrid) onRedraw() {
 	xpos := drawing.DrawVLine(drawing.series.Head.To, drawing.MainColor.Opacify(0.5), true)
 
 	// draw the ending date
-	if xpos >= 0 {
+	if !drawing.fLinesOnly && xpos >= 0 {
 
 		strdtefmt := timeline.MASK_SHORTEST.GetTimeFormat(drawing.series.Head.To, time.Time{})
 		var strtime string
@@ -143,3 +124,35 @@ func (drawing DrawingXGrid) onRedraw() {
 	}
 
 }
+
+// xtick is a time of the grid and its x position
+type xtick struct {
+	at   time.Time
+	xpos int
+}
+
+// ticks scans the full xAxisRange every mask step, in the zone of the chart.
+// With a trading calendar, ticks in the same non-trading period share the same position, only the last one is kept.
+func (drawing DrawingXGrid) ticks(mask timeline.TimeMask) (ticks []xtick) {
+	fcollapse := drawing.tradingCalendar() != nil
+	var xtime time.Time
+	for drawing.xAxisRange.Scan(&xtime, mask, true); !xtime.IsZero(); drawing.xAxisRange.Scan(&xtime, mask, false) {
+
+		if drawing.chart.localZone {
+			xtime = xtime.Local()
+		} else {
+			xtime = xtime.UTC()
+		}
+
+		// calculate xpos. if the timeslice is a single date then draw a single bar at the middle
+		xtimerate := drawing.xProgress(xtime)
+		xpos := drawing.drawArea.O.X + int(float64(drawing.drawArea.Width)*xtimerate)
+
+		if n := len(ticks); fcollapse && n > 0 && ticks[n-1].xpos == xpos {
+			ticks[n-1] = xtick{at: xtime, xpos: xpos}
+			continue
+		}
+		ticks = append(ticks, xtick{at: xtime, xpos: xpos})
+	}
+	return ticks
+}
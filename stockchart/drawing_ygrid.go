@@ -1,8 +1,8 @@
 package stockchart
 
 import (
-	"github.com/gowebapi/webapi/core/js"
-	"github.com/gowebapi/webapi/html/canvas"
+	"math"
+
 	"github.com/larry868/datarange"
 	"github.com/larry868/rgb"
 )
@@ -22,28 +22,53 @@ func NewDrawingYGrid(series *DataList, fscale bool) *DrawingYGrid {
 
 	drawing.Drawing.OnRedraw = func() {
 		//	yrange := drawing.series.DataRange(drawing.xAxisRange, 10)
-		drawing.chart.yAxisRange = drawing.series.DataRange(&drawing.chart.selectedTimeSlice, 10)
-		drawing.lastyrange = drawing.chart.yAxisRange
+		*drawing.yAxis() = drawing.yRange()
+		drawing.lastyrange = *drawing.yAxis()
 		// Debug(DBG_REDRAW, "%q OnRedraw drawarea:%s, xAxisRange:%v, datarange:%v", drawing.Name, drawing.drawArea, drawing.xAxisRange.String(), drawing.chart.yAxisRange)
 		drawing.onRedraw()
 	}
 	drawing.Drawing.NeedRedraw = func() bool {
-		ynewrange := drawing.series.DataRange(&drawing.chart.selectedTimeSlice, 10)
+		ynewrange := drawing.yRange()
 		return !ynewrange.Equal(drawing.lastyrange)
 	}
 	return drawing
 }
 
+// yRange returns the data range of the series within the selected time slice,
+// extended to the extremes of the drawings of the chart included in the autoscale.
+// On a pane, returns the data range of the drawings of the pane.
+func (drawing DrawingYGrid) yRange() datarange.DataRange {
+	if drawing.pane != nil {
+		return drawing.pane.yRange()
+	}
+	sel := drawing.chart.selectedTimeSlice
+	e := drawing.series.extremes(&sel)
+	for _, layer := range drawing.chart.layers {
+		if layer == nil || layer.pane != nil {
+			continue
+		}
+		for _, dr := range layer.drawings {
+			if dr.YExtremes == nil || !dr.hasNonEmptySeries() {
+				continue
+			}
+			if low, high := dr.YExtremes(sel); !math.IsNaN(low) && !math.IsNaN(high) {
+				e = e.merge(extremes{low: low, high: high})
+			}
+		}
+	}
+	return datarange.Make(e.low, math.Max(0, e.high), -10, drawing.series.Name)
+}
+
 // OnRedraw redraw the Y axis
 func (drawing DrawingYGrid) onRedraw() {
 
 	// setup default text drawing properties
-	drawing.Ctx2D.SetTextAlign(canvas.StartCanvasTextAlign)
-	drawing.Ctx2D.SetTextBaseline(canvas.MiddleCanvasTextBaseline)
+	drawing.Ctx2D.SetTextAlign(TA_Start)
+	drawing.Ctx2D.SetTextBaseline(TB_Middle)
 	drawing.Ctx2D.SetFont(`12px 'Roboto', sans-serif`)
 
 	// draw the Y Scale
-	yrange := drawing.chart.yAxisRange
+	yrange := *drawing.yAxis()
 	for val := yrange.High(); val >= yrange.Low() && yrange.StepSize() > 0; val -= yrange.StepSize() {
 
 		// calculate ypos
@@ -52,7 +77,7 @@ func (drawing DrawingYGrid) onRedraw() {
 		ypos = float64(drawing.drawArea.BoundY(int(ypos)))
 
 		// draw the grid line
-		drawing.Ctx2D.SetFillStyle(&canvas.Union{Value: js.ValueOf(drawing.MainColor.Hexa())})
+		drawing.Ctx2D.SetFillStyle(drawing.MainColor)
 		linew := 10.0
 		if !drawing.fScale {
 			linew = float64(drawing.drawArea.Width)
@@ -62,8 +87,8 @@ func (drawing DrawingYGrid) onRedraw() {
 		// draw yscale label
 		if drawing.fScale {
 			strvalue := datarange.FormatData(val, yrange.StepSize()) // fmt.Sprintf("%.1f", val)
-			drawing.Ctx2D.SetFillStyle(&canvas.Union{Value: js.ValueOf(rgb.Gray.Darken(0.5).Hexa())})
-			drawing.Ctx2D.FillText(strvalue, float64(drawing.drawArea.O.Y+7), ypos+1, nil)
+			drawing.Ctx2D.SetFillStyle(rgb.Gray.Darken(0.5))
+			drawing.Ctx2D.FillText(strvalue, float64(drawing.drawArea.O.Y+7), ypos+1)
 		}
 	}
 }
@@ -1,5 +1,20 @@
 package stockchart
 
+// MouseEvent is a mouse event dispatched to drawings, whatever the host of the chart
+type MouseEvent struct {
+	ShiftKey bool
+	CtrlKey  bool
+	AltKey   bool
+	Button   int // the button pressed: 0 main, 1 auxiliary, 2 secondary
+}
+
+// WheelEvent is a wheel event dispatched to drawings, whatever the host of the chart
+type WheelEvent struct {
+	MouseEvent
+	DeltaX float64
+	DeltaY float64
+}
+
 type evtHandler int
 
 const (
@@ -41,3 +56,22 @@ func (layer *Layer) HandledEvents() evtHandler {
 	}
 	return e
 }
+
+// mouseHandler returns the drawing function handling the evt mouse event, nil if none
+func (drawing *Drawing) mouseHandler(evt evtHandler) func(xy Point, event *MouseEvent) {
+	switch evt {
+	case evt_MouseUp:
+		return drawing.OnMouseUp
+	case evt_MouseDown:
+		return drawing.OnMouseDown
+	case evt_MouseMove:
+		return drawing.OnMouseMove
+	case evt_MouseEnter:
+		return drawing.OnMouseEnter
+	case evt_MouseLeave:
+		return drawing.OnMouseLeave
+	case evt_Click:
+		return drawing.OnClick
+	}
+	return nil
+}
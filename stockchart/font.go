@@ -0,0 +1,80 @@
+package stockchart
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// fontFaces is a cache of font faces, used by renderers without any browser to render or measure texts.
+// The Go fonts are used whatever the family requested, only the size in px and the bold weight are taken into account.
+type fontFaces map[string]font.Face
+
+// face returns the font face corresponding to a CSS font like `bold 12px 'Roboto', sans-serif`
+func (faces fontFaces) face(cssfont string) font.Face {
+	size, bold := parseCSSFont(cssfont)
+	key := fmt.Sprintf("%v-%v", size, bold)
+	if face, found := faces[key]; found {
+		return face
+	}
+	face, err := opentype.NewFace(goFont(bold), &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingNone})
+	if err != nil {
+		log.Printf("unable to create the font face %q: %s", cssfont, err)
+		return basicfont.Face7x13
+	}
+	faces[key] = face
+	return face
+}
+
+// measureText returns the metrics of txt rendered with face
+func measureText(face font.Face, txt string) TextMetrics {
+	bounds, advance := font.BoundString(face, txt)
+	return TextMetrics{
+		Width:   fixedToFloat(advance),
+		Ascent:  -fixedToFloat(bounds.Min.Y),
+		Descent: fixedToFloat(bounds.Max.Y)}
+}
+
+var (
+	goFontsOnce sync.Once
+	goRegular   *opentype.Font
+	goBold      *opentype.Font
+)
+
+// goFont returns the Go regular or bold font, parsed only once
+func goFont(bold bool) *opentype.Font {
+	goFontsOnce.Do(func() {
+		goRegular, _ = opentype.Parse(goregular.TTF)
+		goBold, _ = opentype.Parse(gobold.TTF)
+	})
+	if bold {
+		return goBold
+	}
+	return goRegular
+}
+
+var cssFontSize = regexp.MustCompile(`(\d+(\.\d+)?)px`)
+
+// parseCSSFont extracts the size in px and the bold weight of a CSS font. The size is 10px by default.
+func parseCSSFont(cssfont string) (size float64, bold bool) {
+	size = 10
+	if m := cssFontSize.FindStringSubmatch(cssfont); m != nil {
+		size, _ = strconv.ParseFloat(m[1], 64)
+	}
+	bold = strings.Contains(cssfont, "bold")
+	return size, bold
+}
+
+func fixedToFloat(f fixed.Int26_6) float64 {
+	return float64(f) / 64.0
+}
@@ -0,0 +1,249 @@
+package stockchart
+
+import (
+	"flag"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/larry868/rgb"
+)
+
+// go test -run TestGolden -update regenerates the golden images
+var fUpdate = flag.Bool("update", false, "update the golden images of the drawings")
+
+const (
+	goldenTolerance = 24    // max difference of a channel for a pixel to be considered unchanged
+	goldenMaxRate   = 0.002 // max rate of changed pixels
+)
+
+// renderDrawings renders drawings on a single offscreen layer of w x h pixels, showing the whole series.
+// Returns the image composed with a white background.
+func renderDrawings(series DataList, w int, h int, drawings ...*Drawing) *image.RGBA {
+	h0 := &imageHost{width: w, height: h, dpr: 1}
+	chart := &StockChart{ID: "golden", host: h0, MainSeries: series}
+	chart.SetTimeRange(chart.MainSeries.TimeSlice(), 0)
+	layer := chart.addNewLayer("golden", lAREA_FULL, rgb.White, &chart.selectedTimeSlice)
+	for _, dr := range drawings {
+		dr.series = &chart.MainSeries
+		layer.AddDrawing(dr, rgb.White, true)
+	}
+	layer.Resize(Rect{Width: w, Height: h})
+	return h0.compose([]*Layer{layer})
+}
+
+func TestGolden(t *testing.T) {
+	series := testSeries(48)
+
+	tests := []struct {
+		name     string
+		w, h     int
+		drawings func() []*Drawing
+	}{
+		{"candles_stick", 400, 200, func() []*Drawing {
+			return []*Drawing{&NewDrawingYGrid(nil, false).Drawing, &NewDrawingCandles(nil, DS_Stick).Drawing}
+		}},
+		{"candles_bar", 400, 200, func() []*Drawing {
+			return []*Drawing{&NewDrawingYGrid(nil, false).Drawing, &NewDrawingCandles(nil, DS_Bar).Drawing}
+		}},
+		{"candles_area", 400, 200, func() []*Drawing {
+			return []*Drawing{&NewDrawingYGrid(nil, false).Drawing, &NewDrawingCandles(nil, DS_Area).Drawing}
+		}},
+		{"candles_frame", 400, 200, func() []*Drawing {
+			return []*Drawing{&NewDrawingYGrid(nil, false).Drawing, &NewDrawingCandles(nil, DS_Frame).Drawing}
+		}},
+		{"volume_bars", 400, 80, func() []*Drawing {
+			return []*Drawing{&NewDrawingBars(nil).Drawing}
+		}},
+		{"xgrid", 400, 60, func() []*Drawing {
+			return []*Drawing{&NewDrawingXGrid(nil, true, true).Drawing}
+		}},
+		{"ygrid_scale", 80, 200, func() []*Drawing {
+			return []*Drawing{&NewDrawingYGrid(nil, true).Drawing}
+		}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			img := renderDrawings(series, tc.w, tc.h, tc.drawings()...)
+			checkGolden(t, tc.name, img)
+		})
+	}
+
+	// thousands of candles narrower than a pixel
+	t.Run("candles_decimated", func(t *testing.T) {
+		img := renderDrawings(testSeriesPrecision(4000, time.Minute), 400, 200, &NewDrawingYGrid(nil, false).Drawing, &NewDrawingCandles(nil, DS_Stick).Drawing)
+		checkGolden(t, "candles_decimated", img)
+	})
+
+	// gaps
+	for _, tc := range []struct {
+		name  string
+		style GapStyle
+	}{{"gaps_break", GS_Break}, {"gaps_band", GS_Band}, {"gaps_bridge", GS_Bridge}} {
+		t.Run(tc.name, func(t *testing.T) {
+			candles := NewDrawingCandles(nil, DS_Stick)
+			candles.GapStyle = tc.style
+			series := NewDrawingSeries(nil, false)
+			series.GapStyle = tc.style
+			img := renderDrawings(testSeriesGaps(), 400, 200, &NewDrawingYGrid(nil, false).Drawing, &series.Drawing, &candles.Drawing)
+			checkGolden(t, tc.name, img)
+		})
+	}
+
+	// the full chart on a session-compressed time axis
+	t.Run("chart_calendar", func(t *testing.T) {
+		chart := NewImageStockChart(rgb.White, testSeriesSessions(), 0, 600, 400, 1)
+		chart.SetTradingCalendar(testCalendar(t))
+		img, err := chart.RenderImage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkGolden(t, "chart_calendar", img)
+	})
+
+	// moving averages over the candles
+	t.Run("chart_movingaverages", func(t *testing.T) {
+		chart := NewImageStockChart(rgb.White, testSeries(120), 0, 600, 400, 1)
+		chart.AddMovingAverage(MA_SMA, 20, PS_Close, rgb.Red)
+		chart.AddMovingAverage(MA_EMA, 10, PS_Typical, rgb.Blue)
+		img, err := chart.RenderImage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkGolden(t, "chart_movingaverages", img)
+	})
+
+	// bands over the candles, included in the y autoscale
+	t.Run("chart_bands", func(t *testing.T) {
+		chart := NewImageStockChart(rgb.White, testSeries(120), 0, 600, 400, 1)
+		chart.AddBands(BK_Bollinger, 20, 2, rgb.Blue, true)
+		chart.AddBands(BK_Keltner, 20, 4, rgb.Red, true)
+		img, err := chart.RenderImage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkGolden(t, "chart_bands", img)
+	})
+
+	// the volume bars moved to a pane below the chart
+	t.Run("chart_panes", func(t *testing.T) {
+		chart := NewImageStockChart(rgb.White, testSeries(120), 0, 600, 400, 1)
+		chart.AddVolumePane(0.25)
+		img, err := chart.RenderImage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkGolden(t, "chart_panes", img)
+	})
+
+	// RSI and stochastic panes, with their levels and zones
+	t.Run("chart_oscillators", func(t *testing.T) {
+		chart := NewImageStockChart(rgb.White, testSeries(120), 0, 600, 500, 1)
+		chart.AddRSI(14, rgb.Blue)
+		chart.AddStochastic(14, 3, 3, rgb.Blue)
+		img, err := chart.RenderImage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkGolden(t, "chart_oscillators", img)
+	})
+
+	// MACD pane, the histogram around the zero baseline
+	t.Run("chart_macd", func(t *testing.T) {
+		chart := NewImageStockChart(rgb.White, testSeries(120), 0, 600, 400, 1)
+		chart.AddMACD(12, 26, 9, rgb.Blue)
+		img, err := chart.RenderImage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkGolden(t, "chart_macd", img)
+	})
+
+	// the full chart, with a selected candle
+	t.Run("chart", func(t *testing.T) {
+		chart := NewImageStockChart(rgb.White, testSeries(48), 0.1, 600, 400, 1)
+		chart.selectedData = chart.MainSeries.Head.Prev.Prev
+		img, err := chart.RenderImage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkGolden(t, "chart", img)
+	})
+}
+
+// checkGolden compares img with the golden image name, or updates the golden image with the -update flag.
+// In case of failure, the rendered image is saved aside the golden image for investigation.
+func checkGolden(t *testing.T, name string, img *image.RGBA) {
+	t.Helper()
+	goldenfile := filepath.Join("testdata", "golden", name+".png")
+	if *fUpdate {
+		if err := writePNGFile(goldenfile, img); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	file, err := os.Open(goldenfile)
+	if err != nil {
+		t.Fatalf("%s, run go test -update to generate it", err)
+	}
+	golden, err := png.Decode(file)
+	file.Close()
+	if err != nil {
+		t.Fatalf("decoding %s fails: %s", goldenfile, err)
+	}
+
+	changed, total := diffImages(golden, img)
+	if changed < 0 || float64(changed)/float64(total) > goldenMaxRate {
+		failedfile := filepath.Join(os.TempDir(), "stockchart_"+name+".png")
+		writePNGFile(failedfile, img)
+		if changed < 0 {
+			t.Fatalf("%s fails: want size %v, get %v. Rendered image saved in %s", name, golden.Bounds().Size(), img.Bounds().Size(), failedfile)
+		}
+		t.Fatalf("%s fails: %d/%d pixels changed. Rendered image saved in %s", name, changed, total, failedfile)
+	}
+}
+
+// diffImages returns the number of pixels having at least one channel changed by more than goldenTolerance.
+// Returns -1 if the images do not have the same size.
+func diffImages(want image.Image, get image.Image) (changed int, total int) {
+	wb, gb := want.Bounds(), get.Bounds()
+	if wb.Size() != gb.Size() {
+		return -1, 0
+	}
+	diff := func(a, b uint32) uint32 {
+		if a > b {
+			return (a - b) >> 8
+		}
+		return (b - a) >> 8
+	}
+	for y := 0; y < wb.Dy(); y++ {
+		for x := 0; x < wb.Dx(); x++ {
+			r1, g1, b1, a1 := want.At(wb.Min.X+x, wb.Min.Y+y).RGBA()
+			r2, g2, b2, a2 := get.At(gb.Min.X+x, gb.Min.Y+y).RGBA()
+			if max(diff(r1, r2), diff(g1, g2), diff(b1, b2), diff(a1, a2)) > goldenTolerance {
+				changed++
+			}
+		}
+	}
+	return changed, wb.Dx() * wb.Dy()
+}
+
+func writePNGFile(filename string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
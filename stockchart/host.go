@@ -0,0 +1,176 @@
+package stockchart
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gowebapi/webapi"
+	"github.com/gowebapi/webapi/core/js"
+	"github.com/gowebapi/webapi/css/typedom"
+	"github.com/gowebapi/webapi/dom"
+	"github.com/gowebapi/webapi/html"
+	"github.com/gowebapi/webapi/html/canvas"
+	"github.com/gowebapi/webapi/html/htmlcommon"
+	"github.com/gowebapi/webapi/html/htmlevent"
+)
+
+// host is the environment embedding a stockchart and providing the drawing surface of its layers:
+// the browser DOM, or an offscreen image.
+type host interface {
+	// bounds returns the position and the size of the chart, in css pixels
+	bounds() Rect
+
+	// devicePixelRatio returns the ratio between the size of a drawing buffer and its size in css pixels
+	devicePixelRatio() float64
+
+	// newSurface creates the drawing surface of the layer and setup its Renderer
+	newSurface(layer *Layer) error
+
+	// resizeSurface moves and sizes the drawing surface of the layer.
+	// area is expressed in css pixels, bufwidth and bufheight are the size of the drawing buffer
+	resizeSurface(layer *Layer, area Rect, bufwidth int, bufheight int)
+
+	// bindEvents captures the handled events on the surface of the layer and dispatch them to the layer
+	bindEvents(layer *Layer, handled evtHandler)
+
+	// setCursor changes the mouse cursor hovering the surface of the layer
+	setCursor(layer *Layer, cursor string)
+
+	// requestFrame calls callback once, before the next repaint
+	requestFrame(callback func())
+}
+
+// browserHost embeds the chart into a <stockchart> HTML element, each layer is a stacked canvas
+type browserHost struct {
+	chartid string
+	masterE *dom.Element // the master element containing the chart
+}
+
+func (h *browserHost) bounds() Rect {
+	cr := h.masterE.GetBoundingClientRect()
+	return Rect{O: Point{X: int(cr.X()), Y: int(cr.Y())}, Width: int(cr.Width()), Height: int(cr.Height())}
+}
+
+func (h *browserHost) devicePixelRatio() float64 {
+	return webapi.GetWindow().DevicePixelRatio()
+}
+
+// newSurface creates a new canvas, inside the masterE div.
+// It's background color is setup if any, and the canvas 2D drawing context becomes the layer Renderer
+func (h *browserHost) newSurface(layer *Layer) error {
+	// create a canvas
+	domE := webapi.GetWindow().Document().CreateElement("canvas", &webapi.Union{Value: js.ValueOf("dom.Node")})
+	domE.SetId("canvas" + h.chartid + layer.Name)
+	newE := h.masterE.AppendChild(&domE.Node)
+	canvasE := canvas.HTMLCanvasElementFromWrapper(newE)
+	canvasE.AttributeStyleMap().Set("position", &typedom.Union{Value: js.ValueOf(`absolute`)})
+	canvasE.AttributeStyleMap().Set("border", &typedom.Union{Value: js.ValueOf(`none`)})
+	canvasE.AttributeStyleMap().Set("padding", &typedom.Union{Value: js.ValueOf(`0`)})
+	canvasE.AttributeStyleMap().Set("margin", &typedom.Union{Value: js.ValueOf(`0`)})
+	layer.canvasE = canvasE
+
+	// set canvas background color or leave it transparent
+	if layer.bgcolor.Alpha() != 0 {
+		canvasE.HTMLElement.AttributeStyleMap().Set("background-color", &typedom.Union{Value: js.ValueOf(layer.bgcolor.Hexa())})
+	}
+
+	// to use a canvas we need to get a 2d or 3d contextto enable drawing, here we use a 2d context
+	// https://developer.mozilla.org/fr/docs/Web/API/HTMLCanvasElement/getContext
+	ctx2D := canvas.CanvasRenderingContext2DFromWrapper(canvasE.GetContext("2d", "alias:false"))
+	if ctx2D == nil {
+		return fmt.Errorf("unable to get the canvas 2D context for drawing")
+	}
+	layer.Ctx2D = NewCanvasRenderer(ctx2D)
+	return nil
+}
+
+// resizeSurface resizes the canvas HTML element and its drawing buffer
+func (h *browserHost) resizeSurface(layer *Layer, area Rect, bufwidth int, bufheight int) {
+	stylemap := layer.canvasE.HTMLElement.AttributeStyleMap()
+	stylemap.Set("left", &typedom.Union{Value: js.ValueOf(fmt.Sprintf("%dpx", area.O.X))})
+	stylemap.Set("top", &typedom.Union{Value: js.ValueOf(fmt.Sprintf("%dpx", area.O.Y))})
+	stylemap.Set("width", &typedom.Union{Value: js.ValueOf(fmt.Sprintf("%dpx", area.Width))})
+	stylemap.Set("height", &typedom.Union{Value: js.ValueOf(fmt.Sprintf("%dpx", area.Height))})
+
+	layer.canvasE.SetWidth(uint(bufwidth))
+	layer.canvasE.SetHeight(uint(bufheight))
+}
+
+// bindEvents sets the canvas event handlers, converting browser events to the layer dispatcher
+func (h *browserHost) bindEvents(layer *Layer, handled evtHandler) {
+	bindMouse := func(evt evtHandler, setter func(listener func(event *htmlevent.MouseEvent, currentTarget *html.HTMLElement)) js.Func) {
+		if (handled & evt) != 0 {
+			setter(func(event *htmlevent.MouseEvent, currentTarget *html.HTMLElement) {
+				layer.dispatchMouseEvent(evt, getMouseXY(event), makeMouseEvent(event))
+			})
+		}
+	}
+	bindMouse(evt_MouseDown, layer.canvasE.SetOnMouseDown)
+	bindMouse(evt_MouseUp, layer.canvasE.SetOnMouseUp)
+	bindMouse(evt_MouseMove, layer.canvasE.SetOnMouseMove)
+	bindMouse(evt_MouseEnter, layer.canvasE.SetOnMouseEnter)
+	bindMouse(evt_MouseLeave, layer.canvasE.SetOnMouseLeave)
+	bindMouse(evt_Click, layer.canvasE.SetOnClick)
+
+	if (handled & evt_Wheel) != 0 {
+		layer.canvasE.SetOnWheel(func(event *htmlevent.WheelEvent, currentTarget *html.HTMLElement) {
+			wheelevent := &WheelEvent{
+				MouseEvent: *makeMouseEvent(&event.MouseEvent),
+				DeltaX:     event.DeltaX(),
+				DeltaY:     event.DeltaY()}
+			layer.dispatchWheelEvent(wheelevent)
+		})
+	}
+}
+
+func (h *browserHost) setCursor(layer *Layer, cursor string) {
+	layer.canvasE.AttributeStyleMap().Set("cursor", &typedom.Union{Value: js.ValueOf(cursor)})
+}
+
+// requestFrame calls callback at the next browser animation frame
+func (h *browserHost) requestFrame(callback func()) {
+	var jscb *htmlcommon.FrameRequestCallback
+	jscb = htmlcommon.FrameRequestCallbackToJS(func(time float64) {
+		js.Func(*jscb).Release()
+		callback()
+	})
+	webapi.GetWindow().RequestAnimationFrame(jscb)
+}
+
+// makeMouseEvent converts a browser mouse event
+func makeMouseEvent(event *htmlevent.MouseEvent) *MouseEvent {
+	return &MouseEvent{
+		ShiftKey: event.ShiftKey(),
+		CtrlKey:  event.CtrlKey(),
+		AltKey:   event.AltKey(),
+		Button:   event.Button()}
+}
+
+// get Mouse position taking into account DevicePixelRatio in case of browser zoom
+func getMouseXY(event *htmlevent.MouseEvent) (xy Point) {
+	dpr := webapi.GetWindow().DevicePixelRatio()
+	dx := float64(event.OffsetX()) * dpr
+	dy := float64(event.OffsetY()) * dpr
+	xy = Point{X: int(dx), Y: int(dy)}
+	return xy
+}
+
+// getChartElement looks for chartid in the DOM and check it's type <stockchart>
+func getChartElement(chartid string) (*dom.Element, error) {
+	doc := webapi.GetWindow().Document()
+	if doc == nil {
+		return nil, fmt.Errorf("unable to access the html page content")
+	}
+
+	element := doc.GetElementById(chartid)
+	if element == nil {
+		return nil, fmt.Errorf("unable to find %q drawing area element in your html page", chartid)
+	}
+
+	strname := strings.ToLower(element.NodeName())
+	if strname != "stockchart" {
+		return nil, fmt.Errorf("drawing area element is not a <stockchart>, it should: %s\n", strname)
+	}
+
+	return element, nil
+}
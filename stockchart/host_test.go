@@ -0,0 +1,117 @@
+package stockchart
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/larry868/rgb"
+	timeline "github.com/larry868/timeline/v2"
+)
+
+func TestMain(m *testing.M) {
+	// keep the test output readable
+	DEBUG = DBG_OFF
+	os.Exit(m.Run())
+}
+
+// fakeHost is a host without any browser, for testing purpose.
+// Each layer draws into a RecordingRenderer, events are sent directly to the layer dispatchers.
+type fakeHost struct {
+	width  int
+	height int
+
+	handled map[string]evtHandler // events bound by layer name
+	cursors map[string]string     // the current cursor by layer name
+	frames  []func()              // frames requested and not yet painted
+}
+
+func newFakeHost(width int, height int) *fakeHost {
+	return &fakeHost{
+		width:   width,
+		height:  height,
+		handled: make(map[string]evtHandler),
+		cursors: make(map[string]string)}
+}
+
+func (h *fakeHost) bounds() Rect {
+	return Rect{Width: h.width, Height: h.height}
+}
+
+func (h *fakeHost) devicePixelRatio() float64 {
+	return 1
+}
+
+func (h *fakeHost) newSurface(layer *Layer) error {
+	layer.Ctx2D = NewRecordingRenderer()
+	return nil
+}
+
+func (h *fakeHost) resizeSurface(layer *Layer, area Rect, bufwidth int, bufheight int) {
+}
+
+func (h *fakeHost) bindEvents(layer *Layer, handled evtHandler) {
+	h.handled[layer.Name] = handled
+}
+
+func (h *fakeHost) setCursor(layer *Layer, cursor string) {
+	h.cursors[layer.Name] = cursor
+}
+
+func (h *fakeHost) requestFrame(callback func()) {
+	h.frames = append(h.frames, callback)
+}
+
+// flushFrames paints the frames requested, like the browser does before a repaint
+func (h *fakeHost) flushFrames() {
+	frames := h.frames
+	h.frames = nil
+	for _, frame := range frames {
+		frame()
+	}
+}
+
+// newTestChart builds a 800x500 chart on a fakeHost, with the series and no extension of the time range
+func newTestChart(series DataList) (*StockChart, *fakeHost) {
+	h := newFakeHost(800, 500)
+	chart := newStockChart("test", h, rgb.White, series, 0)
+	chart.Resize()
+	return chart, h
+}
+
+// recorder returns the RecordingRenderer of the layer
+func (chart *StockChart) recorder(layerid int) *RecordingRenderer {
+	return chart.layers[layerid].Ctx2D.(*RecordingRenderer)
+}
+
+// testSeries returns a deterministic series of nb hourly candles starting on the 1st January 2024 UTC
+func testSeries(nb int) DataList {
+	return testSeriesPrecision(nb, time.Hour)
+}
+
+// testSeriesPrecision returns a deterministic series of nb candles of precision duration, starting on the 1st January 2024 UTC
+func testSeriesPrecision(nb int, precision time.Duration) DataList {
+	series := DataList{Name: "TEST", Precision: precision}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	price := 100.0
+	for i := 0; i < nb; i++ {
+		ds := new(DataStock)
+		ds.TimeSlice = timeline.MakeTimeSlice(start.Add(time.Duration(i)*precision), precision)
+		ds.Open = price
+		// zigzag prices, rising every third candle
+		switch i % 3 {
+		case 0:
+			price += 4
+		case 1:
+			price -= 2
+		case 2:
+			price -= 1
+		}
+		ds.Close = price
+		ds.Low = min(ds.Open, ds.Close) - 1
+		ds.High = max(ds.Open, ds.Close) + 1
+		ds.Volume = float64(100 + 10*(i%5))
+		series.Append(ds)
+	}
+	return series
+}
@@ -0,0 +1,204 @@
+package stockchart
+
+import (
+	"math"
+	"sort"
+)
+
+// PriceSource is the price of a data point an indicator is computed from
+type PriceSource int
+
+const (
+	PS_Close   PriceSource = 0 // the Close, by default
+	PS_Open    PriceSource = 1
+	PS_High    PriceSource = 2
+	PS_Low     PriceSource = 3
+	PS_Typical PriceSource = 4 // the typical price, (High+Low+Close)/3
+)
+
+// Price returns the price of data according to the source
+func (src PriceSource) Price(data *DataStock) float64 {
+	switch src {
+	case PS_Open:
+		return data.Open
+	case PS_High:
+		return data.High
+	case PS_Low:
+		return data.Low
+	case PS_Typical:
+		return (data.High + data.Low + data.Close) / 3
+	}
+	return data.Close
+}
+
+func (src PriceSource) String() string {
+	switch src {
+	case PS_Open:
+		return "open"
+	case PS_High:
+		return "high"
+	case PS_Low:
+		return "low"
+	case PS_Typical:
+		return "typical"
+	}
+	return "close"
+}
+
+// MAKind is the kind of a moving average
+type MAKind int
+
+const (
+	MA_SMA MAKind = 0 // simple moving average, by default
+	MA_EMA MAKind = 1 // exponential moving average, seeded with the simple moving average of the first period
+	MA_WMA MAKind = 2 // linearly weighted moving average, the latest data point having the highest weight
+)
+
+func (kind MAKind) String() string {
+	switch kind {
+	case MA_EMA:
+		return "EMA"
+	case MA_WMA:
+		return "WMA"
+	}
+	return "SMA"
+}
+
+// movingAverage returns the moving average of kind over the period values ending at i, value(j) returning the input at j.
+// prev is the moving average at i-1, used by EMA.
+// Returns NaN if one of the inputs of the period is undefined.
+func movingAverage(kind MAKind, period int, value func(j int) float64, i int, prev float64) float64 {
+	if period <= 0 || i < period-1 {
+		return math.NaN()
+	}
+	if kind == MA_EMA && !math.IsNaN(prev) {
+		alpha := 2.0 / float64(period+1)
+		return alpha*value(i) + (1-alpha)*prev
+	}
+	var sum, weights float64
+	for j := i - period + 1; j <= i; j++ {
+		w := 1.0
+		if kind == MA_WMA {
+			w = float64(j - i + period)
+		}
+		sum += w * value(j)
+		weights += w
+	}
+	return sum / weights
+}
+
+// standardDeviation returns the population standard deviation of the period values ending at i, around mean
+func standardDeviation(period int, value func(j int) float64, i int, mean float64) float64 {
+	if period <= 0 || i < period-1 {
+		return math.NaN()
+	}
+	var sum float64
+	for j := i - period + 1; j <= i; j++ {
+		d := value(j) - mean
+		sum += d * d
+	}
+	return math.Sqrt(sum / float64(period))
+}
+
+// trueRange returns the range of data extended to the close of prev, if any
+func trueRange(prev *DataStock, data *DataStock) float64 {
+	if prev == nil {
+		return data.High - data.Low
+	}
+	return math.Max(data.High, prev.Close) - math.Min(data.Low, prev.Close)
+}
+
+// indicator caches the lines of an indicator computed for every data point of a DataList, in chronological order.
+// Undefined values are NaN, like before the first period of a moving average.
+//
+// Lines are recomputed incrementally: appended data points are computed, and the data points from the first one invalidated,
+// the head being always recomputed as it can be updated in place.
+// The lines are fully recomputed if the series has changed, like after a prepend, an insert or a timeframe switch.
+type indicator struct {
+	compute func(ind *indicator, i int) // sets the lines at i, from the data points and the lines before i
+	items   []*DataStock                // the data points computed
+	lines   [][]float64                 // the computed lines, one value per data point
+	dirty   int                         // the position to recompute from
+}
+
+// newIndicator returns an indicator of nlines computed by compute
+func newIndicator(nlines int, compute func(ind *indicator, i int)) *indicator {
+	return &indicator{compute: compute, lines: make([][]float64, nlines)}
+}
+
+// reset forces a full computation at the next update
+func (ind *indicator) reset() {
+	ind.items = nil
+	ind.dirty = 0
+}
+
+// invalidate marks the lines to be recomputed from data at the next update.
+// If data is not computed but is older than the last computed data point, it has been inserted, and the lines are fully recomputed.
+func (ind *indicator) invalidate(data *DataStock) {
+	i := ind.position(data)
+	switch {
+	case i >= 0:
+		ind.dirty = min(ind.dirty, i)
+	case len(ind.items) > 0 && data.From.Before(ind.items[len(ind.items)-1].From):
+		ind.reset()
+	}
+}
+
+// position returns the position of data in the computed data points, -1 if not found
+func (ind *indicator) position(data *DataStock) int {
+	i := sort.Search(len(ind.items), func(i int) bool { return !ind.items[i].From.Before(data.From) })
+	if i < len(ind.items) && ind.items[i] == data {
+		return i
+	}
+	return -1
+}
+
+// update computes the lines of the data points of series not yet computed or invalidated
+func (ind *indicator) update(series *DataList) {
+	n := len(ind.items)
+	if n == 0 || ind.items[0] != series.Tail || (ind.items[n-1] != series.Head && ind.items[n-1].Next == nil) {
+		ind.items = append([]*DataStock(nil), series.window(nil)...)
+		ind.dirty = 0
+	} else {
+		for item := ind.items[n-1]; item != series.Head && item.Next != nil; {
+			item = item.Next
+			ind.items = append(ind.items, item)
+		}
+		ind.dirty = min(ind.dirty, n-1)
+	}
+
+	for k := range ind.lines {
+		if len(ind.lines[k]) > len(ind.items) {
+			ind.lines[k] = ind.lines[k][:len(ind.items)]
+		}
+		for len(ind.lines[k]) < len(ind.items) {
+			ind.lines[k] = append(ind.lines[k], math.NaN())
+		}
+	}
+	for i := ind.dirty; i < len(ind.items); i++ {
+		ind.compute(ind, i)
+	}
+	ind.dirty = len(ind.items)
+}
+
+// prev returns the value of line at i-1, NaN if i is the first position
+func (ind *indicator) prev(line int, i int) float64 {
+	if i <= 0 {
+		return math.NaN()
+	}
+	return ind.lines[line][i-1]
+}
+
+// bounds returns the positions of the first and after the last data points of window, a chronological window of the computed data points.
+// Returns 0, 0 if window is empty or not computed.
+func (ind *indicator) bounds(window []*DataStock) (first int, last int) {
+	if len(window) == 0 {
+		return 0, 0
+	}
+	first = ind.position(window[0])
+	last = ind.position(window[len(window)-1])
+	if first < 0 || last < 0 {
+		return 0, 0
+	}
+	return first, last + 1
+}
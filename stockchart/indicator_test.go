@@ -0,0 +1,186 @@
+package stockchart
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/larry868/rgb"
+	timeline "github.com/larry868/timeline/v2"
+)
+
+// almostEqual returns true if a and b are both NaN or differ by less than 1e-9
+func almostEqual(a float64, b float64) bool {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.IsNaN(a) && math.IsNaN(b)
+	}
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestMovingAverage(t *testing.T) {
+	inputs := []float64{1, 2, 3, 4, 5, 6}
+	value := func(j int) float64 { return inputs[j] }
+	nan := math.NaN()
+
+	for _, tc := range []struct {
+		kind MAKind
+		want []float64
+	}{
+		{MA_SMA, []float64{nan, nan, 2, 3, 4, 5}},
+		{MA_WMA, []float64{nan, nan, 14.0 / 6, 20.0 / 6, 26.0 / 6, 32.0 / 6}},
+		{MA_EMA, []float64{nan, nan, 2, 3, 4, 5}}, // alpha=0.5, seeded with the SMA
+	} {
+		prev := nan
+		for i := range inputs {
+			get := movingAverage(tc.kind, 3, value, i, prev)
+			if !almostEqual(get, tc.want[i]) {
+				t.Errorf("%s at %d fails: want %v, get %v", tc.kind, i, tc.want[i], get)
+			}
+			prev = get
+		}
+	}
+
+	// EMA from the previous value, alpha=0.5
+	inputs = []float64{1, 1, 1, 5}
+	if get := movingAverage(MA_EMA, 3, value, 3, 1); !almostEqual(get, 3) {
+		t.Errorf("EMA fails: want 3, get %v", get)
+	}
+
+	// undefined inputs
+	inputs = []float64{nan, 1, 2, 3}
+	if get := movingAverage(MA_SMA, 3, value, 2, nan); !math.IsNaN(get) {
+		t.Errorf("SMA with NaN fails: get %v", get)
+	}
+	if get := movingAverage(MA_EMA, 3, value, 3, nan); !almostEqual(get, 2) {
+		t.Errorf("EMA seeded after NaN fails: get %v", get)
+	}
+
+	if get := (PriceSource(PS_Typical)).Price(&DataStock{High: 12, Low: 6, Close: 9}); get != 9 {
+		t.Errorf("PS_Typical fails: get %v", get)
+	}
+}
+
+func TestIndicatorIncremental(t *testing.T) {
+	older, series := splitSeries(testSeries(60), 10)
+	computed := 0
+	ind := newIndicator(1, func(ind *indicator, i int) {
+		computed++
+		value := func(j int) float64 { return ind.items[j].Close }
+		ind.lines[0][i] = movingAverage(MA_EMA, 10, value, i, ind.prev(0, i))
+	})
+	full := func() []float64 {
+		ref := newIndicator(1, ind.compute)
+		ref.update(&series)
+		return ref.lines[0]
+	}
+	check := func(name string) {
+		t.Helper()
+		want := full()
+		if len(ind.lines[0]) != len(want) {
+			t.Fatalf("%s fails: want %d values, get %d", name, len(want), len(ind.lines[0]))
+		}
+		for i := range want {
+			if !almostEqual(ind.lines[0][i], want[i]) {
+				t.Fatalf("%s fails at %d: want %v, get %v", name, i, want[i], ind.lines[0][i])
+			}
+		}
+	}
+
+	ind.update(&series)
+	check("update")
+
+	// append: only the previous head and the new data point
+	computed = 0
+	head := series.Head
+	series.Append(&DataStock{TimeSlice: timeline.MakeTimeSlice(head.To, time.Hour), Open: head.Close, High: 200, Low: 100, Close: 150})
+	ind.update(&series)
+	if computed != 2 {
+		t.Errorf("update after append fails: %d computed, want 2", computed)
+	}
+	check("append")
+
+	// update in the middle
+	computed = 0
+	middle := series.window(nil)[40]
+	middle.Close = 500
+	ind.invalidate(middle)
+	ind.update(&series)
+	if computed != 11 {
+		t.Errorf("update after invalidate fails: %d computed, want 11", computed)
+	}
+	check("invalidate")
+
+	// prepend: full recompute
+	if err := series.Prepend(&older); err != nil {
+		t.Fatal(err)
+	}
+	ind.update(&series)
+	check("prepend")
+}
+
+func TestDrawingMovingAverage(t *testing.T) {
+	chart, h := newTestChart(testSeries(100))
+	ma := chart.AddMovingAverage(MA_SMA, 20, PS_Close, rgb.Red)
+	resetRecorders(chart, h)
+	chart.Redraw()
+	h.flushFrames()
+
+	items, values := ma.Values()
+	if len(items) != 100 || !math.IsNaN(values[18]) || math.IsNaN(values[19]) {
+		t.Fatalf("Values fails: %d items", len(items))
+	}
+	found := false
+	for _, call := range chart.recorder(4).Filter("SetStrokeStyle") {
+		if call.Args[0] == rgb.Red {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("AddMovingAverage fails: the line is not drawn")
+	}
+
+	// live update
+	chart.UpdateHead(1000, 0)
+	h.flushFrames()
+	if _, values = ma.Values(); !almostEqual(values[99], values[98]+(1000-items[79].Close)/20) {
+		t.Errorf("UpdateHead fails: get %v", values[99])
+	}
+
+	// changing the parameters recomputes all values
+	ma.Period = 5
+	if _, values = ma.Values(); !math.IsNaN(values[3]) || math.IsNaN(values[4]) {
+		t.Errorf("changing the period fails")
+	}
+}
+
+func TestIndicatorInsert(t *testing.T) {
+	// minute candles with a missing one at 00:15
+	series := DataList{Name: "TEST", Precision: time.Minute}
+	for i, item := range testSeriesPrecision(30, time.Minute).window(nil) {
+		if i != 15 {
+			series.Append(&DataStock{TimeSlice: item.TimeSlice, Open: item.Open, High: item.High, Low: item.Low, Close: item.Close, Volume: item.Volume})
+		}
+	}
+	chart, _ := newTestChart(series)
+	ma := chart.AddMovingAverage(MA_SMA, 5, PS_Close, rgb.Red)
+	if items, _ := ma.Values(); len(items) != 29 {
+		t.Fatalf("Values fails: %d items", len(items))
+	}
+
+	// a late tick in the missing candle
+	gap := chart.MainSeries.Tail.From.Add(15 * time.Minute)
+	if _, isnew, err := chart.NewAggregator().AddTick(gap.Add(30*time.Second), 1000, 1); err != nil || !isnew {
+		t.Fatalf("AddTick fails: %v", err)
+	}
+	items, values := ma.Values()
+	if len(items) != 30 || !items[15].From.Equal(gap) {
+		t.Fatalf("Values after insert fails: %d items", len(items))
+	}
+	want := 0.0
+	for _, item := range items[15:20] {
+		want += item.Close / 5
+	}
+	if !almostEqual(values[19], want) {
+		t.Errorf("Values after insert fails: want %v, get %v", want, values[19])
+	}
+}
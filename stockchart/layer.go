@@ -5,12 +5,8 @@ import (
 	"math"
 	"strings"
 
-	"github.com/gowebapi/webapi"
-	"github.com/gowebapi/webapi/core/js"
-	"github.com/gowebapi/webapi/css/typedom"
-	"github.com/gowebapi/webapi/html"
 	"github.com/gowebapi/webapi/html/canvas"
-	"github.com/gowebapi/webapi/html/htmlevent"
+	"github.com/larry868/datarange"
 	"github.com/larry868/rgb"
 	timeline "github.com/larry868/timeline/v2"
 )
@@ -22,33 +18,39 @@ const (
 	lAREA_GRAPH
 	lAREA_YSCALE
 	lAREA_NAVBAR
+	lAREA_PANE       // a pane stacked below the graph
+	lAREA_PANEYSCALE // the yscale of a pane
 )
 
 // A Layer correspond to a single canvas with an html5 2D drawing context.
 // It's embedding a stack of drawings.
 type Layer struct {
 	ClipArea Rect
-	Ctx2D    *canvas.CanvasRenderingContext2D
+	Ctx2D    Renderer // the 2D drawing context of the layer
 
-	Name    string                   // the name of the layer, for debugging purpose
-	chart   *StockChart              // the parent chart
-	canvasE canvas.HTMLCanvasElement // the <canvas> element of this layer
-	layout  layoutT                  // The layout of this layer within the chart
+	Name    string                    // the name of the layer, for debugging purpose
+	chart   *StockChart               // the parent chart
+	canvasE *canvas.HTMLCanvasElement // the <canvas> element of this layer, nil if not hosted by a browser
+	layout  layoutT                   // The layout of this layer within the chart
+	area    Rect                      // the position and the size of the layer within the host, in css pixels
+	bgcolor rgb.Color                 // the background color of the layer, transparent if rgb.None
 
 	xAxisRange *timeline.TimeSlice // the timeslice to show and draw on this layer
+	pane       *Pane               // the pane of the layer, nil if the layer is not part of a pane
 
 	TitleAreas []Rect // the area to stack titles of series in the layer
 
 	drawings []*Drawing // stack of drawings
+
+	dirty bool // the layer must be redrawn at the next frame
 }
 
-func NewLayer(id string, chart *StockChart, layout layoutT, xaxisrange *timeline.TimeSlice, canvasE canvas.HTMLCanvasElement) *Layer {
+func NewLayer(id string, chart *StockChart, layout layoutT, xaxisrange *timeline.TimeSlice) *Layer {
 	layer := new(Layer)
 	layer.Name = strings.ToLower(strings.Trim(id, " "))
 	layer.chart = chart
 	layer.layout = layout
 	layer.xAxisRange = xaxisrange
-	layer.canvasE = canvasE
 	return layer
 }
 
@@ -71,179 +73,99 @@ func (layer *Layer) AddDrawing(dr *Drawing, bgcolor rgb.Color, ontop bool) *Draw
 
 // Default string interface
 func (layer Layer) String() string {
-	str := fmt.Sprintf("%q canvasE:%p, ctx2D:%p area:{%v} nb drawings:%d", layer.Name, &layer.canvasE, layer.Ctx2D, layer.ClipArea, len(layer.drawings))
+	str := fmt.Sprintf("%q canvasE:%p, ctx2D:%p area:{%v} nb drawings:%d", layer.Name, layer.canvasE, layer.Ctx2D, layer.ClipArea, len(layer.drawings))
 	return str
 }
 
+// yAxis returns the y axis range of the layer, the one of its pane if any, otherwise the one of the chart
+func (layer *Layer) yAxis() *datarange.DataRange {
+	if layer.pane != nil {
+		return &layer.pane.yAxisRange
+	}
+	return &layer.chart.yAxisRange
+}
+
+// removeDrawing removes dr from the stack of drawings of the layer, if any
+func (layer *Layer) removeDrawing(dr *Drawing) {
+	for i, d := range layer.drawings {
+		if d == dr {
+			layer.drawings = append(layer.drawings[:i], layer.drawings[i+1:]...)
+			return
+		}
+	}
+}
+
 func (layer Layer) hasValidXAxisRange() bool {
 	return layer.xAxisRange != nil && layer.xAxisRange.Duration().IsFinite && layer.xAxisRange.Duration().Seconds() >= 1
 }
 
-// SetEventDispatcher activates mouse event handler on the canvas of the layer.
+// SetEventDispatcher activates mouse event handler on the surface of the layer.
 // When setup, if the mouse is located in the cliparea of the layer,
 // the event is propagated to all drawings having defined their own MouseEvent func.
 //
 // Usually SetEventDispatcher is called by the chart factory.
 func (layer *Layer) SetEventDispatcher() {
-
-	// update changed time selection
-	var oldselts timeline.TimeSlice
-	var oldseldata *DataStock
-	processSelChange := func() {
-		if oldselts.Compare(layer.chart.selectedTimeSlice) == timeline.DIFFERENT {
-			layer.chart.DoChangeSelTimeSlice(layer.chart.selectedTimeSlice, true)
-		}
-		if oldseldata != layer.chart.selectedData {
-			layer.chart.DoChangeSelData(layer.chart.selectedData, true)
-		}
-	}
-
 	// Define functions to capture mouse events on this layer,
 	// only if the layer contains at least one mouse function on its drawings
 	hme := layer.HandledEvents()
 
 	// Debug(DBG_EVENT, "%q layer, SetEventDispatcher event handled=%08b ", layer.Name, hme)
 
-	if (hme & evt_MouseDown) != 0 {
-		layer.canvasE.SetOnMouseDown(func(event *htmlevent.MouseEvent, currentTarget *html.HTMLElement) {
-			xy := getMouseXY(event)
-			if xy.IsIn(layer.ClipArea) {
-				if !layer.hasValidXAxisRange() {
-					return
-				}
-				oldselts = layer.chart.selectedTimeSlice
-				oldseldata = layer.chart.selectedData
-				for _, drawing := range layer.drawings {
-					if drawing.OnMouseDown != nil {
-						if !drawing.hasNonEmptySeries() {
-							continue
-						}
-						drawing.OnMouseDown(xy, event)
-					}
-				}
-				processSelChange()
-			}
-		})
-	}
+	layer.chart.host.bindEvents(layer, hme)
+}
 
-	if (hme & evt_MouseUp) != 0 {
-		layer.canvasE.SetOnMouseUp(func(event *htmlevent.MouseEvent, currentTarget *html.HTMLElement) {
-			if !layer.hasValidXAxisRange() {
-				return
-			}
-			xy := getMouseXY(event)
-			oldselts = layer.chart.selectedTimeSlice
-			oldseldata = layer.chart.selectedData
-			for _, drawing := range layer.drawings {
-				if drawing.OnMouseUp != nil {
-					if !drawing.hasNonEmptySeries() {
-						continue
-					}
-					drawing.OnMouseUp(xy, event)
-				}
-			}
-			processSelChange()
-		})
+// dispatchMouseEvent propagates a mouse event to all drawings of the layer handling it.
+// A mouse down is propagated only if xy is inside the cliparea.
+//
+// Then the chart is updated if the selection has changed during the drawings.
+func (layer *Layer) dispatchMouseEvent(evt evtHandler, xy Point, event *MouseEvent) {
+	if evt == evt_MouseDown && !xy.IsIn(layer.ClipArea) {
+		return
 	}
-
-	if (hme & evt_MouseMove) != 0 {
-		layer.canvasE.SetOnMouseMove(func(event *htmlevent.MouseEvent, currentTarget *html.HTMLElement) {
-			if !layer.hasValidXAxisRange() {
-				return
-			}
-			xy := getMouseXY(event)
-			oldselts = layer.chart.selectedTimeSlice
-			oldseldata = layer.chart.selectedData
-			for _, drawing := range layer.drawings {
-				if drawing.OnMouseMove != nil {
-					if !drawing.hasNonEmptySeries() {
-						continue
-					}
-					drawing.OnMouseMove(xy, event)
-				}
-			}
-			processSelChange()
-		})
+	if !layer.hasValidXAxisRange() {
+		return
 	}
-
-	if (hme & evt_MouseEnter) != 0 {
-		layer.canvasE.SetOnMouseEnter(func(event *htmlevent.MouseEvent, currentTarget *html.HTMLElement) {
-			if !layer.hasValidXAxisRange() {
-				return
-			}
-			xy := getMouseXY(event)
-			oldselts = layer.chart.selectedTimeSlice
-			oldseldata = layer.chart.selectedData
-			for _, drawing := range layer.drawings {
-				if drawing.OnMouseEnter != nil {
-					if !drawing.hasNonEmptySeries() {
-						continue
-					}
-					drawing.OnMouseEnter(xy, event)
-				}
+	oldselts := layer.chart.selectedTimeSlice
+	oldseldata := layer.chart.selectedData
+	for _, drawing := range layer.drawings {
+		if handler := drawing.mouseHandler(evt); handler != nil {
+			if !drawing.hasNonEmptySeries() {
+				continue
 			}
-			processSelChange()
-		})
+			handler(xy, event)
+		}
 	}
+	layer.processSelChange(oldselts, oldseldata)
+}
 
-	if (hme & evt_MouseLeave) != 0 {
-		layer.canvasE.SetOnMouseLeave(func(event *htmlevent.MouseEvent, currentTarget *html.HTMLElement) {
-			if !layer.hasValidXAxisRange() {
-				return
-			}
-			xy := getMouseXY(event)
-			oldselts = layer.chart.selectedTimeSlice
-			oldseldata = layer.chart.selectedData
-			for _, drawing := range layer.drawings {
-				if drawing.OnMouseLeave != nil {
-					if !drawing.hasNonEmptySeries() {
-						continue
-					}
-					drawing.OnMouseLeave(xy, event)
-				}
-			}
-			processSelChange()
-		})
+// dispatchWheelEvent propagates a wheel event to all drawings of the layer handling it.
+//
+// Then the chart is updated if the selection has changed during the drawings.
+func (layer *Layer) dispatchWheelEvent(event *WheelEvent) {
+	if !layer.hasValidXAxisRange() {
+		return
 	}
-
-	if (hme & evt_Wheel) != 0 {
-		layer.canvasE.SetOnWheel(func(event *htmlevent.WheelEvent, currentTarget *html.HTMLElement) {
-			if !layer.hasValidXAxisRange() {
-				return
-			}
-			oldselts = layer.chart.selectedTimeSlice
-			oldseldata = layer.chart.selectedData
-			for _, drawing := range layer.drawings {
-				if drawing.OnWheel != nil {
-					if !drawing.hasNonEmptySeries() {
-						continue
-					}
-					drawing.OnWheel(event)
-				}
+	oldselts := layer.chart.selectedTimeSlice
+	oldseldata := layer.chart.selectedData
+	for _, drawing := range layer.drawings {
+		if drawing.OnWheel != nil {
+			if !drawing.hasNonEmptySeries() {
+				continue
 			}
-			// Debug(DBG_SELCHANGE, "OnWheel dispatcher: last %s, new %s", oldselts.String(), layer.chart.selectedTimeSlice.String())
-			processSelChange()
-		})
+			drawing.OnWheel(event)
+		}
 	}
+	// Debug(DBG_SELCHANGE, "OnWheel dispatcher: last %s, new %s", oldselts.String(), layer.chart.selectedTimeSlice.String())
+	layer.processSelChange(oldselts, oldseldata)
+}
 
-	if (hme & evt_Click) != 0 {
-		layer.canvasE.SetOnClick(func(event *htmlevent.MouseEvent, currentTarget *html.HTMLElement) {
-			if !layer.hasValidXAxisRange() {
-				return
-			}
-			xy := getMouseXY(event)
-			oldselts = layer.chart.selectedTimeSlice
-			oldseldata = layer.chart.selectedData
-			for _, drawing := range layer.drawings {
-				if drawing.OnClick != nil {
-					if !drawing.hasNonEmptySeries() {
-						continue
-					}
-					drawing.OnClick(xy, event)
-				}
-			}
-			processSelChange()
-		})
+// processSelChange updates the chart if the selected time slice or the selected data have changed
+func (layer *Layer) processSelChange(oldselts timeline.TimeSlice, oldseldata *DataStock) {
+	if oldselts.Compare(layer.chart.selectedTimeSlice) == timeline.DIFFERENT {
+		layer.chart.DoChangeSelTimeSlice(layer.chart.selectedTimeSlice, true)
+	}
+	if oldseldata != layer.chart.selectedData {
+		layer.chart.DoChangeSelData(layer.chart.selectedData, true)
 	}
 }
 
@@ -257,21 +179,14 @@ func (layer *Layer) SetEventDispatcher() {
 //
 // Resize calls automatically redraw
 func (layer *Layer) Resize(newarea Rect) {
-	// resize the canvas HTML element
-	stylemap := layer.canvasE.HTMLElement.AttributeStyleMap()
-	stylemap.Set("left", &typedom.Union{Value: js.ValueOf(fmt.Sprintf("%dpx", newarea.O.X))})
-	stylemap.Set("top", &typedom.Union{Value: js.ValueOf(fmt.Sprintf("%dpx", newarea.O.Y))})
-	stylemap.Set("width", &typedom.Union{Value: js.ValueOf(fmt.Sprintf("%dpx", newarea.Width))})
-	stylemap.Set("height", &typedom.Union{Value: js.ValueOf(fmt.Sprintf("%dpx", newarea.Height))})
-
-	// resize the drawing buffer of the canvas
-	dpr := webapi.GetWindow().DevicePixelRatio()
+	// resize the surface and its drawing buffer
+	dpr := layer.chart.host.devicePixelRatio()
 	dw := math.Abs(float64(newarea.Width) * dpr)
 	dh := math.Abs(float64(newarea.Height) * dpr)
 	dbuffwidth := int(dw)
 	dbuffheight := int(dh)
-	layer.canvasE.SetWidth(uint(dbuffwidth))
-	layer.canvasE.SetHeight(uint(dbuffheight))
+	layer.area = newarea
+	layer.chart.host.resizeSurface(layer, newarea, dbuffwidth, dbuffheight)
 
 	// update the cliparea
 	layer.ClipArea.O.X = 0
@@ -294,14 +209,22 @@ func (layer *Layer) Resize(newarea Rect) {
 	layer.Redraw()
 }
 
+// setCursor changes the mouse cursor hovering the canvas of the layer, like `grab` or `auto`
+func (layer *Layer) setCursor(cursor string) {
+	layer.chart.host.setCursor(layer, cursor)
+}
+
 // Clear the layer
 func (layer *Layer) Clear() {
 	layer.Ctx2D.ClearRect(float64(layer.ClipArea.O.X), float64(layer.ClipArea.O.Y), float64(layer.ClipArea.Width), float64(layer.ClipArea.Height))
 	layer.TitleAreas = make([]Rect, 0)
 }
 
-// Clear the layer and redraw all drawings.
+// Clear the layer and redraw all drawings immediately.
+//
+// Prefer Invalidate to coalesce redraws at the next frame.
 func (layer *Layer) Redraw() {
+	layer.dirty = false
 	layer.Clear()
 	if !layer.hasValidXAxisRange() {
 		return
@@ -318,13 +241,25 @@ func (layer *Layer) Redraw() {
 	}
 }
 
-// Redraw the layer if at least one drawings need to be redrawn.
-// Then update selection on the layer
+// Invalidate marks the layer to be redrawn at the next frame.
+// Many calls before the next frame lead to a single redraw.
+func (layer *Layer) Invalidate() {
+	layer.dirty = true
+	layer.chart.requestFrame()
+}
+
+// Redraw the layer immediately if at least one drawings need to be redrawn.
 func (layer *Layer) RedrawOnlyNeeds() {
+	if layer.needRedraw() {
+		layer.Redraw()
+	}
+}
+
+// needRedraw returns true if at least one drawing of the layer needs to be redrawn
+func (layer *Layer) needRedraw() bool {
 	if !layer.hasValidXAxisRange() {
-		return
+		return false
 	}
-	fredrawn := false
 	for _, drawing := range layer.drawings {
 		if drawing.NeedRedraw != nil {
 			if !drawing.hasNonEmptySeries() {
@@ -334,10 +269,10 @@ func (layer *Layer) RedrawOnlyNeeds() {
 
 			// Debug(DBG_SELCHANGE|DBG_REDRAW, "%q/%q layer/drawing, RedrawOnlyNeeds NeedRedraw:%v", layer.Name, drawing.Name, need)
 
-			if need && !fredrawn {
-				layer.Redraw()
-				fredrawn = true
+			if need {
+				return true
 			}
 		}
 	}
+	return false
 }
@@ -0,0 +1,84 @@
+package stockchart
+
+import (
+	"github.com/larry868/rgb"
+)
+
+// Renderer is the 2D drawing context used by drawings to render themselves on their layer.
+// It provides paths, rects, text, styles and clipping primitives, in drawing buffer coordinates.
+//
+// The HTML5 canvas 2D context is the default backend, see CanvasRenderer.
+type Renderer interface {
+	// drawing state
+	Save()
+	Restore()
+
+	// styles
+	SetFillStyle(color rgb.Color)
+	SetStrokeStyle(color rgb.Color)
+	SetLineWidth(width float64)
+	SetLineDash(segments []float64)
+	SetLineCap(linecap LineCap)
+	SetLineJoin(linejoin LineJoin)
+
+	// rects
+	ClearRect(x float64, y float64, w float64, h float64)
+	FillRect(x float64, y float64, w float64, h float64)
+	StrokeRect(x float64, y float64, w float64, h float64)
+
+	// paths
+	BeginPath()
+	ClosePath()
+	MoveTo(x float64, y float64)
+	LineTo(x float64, y float64)
+	Rect(x float64, y float64, w float64, h float64)
+	Stroke()
+	Fill() // fill the current path with the nonzero rule
+	Clip() // intersect the clipping region with the current path
+
+	// text
+	SetFont(font string) // CSS font like `12px 'Roboto', sans-serif`
+	SetTextAlign(align TextAlign)
+	SetTextBaseline(baseline TextBaseline)
+	FillText(txt string, x float64, y float64)
+	MeasureText(txt string) TextMetrics
+}
+
+// TextMetrics are the dimensions of a text rendered with the current font
+type TextMetrics struct {
+	Width   float64 // advance width of the text
+	Ascent  float64 // distance from the baseline to the top of the text bounding box
+	Descent float64 // distance from the baseline to the bottom of the text bounding box
+}
+
+type TextAlign int
+
+const (
+	TA_Start  TextAlign = 0
+	TA_End    TextAlign = 1
+	TA_Center TextAlign = 2
+)
+
+type TextBaseline int
+
+const (
+	TB_Top    TextBaseline = 0
+	TB_Middle TextBaseline = 1
+	TB_Bottom TextBaseline = 2
+)
+
+type LineCap int
+
+const (
+	LC_Butt   LineCap = 0
+	LC_Round  LineCap = 1
+	LC_Square LineCap = 2
+)
+
+type LineJoin int
+
+const (
+	LJ_Miter LineJoin = 0
+	LJ_Round LineJoin = 1
+	LJ_Bevel LineJoin = 2
+)
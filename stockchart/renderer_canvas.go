@@ -0,0 +1,147 @@
+package stockchart
+
+import (
+	"github.com/gowebapi/webapi/core/js"
+	"github.com/gowebapi/webapi/html/canvas"
+	"github.com/larry868/rgb"
+)
+
+// CanvasRenderer is the Renderer backend drawing into an HTML5 canvas 2D context
+type CanvasRenderer struct {
+	ctx *canvas.CanvasRenderingContext2D
+}
+
+// NewCanvasRenderer wraps a canvas 2D context into a Renderer
+func NewCanvasRenderer(ctx *canvas.CanvasRenderingContext2D) *CanvasRenderer {
+	return &CanvasRenderer{ctx: ctx}
+}
+
+func (r *CanvasRenderer) Save() {
+	r.ctx.Save()
+}
+
+func (r *CanvasRenderer) Restore() {
+	r.ctx.Restore()
+}
+
+func (r *CanvasRenderer) SetFillStyle(color rgb.Color) {
+	r.ctx.SetFillStyle(&canvas.Union{Value: js.ValueOf(color.Hexa())})
+}
+
+func (r *CanvasRenderer) SetStrokeStyle(color rgb.Color) {
+	r.ctx.SetStrokeStyle(&canvas.Union{Value: js.ValueOf(color.Hexa())})
+}
+
+func (r *CanvasRenderer) SetLineWidth(width float64) {
+	r.ctx.SetLineWidth(width)
+}
+
+func (r *CanvasRenderer) SetLineDash(segments []float64) {
+	r.ctx.SetLineDash(segments)
+}
+
+func (r *CanvasRenderer) SetLineCap(linecap LineCap) {
+	switch linecap {
+	case LC_Round:
+		r.ctx.SetLineCap(canvas.RoundCanvasLineCap)
+	case LC_Square:
+		r.ctx.SetLineCap(canvas.SquareCanvasLineCap)
+	default:
+		r.ctx.SetLineCap(canvas.ButtCanvasLineCap)
+	}
+}
+
+func (r *CanvasRenderer) SetLineJoin(linejoin LineJoin) {
+	switch linejoin {
+	case LJ_Round:
+		r.ctx.SetLineJoin(canvas.RoundCanvasLineJoin)
+	case LJ_Bevel:
+		r.ctx.SetLineJoin(canvas.BevelCanvasLineJoin)
+	default:
+		r.ctx.SetLineJoin(canvas.MiterCanvasLineJoin)
+	}
+}
+
+func (r *CanvasRenderer) ClearRect(x float64, y float64, w float64, h float64) {
+	r.ctx.ClearRect(x, y, w, h)
+}
+
+func (r *CanvasRenderer) FillRect(x float64, y float64, w float64, h float64) {
+	r.ctx.FillRect(x, y, w, h)
+}
+
+func (r *CanvasRenderer) StrokeRect(x float64, y float64, w float64, h float64) {
+	r.ctx.StrokeRect(x, y, w, h)
+}
+
+func (r *CanvasRenderer) BeginPath() {
+	r.ctx.BeginPath()
+}
+
+func (r *CanvasRenderer) ClosePath() {
+	r.ctx.ClosePath()
+}
+
+func (r *CanvasRenderer) MoveTo(x float64, y float64) {
+	r.ctx.MoveTo(x, y)
+}
+
+func (r *CanvasRenderer) LineTo(x float64, y float64) {
+	r.ctx.LineTo(x, y)
+}
+
+func (r *CanvasRenderer) Rect(x float64, y float64, w float64, h float64) {
+	r.ctx.Rect(x, y, w, h)
+}
+
+func (r *CanvasRenderer) Stroke() {
+	r.ctx.Stroke()
+}
+
+func (r *CanvasRenderer) Fill() {
+	fillrule := canvas.NonzeroCanvasFillRule
+	r.ctx.Fill(&fillrule)
+}
+
+func (r *CanvasRenderer) Clip() {
+	fillrule := canvas.NonzeroCanvasFillRule
+	r.ctx.Clip(&fillrule)
+}
+
+func (r *CanvasRenderer) SetFont(font string) {
+	r.ctx.SetFont(font)
+}
+
+func (r *CanvasRenderer) SetTextAlign(align TextAlign) {
+	switch align {
+	case TA_End:
+		r.ctx.SetTextAlign(canvas.EndCanvasTextAlign)
+	case TA_Center:
+		r.ctx.SetTextAlign(canvas.CenterCanvasTextAlign)
+	default:
+		r.ctx.SetTextAlign(canvas.StartCanvasTextAlign)
+	}
+}
+
+func (r *CanvasRenderer) SetTextBaseline(baseline TextBaseline) {
+	switch baseline {
+	case TB_Top:
+		r.ctx.SetTextBaseline(canvas.TopCanvasTextBaseline)
+	case TB_Bottom:
+		r.ctx.SetTextBaseline(canvas.BottomCanvasTextBaseline)
+	default:
+		r.ctx.SetTextBaseline(canvas.MiddleCanvasTextBaseline)
+	}
+}
+
+func (r *CanvasRenderer) FillText(txt string, x float64, y float64) {
+	r.ctx.FillText(txt, x, y, nil)
+}
+
+func (r *CanvasRenderer) MeasureText(txt string) TextMetrics {
+	tm := r.ctx.MeasureText(txt)
+	return TextMetrics{
+		Width:   tm.Width(),
+		Ascent:  tm.ActualBoundingBoxAscent(),
+		Descent: tm.ActualBoundingBoxDescent()}
+}
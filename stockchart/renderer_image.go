@@ -0,0 +1,468 @@
+package stockchart
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/larry868/rgb"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// ImageRenderer is the Renderer backend drawing into an in-memory RGBA image, without any browser.
+//
+// Paths are rasterized with anti-aliasing. Texts are rendered with the Go fonts whatever the family
+// requested by SetFont, only the size in px and the bold weight are taken into account.
+type ImageRenderer struct {
+	img   *image.RGBA
+	state imageState   // the current drawing state
+	stack []imageState // states pushed by Save
+
+	path  []subpath // the current path
+	faces fontFaces // font faces cache
+}
+
+// imageState is the drawing state saved and restored by Save and Restore
+type imageState struct {
+	fill      rgb.Color
+	stroke    rgb.Color
+	linewidth float64
+	dash      []float64
+	linecap   LineCap
+	linejoin  LineJoin
+	font      string
+	align     TextAlign
+	baseline  TextBaseline
+	clip      *image.Alpha // the clipping mask, nil if no clipping
+}
+
+type fpoint struct {
+	x, y float64
+}
+
+type subpath struct {
+	pts    []fpoint
+	closed bool
+}
+
+// NewImageRenderer returns a Renderer drawing into a fully transparent image of width x height pixels
+func NewImageRenderer(width int, height int) *ImageRenderer {
+	r := new(ImageRenderer)
+	r.faces = make(fontFaces)
+	r.Resize(width, height)
+	return r
+}
+
+// Resize resets the image to a new fully transparent one, and resets the drawing state like a canvas does.
+func (r *ImageRenderer) Resize(width int, height int) {
+	r.img = image.NewRGBA(image.Rect(0, 0, imax(0, width), imax(0, height)))
+	r.state = imageState{
+		fill:      rgb.Black,
+		stroke:    rgb.Black,
+		linewidth: 1,
+		font:      `10px sans-serif`,
+		baseline:  TB_Bottom}
+	r.stack = nil
+	r.path = nil
+}
+
+// Image returns the image drawn so far
+func (r *ImageRenderer) Image() *image.RGBA {
+	return r.img
+}
+
+func (r *ImageRenderer) Save() {
+	r.stack = append(r.stack, r.state)
+}
+
+func (r *ImageRenderer) Restore() {
+	if len(r.stack) == 0 {
+		return
+	}
+	r.state = r.stack[len(r.stack)-1]
+	r.stack = r.stack[:len(r.stack)-1]
+}
+
+func (r *ImageRenderer) SetFillStyle(color rgb.Color) {
+	r.state.fill = color
+}
+
+func (r *ImageRenderer) SetStrokeStyle(color rgb.Color) {
+	r.state.stroke = color
+}
+
+// SetLineWidth changes the line width. Like the canvas, zero or negative values are ignored.
+func (r *ImageRenderer) SetLineWidth(width float64) {
+	if width > 0 {
+		r.state.linewidth = width
+	}
+}
+
+func (r *ImageRenderer) SetLineDash(segments []float64) {
+	r.state.dash = append([]float64{}, segments...)
+}
+
+func (r *ImageRenderer) SetLineCap(linecap LineCap) {
+	r.state.linecap = linecap
+}
+
+func (r *ImageRenderer) SetLineJoin(linejoin LineJoin) {
+	r.state.linejoin = linejoin
+}
+
+// ClearRect makes the rect fully transparent. The clipping region is ignored.
+func (r *ImageRenderer) ClearRect(x float64, y float64, w float64, h float64) {
+	rect := pixelRect(x, y, w, h).Intersect(r.img.Bounds())
+	draw.Draw(r.img, rect, image.Transparent, image.Point{}, draw.Src)
+}
+
+func (r *ImageRenderer) FillRect(x float64, y float64, w float64, h float64) {
+	// fast path for pixel aligned rects
+	if r.state.clip == nil && x == math.Trunc(x) && y == math.Trunc(y) && w == math.Trunc(w) && h == math.Trunc(h) {
+		rect := pixelRect(x, y, w, h).Intersect(r.img.Bounds())
+		draw.Draw(r.img, rect, image.NewUniform(toNRGBA(r.state.fill)), image.Point{}, draw.Over)
+		return
+	}
+	r.fillPolygons([][]fpoint{rectPoints(x, y, w, h)}, r.state.fill)
+}
+
+func (r *ImageRenderer) StrokeRect(x float64, y float64, w float64, h float64) {
+	r.strokeSubpaths([]subpath{{pts: rectPoints(x, y, w, h), closed: true}})
+}
+
+func (r *ImageRenderer) BeginPath() {
+	r.path = r.path[:0]
+}
+
+func (r *ImageRenderer) ClosePath() {
+	if len(r.path) == 0 {
+		return
+	}
+	last := &r.path[len(r.path)-1]
+	if len(last.pts) == 0 {
+		return
+	}
+	last.closed = true
+	// a new subpath starts at the first point of the closed one
+	r.path = append(r.path, subpath{pts: []fpoint{last.pts[0]}})
+}
+
+func (r *ImageRenderer) MoveTo(x float64, y float64) {
+	r.path = append(r.path, subpath{pts: []fpoint{{x, y}}})
+}
+
+// LineTo adds a line to the current subpath, or starts a new one if there's no current point
+func (r *ImageRenderer) LineTo(x float64, y float64) {
+	if len(r.path) == 0 {
+		r.MoveTo(x, y)
+		return
+	}
+	last := &r.path[len(r.path)-1]
+	last.pts = append(last.pts, fpoint{x, y})
+}
+
+func (r *ImageRenderer) Rect(x float64, y float64, w float64, h float64) {
+	r.path = append(r.path, subpath{pts: rectPoints(x, y, w, h), closed: true})
+	r.MoveTo(x, y)
+}
+
+func (r *ImageRenderer) Stroke() {
+	r.strokeSubpaths(r.path)
+}
+
+func (r *ImageRenderer) Fill() {
+	polys := make([][]fpoint, 0, len(r.path))
+	for _, sp := range r.path {
+		if len(sp.pts) > 2 {
+			polys = append(polys, sp.pts)
+		}
+	}
+	r.fillPolygons(polys, r.state.fill)
+}
+
+// Clip intersects the current clipping region with the current path
+func (r *ImageRenderer) Clip() {
+	b := r.img.Bounds()
+	mask := image.NewAlpha(b)
+	z := vector.NewRasterizer(b.Dx(), b.Dy())
+	for _, sp := range r.path {
+		if len(sp.pts) > 2 {
+			addPolygon(z, sp.pts, 0, 0)
+		}
+	}
+	z.DrawOp = draw.Src
+	z.Draw(mask, b, image.Opaque, image.Point{})
+	if r.state.clip != nil {
+		for i := range mask.Pix {
+			mask.Pix[i] = uint8(uint16(mask.Pix[i]) * uint16(r.state.clip.Pix[i]) / 255)
+		}
+	}
+	r.state.clip = mask
+}
+
+func (r *ImageRenderer) SetFont(font string) {
+	r.state.font = font
+}
+
+func (r *ImageRenderer) SetTextAlign(align TextAlign) {
+	r.state.align = align
+}
+
+func (r *ImageRenderer) SetTextBaseline(baseline TextBaseline) {
+	r.state.baseline = baseline
+}
+
+func (r *ImageRenderer) FillText(txt string, x float64, y float64) {
+	face := r.faces.face(r.state.font)
+	width := fixedToFloat(font.MeasureString(face, txt))
+	metrics := face.Metrics()
+	ascent := fixedToFloat(metrics.Ascent)
+	descent := fixedToFloat(metrics.Descent)
+
+	switch r.state.align {
+	case TA_End:
+		x -= width
+	case TA_Center:
+		x -= width / 2
+	}
+	switch r.state.baseline {
+	case TB_Top:
+		y += ascent
+	case TB_Middle:
+		y += (ascent - descent) / 2
+	case TB_Bottom:
+		y -= descent
+	}
+
+	var dst draw.Image = r.img
+	if r.state.clip != nil {
+		dst = clippedImage{RGBA: r.img, clip: r.state.clip}
+	}
+	drawer := font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(toNRGBA(r.state.fill)),
+		Face: face,
+		Dot:  fixed.Point26_6{X: fixed.Int26_6(math.Round(x * 64)), Y: fixed.Int26_6(math.Round(y * 64))}}
+	drawer.DrawString(txt)
+}
+
+func (r *ImageRenderer) MeasureText(txt string) TextMetrics {
+	return measureText(r.faces.face(r.state.font), txt)
+}
+
+/*
+ * Rasterization
+ */
+
+// fillPolygons rasterizes and fills polygons with color, taking into account the clipping region.
+// Only the bounding box of the polygons is rasterized.
+func (r *ImageRenderer) fillPolygons(polys [][]fpoint, c rgb.Color) {
+	if len(polys) == 0 || c.Alpha() == 0 {
+		return
+	}
+
+	// get the bounding box
+	minx, miny := math.Inf(1), math.Inf(1)
+	maxx, maxy := math.Inf(-1), math.Inf(-1)
+	for _, poly := range polys {
+		for _, pt := range poly {
+			minx, maxx = math.Min(minx, pt.x), math.Max(maxx, pt.x)
+			miny, maxy = math.Min(miny, pt.y), math.Max(maxy, pt.y)
+		}
+	}
+	bbox := image.Rect(int(math.Floor(minx)), int(math.Floor(miny)), int(math.Ceil(maxx)), int(math.Ceil(maxy))).Intersect(r.img.Bounds())
+	if bbox.Empty() {
+		return
+	}
+
+	// rasterize the polygons into a mask
+	z := vector.NewRasterizer(bbox.Dx(), bbox.Dy())
+	for _, poly := range polys {
+		addPolygon(z, poly, float64(bbox.Min.X), float64(bbox.Min.Y))
+	}
+	mask := image.NewAlpha(image.Rect(0, 0, bbox.Dx(), bbox.Dy()))
+	z.DrawOp = draw.Src
+	z.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
+
+	// apply the clipping region
+	if clip := r.state.clip; clip != nil {
+		for y := 0; y < bbox.Dy(); y++ {
+			for x := 0; x < bbox.Dx(); x++ {
+				i := mask.PixOffset(x, y)
+				ca := clip.AlphaAt(bbox.Min.X+x, bbox.Min.Y+y).A
+				mask.Pix[i] = uint8(uint16(mask.Pix[i]) * uint16(ca) / 255)
+			}
+		}
+	}
+
+	draw.DrawMask(r.img, bbox, image.NewUniform(toNRGBA(c)), image.Point{}, mask, image.Point{}, draw.Over)
+}
+
+// strokeSubpaths converts subpaths into polygons according to the line width, the dash and the cap, then fill them.
+func (r *ImageRenderer) strokeSubpaths(subpaths []subpath) {
+	hw := r.state.linewidth / 2
+	polys := make([][]fpoint, 0)
+	for _, sp := range subpaths {
+		pts := sp.pts
+		if sp.closed && len(pts) > 1 {
+			pts = append(append([]fpoint{}, pts...), pts[0])
+		}
+		for _, line := range dashPolyline(pts, r.state.dash) {
+			for i := 1; i < len(line); i++ {
+				a, b := line[i-1], line[i]
+				if a == b {
+					continue
+				}
+				// extend the segment at both ends for square caps
+				if r.state.linecap == LC_Square {
+					dx, dy := unitVector(a, b)
+					if i == 1 {
+						a = fpoint{a.x - dx*hw, a.y - dy*hw}
+					}
+					if i == len(line)-1 {
+						b = fpoint{b.x + dx*hw, b.y + dy*hw}
+					}
+				}
+				polys = append(polys, segmentPolygon(a, b, hw))
+			}
+			// joins and round caps
+			if hw > 1 || r.state.linecap == LC_Round {
+				for i, pt := range line {
+					isend := i == 0 || i == len(line)-1
+					if (isend && r.state.linecap == LC_Round) || (!isend && hw > 1) {
+						polys = append(polys, circlePolygon(pt, hw))
+					}
+				}
+			}
+		}
+	}
+	r.fillPolygons(polys, r.state.stroke)
+}
+
+// clippedImage is an image where Set is masked by a clipping region
+type clippedImage struct {
+	*image.RGBA
+	clip *image.Alpha
+}
+
+func (ci clippedImage) Set(x int, y int, c color.Color) {
+	ca := uint32(ci.clip.AlphaAt(x, y).A)
+	if ca == 0 {
+		return
+	}
+	if ca == 255 {
+		ci.RGBA.Set(x, y, c)
+		return
+	}
+	// blend the new color with the existing one according to the clip alpha
+	or, og, ob, oa := ci.RGBA.At(x, y).RGBA()
+	nr, ng, nb, na := c.RGBA()
+	blend := func(o, n uint32) uint16 { return uint16((o*(255-ca) + n*ca) / 255) }
+	ci.RGBA.Set(x, y, color.RGBA64{R: blend(or, nr), G: blend(og, ng), B: blend(ob, nb), A: blend(oa, na)})
+}
+
+/*
+ * Geometry helpers
+ */
+
+func toNRGBA(c rgb.Color) color.NRGBA {
+	r, g, b, a := c.RGBA()
+	return color.NRGBA{R: r, G: g, B: b, A: a}
+}
+
+// pixelRect returns the image rectangle of a rect in float coordinates, with positive sizes
+func pixelRect(x float64, y float64, w float64, h float64) image.Rectangle {
+	if w < 0 {
+		x, w = x+w, -w
+	}
+	if h < 0 {
+		y, h = y+h, -h
+	}
+	return image.Rect(int(math.Round(x)), int(math.Round(y)), int(math.Round(x+w)), int(math.Round(y+h)))
+}
+
+func rectPoints(x float64, y float64, w float64, h float64) []fpoint {
+	return []fpoint{{x, y}, {x + w, y}, {x + w, y + h}, {x, y + h}}
+}
+
+// addPolygon adds a closed polygon to the rasterizer, shifted by the rasterizer origin ox,oy
+func addPolygon(z *vector.Rasterizer, poly []fpoint, ox float64, oy float64) {
+	z.MoveTo(float32(poly[0].x-ox), float32(poly[0].y-oy))
+	for _, pt := range poly[1:] {
+		z.LineTo(float32(pt.x-ox), float32(pt.y-oy))
+	}
+	z.ClosePath()
+}
+
+func unitVector(a fpoint, b fpoint) (dx float64, dy float64) {
+	l := math.Hypot(b.x-a.x, b.y-a.y)
+	return (b.x - a.x) / l, (b.y - a.y) / l
+}
+
+// segmentPolygon returns the rectangle covering the segment ab with a half width hw.
+// All rectangles are built with the same winding so overlaps never cancel each others.
+func segmentPolygon(a fpoint, b fpoint, hw float64) []fpoint {
+	dx, dy := unitVector(a, b)
+	nx, ny := -dy*hw, dx*hw
+	return []fpoint{{a.x + nx, a.y + ny}, {b.x + nx, b.y + ny}, {b.x - nx, b.y - ny}, {a.x - nx, a.y - ny}}
+}
+
+// circlePolygon returns a polygon approximating a circle, with the same winding than segmentPolygon
+func circlePolygon(c fpoint, radius float64) []fpoint {
+	const nbpts = 16
+	poly := make([]fpoint, nbpts)
+	for i := range poly {
+		angle := -2 * math.Pi * float64(i) / nbpts
+		poly[i] = fpoint{c.x + radius*math.Cos(angle), c.y + radius*math.Sin(angle)}
+	}
+	return poly
+}
+
+// dashPolyline splits a polyline into dashes according to the dash pattern.
+// Returns the polyline unchanged if the pattern is empty.
+func dashPolyline(pts []fpoint, pattern []float64) [][]fpoint {
+	if len(pts) < 2 {
+		return nil
+	}
+	total := 0.0
+	for _, d := range pattern {
+		total += d
+	}
+	if len(pattern) == 0 || total <= 0 {
+		return [][]fpoint{pts}
+	}
+	// like the canvas, an odd pattern is repeated to get an even number of entries
+	if len(pattern)%2 != 0 {
+		pattern = append(append([]float64{}, pattern...), pattern...)
+	}
+
+	dashes := make([][]fpoint, 0)
+	idash, remain, on := 0, pattern[0], true
+	current := []fpoint{pts[0]}
+	for i := 1; i < len(pts); i++ {
+		a, b := pts[i-1], pts[i]
+		seglen := math.Hypot(b.x-a.x, b.y-a.y)
+		pos := 0.0
+		for seglen-pos > remain {
+			pos += remain
+			rate := pos / seglen
+			pt := fpoint{a.x + (b.x-a.x)*rate, a.y + (b.y-a.y)*rate}
+			if on {
+				dashes = append(dashes, append(current, pt))
+			}
+			current = []fpoint{pt}
+			on = !on
+			idash = (idash + 1) % len(pattern)
+			remain = pattern[idash]
+		}
+		remain -= seglen - pos
+		current = append(current, b)
+	}
+	if on && len(current) > 1 {
+		dashes = append(dashes, current)
+	}
+	return dashes
+}
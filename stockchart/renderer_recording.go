@@ -0,0 +1,173 @@
+package stockchart
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/larry868/rgb"
+)
+
+// RenderCall is a single call recorded by a RecordingRenderer
+type RenderCall struct {
+	Name string // the name of the Renderer method called, like "FillRect"
+	Args []any  // the arguments of the call
+}
+
+// String interface for RenderCall, mainly for debugging purpose
+func (call RenderCall) String() string {
+	strargs := make([]string, len(call.Args))
+	for i, arg := range call.Args {
+		strargs[i] = fmt.Sprintf("%v", arg)
+	}
+	return fmt.Sprintf("%s(%s)", call.Name, strings.Join(strargs, ", "))
+}
+
+// RecordingRenderer is a Renderer backend recording every call without drawing anything.
+// It allows to check what a drawing does, without any browser. Texts are measured with the Go fonts.
+type RecordingRenderer struct {
+	Calls []RenderCall
+
+	font  string
+	stack []string
+	faces fontFaces // font faces cache, to measure texts
+}
+
+// NewRecordingRenderer returns a RecordingRenderer without any call recorded
+func NewRecordingRenderer() *RecordingRenderer {
+	r := new(RecordingRenderer)
+	r.font = `10px sans-serif`
+	r.faces = make(fontFaces)
+	return r
+}
+
+// Reset forgets all recorded calls
+func (r *RecordingRenderer) Reset() {
+	r.Calls = r.Calls[:0]
+}
+
+// Count returns the number of recorded calls of the name method
+func (r *RecordingRenderer) Count(name string) (count int) {
+	for _, call := range r.Calls {
+		if call.Name == name {
+			count++
+		}
+	}
+	return count
+}
+
+// Filter returns the recorded calls of the name method, in the order of the calls
+func (r *RecordingRenderer) Filter(name string) (calls []RenderCall) {
+	for _, call := range r.Calls {
+		if call.Name == name {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+func (r *RecordingRenderer) record(name string, args ...any) {
+	r.Calls = append(r.Calls, RenderCall{Name: name, Args: args})
+}
+
+func (r *RecordingRenderer) Save() {
+	r.stack = append(r.stack, r.font)
+	r.record("Save")
+}
+
+func (r *RecordingRenderer) Restore() {
+	if len(r.stack) > 0 {
+		r.font = r.stack[len(r.stack)-1]
+		r.stack = r.stack[:len(r.stack)-1]
+	}
+	r.record("Restore")
+}
+
+func (r *RecordingRenderer) SetFillStyle(color rgb.Color) {
+	r.record("SetFillStyle", color)
+}
+
+func (r *RecordingRenderer) SetStrokeStyle(color rgb.Color) {
+	r.record("SetStrokeStyle", color)
+}
+
+func (r *RecordingRenderer) SetLineWidth(width float64) {
+	r.record("SetLineWidth", width)
+}
+
+func (r *RecordingRenderer) SetLineDash(segments []float64) {
+	r.record("SetLineDash", append([]float64{}, segments...))
+}
+
+func (r *RecordingRenderer) SetLineCap(linecap LineCap) {
+	r.record("SetLineCap", linecap)
+}
+
+func (r *RecordingRenderer) SetLineJoin(linejoin LineJoin) {
+	r.record("SetLineJoin", linejoin)
+}
+
+func (r *RecordingRenderer) ClearRect(x float64, y float64, w float64, h float64) {
+	r.record("ClearRect", x, y, w, h)
+}
+
+func (r *RecordingRenderer) FillRect(x float64, y float64, w float64, h float64) {
+	r.record("FillRect", x, y, w, h)
+}
+
+func (r *RecordingRenderer) StrokeRect(x float64, y float64, w float64, h float64) {
+	r.record("StrokeRect", x, y, w, h)
+}
+
+func (r *RecordingRenderer) BeginPath() {
+	r.record("BeginPath")
+}
+
+func (r *RecordingRenderer) ClosePath() {
+	r.record("ClosePath")
+}
+
+func (r *RecordingRenderer) MoveTo(x float64, y float64) {
+	r.record("MoveTo", x, y)
+}
+
+func (r *RecordingRenderer) LineTo(x float64, y float64) {
+	r.record("LineTo", x, y)
+}
+
+func (r *RecordingRenderer) Rect(x float64, y float64, w float64, h float64) {
+	r.record("Rect", x, y, w, h)
+}
+
+func (r *RecordingRenderer) Stroke() {
+	r.record("Stroke")
+}
+
+func (r *RecordingRenderer) Fill() {
+	r.record("Fill")
+}
+
+func (r *RecordingRenderer) Clip() {
+	r.record("Clip")
+}
+
+func (r *RecordingRenderer) SetFont(font string) {
+	r.font = font
+	r.record("SetFont", font)
+}
+
+func (r *RecordingRenderer) SetTextAlign(align TextAlign) {
+	r.record("SetTextAlign", align)
+}
+
+func (r *RecordingRenderer) SetTextBaseline(baseline TextBaseline) {
+	r.record("SetTextBaseline", baseline)
+}
+
+func (r *RecordingRenderer) FillText(txt string, x float64, y float64) {
+	r.record("FillText", txt, x, y)
+}
+
+// MeasureText is not recorded, it does not draw anything
+func (r *RecordingRenderer) MeasureText(txt string) TextMetrics {
+	return measureText(r.faces.face(r.font), txt)
+}
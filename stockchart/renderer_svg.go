@@ -0,0 +1,260 @@
+package stockchart
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/larry868/rgb"
+)
+
+// SVGRenderer is the Renderer backend emitting SVG elements, for a vector output that stays sharp when scaled.
+//
+// SVG elements are stacked, so ClearRect has no effect. Texts are measured with the Go fonts.
+type SVGRenderer struct {
+	buf   bytes.Buffer
+	state svgState
+	stack []svgState
+
+	path   strings.Builder // the current path, in the SVG path data format
+	idpfx  string          // prefix of the ids of the clipPath elements
+	nextid int
+	faces  fontFaces // font faces cache, to measure texts
+}
+
+// svgState is the drawing state saved and restored by Save and Restore
+type svgState struct {
+	fill      rgb.Color
+	stroke    rgb.Color
+	linewidth float64
+	dash      []float64
+	linecap   LineCap
+	linejoin  LineJoin
+	font      string
+	align     TextAlign
+	baseline  TextBaseline
+	groups    int // number of clipping groups opened
+}
+
+// NewSVGRenderer returns an empty SVGRenderer. idprefix must be unique within the SVG document.
+func NewSVGRenderer(idprefix string) *SVGRenderer {
+	r := new(SVGRenderer)
+	r.idpfx = idprefix
+	r.faces = make(fontFaces)
+	r.state = svgState{
+		fill:      rgb.Black,
+		stroke:    rgb.Black,
+		linewidth: 1,
+		font:      `10px sans-serif`,
+		baseline:  TB_Bottom}
+	return r
+}
+
+// String returns the SVG elements emitted so far, closing all clipping groups
+func (r *SVGRenderer) String() string {
+	return r.buf.String() + strings.Repeat("</g>", r.state.groups)
+}
+
+func (r *SVGRenderer) Save() {
+	r.stack = append(r.stack, r.state)
+}
+
+// Restore restores the last saved state, closing the clipping groups opened since then
+func (r *SVGRenderer) Restore() {
+	if len(r.stack) == 0 {
+		return
+	}
+	saved := r.stack[len(r.stack)-1]
+	r.stack = r.stack[:len(r.stack)-1]
+	r.buf.WriteString(strings.Repeat("</g>", r.state.groups-saved.groups))
+	r.state = saved
+}
+
+func (r *SVGRenderer) SetFillStyle(color rgb.Color) {
+	r.state.fill = color
+}
+
+func (r *SVGRenderer) SetStrokeStyle(color rgb.Color) {
+	r.state.stroke = color
+}
+
+// SetLineWidth changes the line width. Like the canvas, zero or negative values are ignored.
+func (r *SVGRenderer) SetLineWidth(width float64) {
+	if width > 0 {
+		r.state.linewidth = width
+	}
+}
+
+func (r *SVGRenderer) SetLineDash(segments []float64) {
+	r.state.dash = append([]float64{}, segments...)
+}
+
+func (r *SVGRenderer) SetLineCap(linecap LineCap) {
+	r.state.linecap = linecap
+}
+
+func (r *SVGRenderer) SetLineJoin(linejoin LineJoin) {
+	r.state.linejoin = linejoin
+}
+
+// ClearRect has no effect, SVG elements are only stacked
+func (r *SVGRenderer) ClearRect(x float64, y float64, w float64, h float64) {
+}
+
+func (r *SVGRenderer) FillRect(x float64, y float64, w float64, h float64) {
+	if r.state.fill.Alpha() == 0 {
+		return
+	}
+	fmt.Fprintf(&r.buf, `<rect %s %s/>`, svgRectAttr(x, y, w, h), svgFillAttr(r.state.fill))
+}
+
+func (r *SVGRenderer) StrokeRect(x float64, y float64, w float64, h float64) {
+	fmt.Fprintf(&r.buf, `<rect %s fill="none" %s/>`, svgRectAttr(x, y, w, h), r.strokeAttr())
+}
+
+func (r *SVGRenderer) BeginPath() {
+	r.path.Reset()
+}
+
+func (r *SVGRenderer) ClosePath() {
+	r.path.WriteString("Z")
+}
+
+func (r *SVGRenderer) MoveTo(x float64, y float64) {
+	fmt.Fprintf(&r.path, "M%s %s", svgNum(x), svgNum(y))
+}
+
+// LineTo adds a line to the current subpath. Like the canvas, it starts a new subpath if there's no current point
+func (r *SVGRenderer) LineTo(x float64, y float64) {
+	if r.path.Len() == 0 {
+		r.MoveTo(x, y)
+		return
+	}
+	fmt.Fprintf(&r.path, "L%s %s", svgNum(x), svgNum(y))
+}
+
+func (r *SVGRenderer) Rect(x float64, y float64, w float64, h float64) {
+	fmt.Fprintf(&r.path, "M%s %sh%sv%sh%sZ", svgNum(x), svgNum(y), svgNum(w), svgNum(h), svgNum(-w))
+}
+
+func (r *SVGRenderer) Stroke() {
+	if r.path.Len() == 0 {
+		return
+	}
+	fmt.Fprintf(&r.buf, `<path d="%s" fill="none" %s/>`, r.path.String(), r.strokeAttr())
+}
+
+func (r *SVGRenderer) Fill() {
+	if r.path.Len() == 0 || r.state.fill.Alpha() == 0 {
+		return
+	}
+	fmt.Fprintf(&r.buf, `<path d="%s" %s/>`, r.path.String(), svgFillAttr(r.state.fill))
+}
+
+// Clip defines a clipPath with the current path and opens a group clipped by it, closed by Restore.
+func (r *SVGRenderer) Clip() {
+	r.nextid++
+	id := fmt.Sprintf("%sclip%d", r.idpfx, r.nextid)
+	fmt.Fprintf(&r.buf, `<clipPath id="%s"><path d="%s"/></clipPath><g clip-path="url(#%s)">`, id, r.path.String(), id)
+	r.state.groups++
+}
+
+func (r *SVGRenderer) SetFont(font string) {
+	r.state.font = font
+}
+
+func (r *SVGRenderer) SetTextAlign(align TextAlign) {
+	r.state.align = align
+}
+
+func (r *SVGRenderer) SetTextBaseline(baseline TextBaseline) {
+	r.state.baseline = baseline
+}
+
+func (r *SVGRenderer) FillText(txt string, x float64, y float64) {
+	anchor := "start"
+	switch r.state.align {
+	case TA_End:
+		anchor = "end"
+	case TA_Center:
+		anchor = "middle"
+	}
+	baseline := "text-after-edge"
+	switch r.state.baseline {
+	case TB_Top:
+		baseline = "text-before-edge"
+	case TB_Middle:
+		baseline = "middle"
+	}
+	var esc bytes.Buffer
+	xml.EscapeText(&esc, []byte(txt))
+	var font bytes.Buffer
+	xml.EscapeText(&font, []byte(r.state.font))
+	fmt.Fprintf(&r.buf, `<text x="%s" y="%s" style="font:%s" text-anchor="%s" dominant-baseline="%s" %s>%s</text>`,
+		svgNum(x), svgNum(y), font.String(), anchor, baseline, svgFillAttr(r.state.fill), esc.String())
+}
+
+func (r *SVGRenderer) MeasureText(txt string) TextMetrics {
+	return measureText(r.faces.face(r.state.font), txt)
+}
+
+// strokeAttr returns the attributes of a stroke according to the current state
+func (r *SVGRenderer) strokeAttr() string {
+	str := fmt.Sprintf(`stroke="%s" stroke-width="%s"`, svgColor(r.state.stroke), svgNum(r.state.linewidth))
+	if a := r.state.stroke.Alpha(); a != 255 {
+		str += fmt.Sprintf(` stroke-opacity="%s"`, svgNum(float64(a)/255))
+	}
+	if len(r.state.dash) > 0 {
+		strdash := make([]string, len(r.state.dash))
+		for i, d := range r.state.dash {
+			strdash[i] = svgNum(d)
+		}
+		str += fmt.Sprintf(` stroke-dasharray="%s"`, strings.Join(strdash, " "))
+	}
+	switch r.state.linecap {
+	case LC_Round:
+		str += ` stroke-linecap="round"`
+	case LC_Square:
+		str += ` stroke-linecap="square"`
+	}
+	switch r.state.linejoin {
+	case LJ_Round:
+		str += ` stroke-linejoin="round"`
+	case LJ_Bevel:
+		str += ` stroke-linejoin="bevel"`
+	}
+	return str
+}
+
+// svgRectAttr returns the position and size attributes of a rect, with positive sizes
+func svgRectAttr(x float64, y float64, w float64, h float64) string {
+	if w < 0 {
+		x, w = x+w, -w
+	}
+	if h < 0 {
+		y, h = y+h, -h
+	}
+	return fmt.Sprintf(`x="%s" y="%s" width="%s" height="%s"`, svgNum(x), svgNum(y), svgNum(w), svgNum(h))
+}
+
+func svgFillAttr(c rgb.Color) string {
+	str := fmt.Sprintf(`fill="%s"`, svgColor(c))
+	if a := c.Alpha(); a != 255 {
+		str += fmt.Sprintf(` fill-opacity="%s"`, svgNum(float64(a)/255))
+	}
+	return str
+}
+
+// svgColor returns the #RRGGBB color, the opacity is set apart
+func svgColor(c rgb.Color) string {
+	r, g, b := c.RGB()
+	return fmt.Sprintf("#%02X%02X%02X", r, g, b)
+}
+
+// svgNum formats a number with at most 3 decimals
+func svgNum(f float64) string {
+	return strconv.FormatFloat(math.Round(f*1000)/1000, 'f', -1, 64)
+}
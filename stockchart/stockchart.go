@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/larry868/datarange"
 	"github.com/larry868/rgb"
@@ -14,10 +15,6 @@ import (
 	"github.com/larry868/verbose"
 
 	"github.com/gowebapi/webapi"
-	"github.com/gowebapi/webapi/core/js"
-	"github.com/gowebapi/webapi/css/typedom"
-	"github.com/gowebapi/webapi/dom"
-	"github.com/gowebapi/webapi/html/canvas"
 	"github.com/gowebapi/webapi/html/htmlevent"
 )
 
@@ -25,19 +22,38 @@ import (
 type StockChart struct {
 	ID string // the identifier of this chart, the canvas id
 
-	masterE   *dom.Element // the master element containing the chart
-	layers    [6]*Layer    // the 6 drawing layers composing a stockchart
-	isDrawing bool         // flag signaling a drawing in progress
+	host           host     // the environment embedding the chart
+	layers         []*Layer // the 6 drawing layers composing a stockchart, followed by the layers of the panes
+	panes          []*Pane  // the panes stacked below the chart, from top to bottom
+	frameRequested bool     // a frame has been requested to the host and not yet painted
+	checkNeeds     bool     // at the next frame, redraw layers having drawings needing it
 
 	MainSeries        DataList
-	timeRange         timeline.TimeSlice  // the overall time range to display
-	selectedTimeSlice timeline.TimeSlice  // the current time slice selected, IsZero if none
-	selectedData      *DataStock          // the current data selected, nil if none
-	localZone         bool                // Show local zone time, otherwise show UTC time
-	yAxisRange        datarange.DataRange // the yAxisRange calculated by the YGrid, can be used by any drawing on the chart layer and above
+	timeRange         timeline.TimeSlice // the overall time range to display
+	extendRate        float64            // the rate extending the time range to the future
+	selectedTimeSlice timeline.TimeSlice // the current time slice selected, IsZero if none
+	selectedData      *DataStock         // the current data selected, nil if none
+	localZone         bool               // Show local zone time, otherwise show UTC time
+	autoFollow        bool               // the selected time slice slides with the head when new data are appended
+	latestButton      Rect               // the area of the "jump to latest" button on the hover layer, zero if hidden
+	calendar          *TradingCalendar   // the trading calendar driving the x axis of all layers, nil for a linear time axis
+
+	baseSeries       DataList            // the series at the base resolution, aggregated to the timeframe
+	timeframes       []Timeframe         // the timeframes the chart can switch to, nil if none
+	timeframe        *Timeframe          // the current timeframe, nil if none
+	showTimeframes   bool                // show the timeframe selector
+	timeframeButtons []Rect              // the areas of the timeframe selector buttons on the hover layer, in the order of timeframes
+	yAxisRange       datarange.DataRange // the yAxisRange calculated by the YGrid, can be used by any drawing on the chart layer and above
+
+	navSeries   *DrawingSeries  // the series drawing of the MainSeries in the navbar
+	mainCandles *DrawingCandles // the candles drawing of the MainSeries in the chart
+	mainBars    *DrawingBars    // the volume bars of the MainSeries, in the chart or in a pane
 
 	NotifySelChangeTimeSlice func(ts timeline.TimeSlice) // function called everytime the timeselection change, if not nil
 	NotifySelChangeData      func(data *DataStock)
+	NotifyNeedHistory        func(before time.Time) // function called when the selected time slice approaches the tail of the MainSeries, if not nil. See PrependData
+	historyRequested         time.Time              // the start of the tail when NotifyNeedHistory has been called
+	NotifyChangeTimeframe    func(tf Timeframe)     // function called everytime the user switches the timeframe, if not nil
 }
 
 // String interface for StockChart, mainly for debugging purpose
@@ -83,6 +99,7 @@ func (pchart *StockChart) ResetMainSeries(series DataList, extendrate float64, r
 	}
 	pchart.timeRange.From = trange.From
 	pchart.timeRange.To = trange.To
+	pchart.extendRate = extendrate
 
 	// init or adjust the SelTimeSlice according to the new timerange
 	if pchart.selectedTimeSlice.IsZero() || pchart.selectedTimeSlice.From.Before(trange.From) || pchart.selectedTimeSlice.To.After(trange.To) {
@@ -104,6 +121,7 @@ func (pchart *StockChart) AddSubChart(layerid int, dr *Drawing) {
 
 	pchart.layers[layerid].AddDrawing(dr, rgb.None, false)
 	dr.DrawArea = getMainDrawArea
+	dr.drawArea = dr.DrawArea(pchart.layers[layerid].ClipArea)
 }
 
 // SetTimeRange defines the overall time range to display. Extend the end with extendCoef.
@@ -124,6 +142,7 @@ func (pchart *StockChart) SetTimeRange(timerange timeline.TimeSlice, extendrate
 
 	pchart.timeRange.From = timerange.From
 	pchart.timeRange.To = timerange.To
+	pchart.extendRate = extendrate
 
 	selNeedUpdate := pchart.selectedTimeSlice.IsZero() || pchart.selectedTimeSlice.From.Before(pchart.timeRange.From) || pchart.selectedTimeSlice.To.After(pchart.timeRange.To)
 	if selNeedUpdate {
@@ -147,10 +166,28 @@ func NewStockChart(chartid string, bgcolor rgb.Color, series DataList, extendrat
 	}
 
 	// build the chart object
+	chart := newStockChart(chartid, &browserHost{chartid: chartid, masterE: stockchartE}, bgcolor, series, extendrate)
+
+	// Add event listener on resize event
+	webapi.GetWindow().AddEventResize(func(event *htmlevent.UIEvent, win *webapi.Window) {
+		// resizing the chart will resize and redraw every layers
+		chart.Resize()
+	})
+
+	// size it the first time to force a full redraw
+	//	chart.Resize()
+
+	return chart, nil
+}
+
+// newStockChart builds the chart object and its layers on the host
+func newStockChart(chartid string, h host, bgcolor rgb.Color, series DataList, extendrate float64) *StockChart {
 	chart := &StockChart{
 		ID:         chartid,
-		masterE:    stockchartE,
-		MainSeries: series}
+		host:       h,
+		layers:     make([]*Layer, 6),
+		MainSeries: series,
+		autoFollow: true}
 
 	// by default the timeMaxRange is the full timeslice + 10% to represents the future.
 	chart.SetTimeRange(chart.MainSeries.TimeSlice(), extendrate)
@@ -163,7 +200,8 @@ func NewStockChart(chartid string, bgcolor rgb.Color, series DataList, extendrat
 
 	// the navbar layer, updated only when navXAxisRange change
 	if layer := chart.addNewLayer("1-navbar", lAREA_NAVBAR, rgb.White, &chart.timeRange); layer != nil {
-		dr := layer.AddDrawing(&NewDrawingSeries(&chart.MainSeries, true).Drawing, rgb.White, true)
+		chart.navSeries = NewDrawingSeries(&chart.MainSeries, true)
+		dr := layer.AddDrawing(&chart.navSeries.Drawing, rgb.White, true)
 		dr.DrawArea = func(cliparea Rect) Rect {
 			area := cliparea
 			area.O.Y += 5
@@ -201,7 +239,8 @@ func NewStockChart(chartid string, bgcolor rgb.Color, series DataList, extendrat
 		layer.AddDrawing(&NewDrawingXGrid(&chart.MainSeries, false, true).Drawing, rgb.None, true)
 
 		// The volume bars
-		dr = layer.AddDrawing(&NewDrawingBars(&chart.MainSeries).Drawing, rgb.None, true)
+		chart.mainBars = NewDrawingBars(&chart.MainSeries)
+		dr = layer.AddDrawing(&chart.mainBars.Drawing, rgb.None, true)
 		dr.DrawArea = func(cliparea Rect) Rect {
 			area := cliparea.Shrink(0, 5)
 			h := int(float64(area.Height) * 0.15) // draw bars at the bottom of the cliparea
@@ -211,7 +250,8 @@ func NewStockChart(chartid string, bgcolor rgb.Color, series DataList, extendrat
 		}
 
 		// The candles
-		dr = layer.AddDrawing(&NewDrawingCandles(&chart.MainSeries, DS_Stick).Drawing, rgb.White, true)
+		chart.mainCandles = NewDrawingCandles(&chart.MainSeries, DS_Stick)
+		dr = layer.AddDrawing(&chart.mainCandles.Drawing, rgb.White, true)
 		dr.DrawArea = getMainDrawArea
 
 		chart.layers[4] = layer
@@ -220,20 +260,13 @@ func NewStockChart(chartid string, bgcolor rgb.Color, series DataList, extendrat
 	// the hover transprent layer
 	if layer := chart.addNewLayer("5-hover", lAREA_GRAPH, rgb.None, &chart.selectedTimeSlice); layer != nil {
 		layer.AddDrawing(&NewDrawingHoverCandles(&chart.MainSeries).Drawing, rgb.White, true)
+		layer.AddDrawing(&NewDrawingTimeframes(&chart.MainSeries).Drawing, rgb.None, true)
+		layer.AddDrawing(&NewDrawingJumpToLatest(&chart.MainSeries).Drawing, rgb.None, true)
 		layer.SetEventDispatcher()
 		chart.layers[5] = layer
 	}
 
-	// Add event listener on resize event
-	webapi.GetWindow().AddEventResize(func(event *htmlevent.UIEvent, win *webapi.Window) {
-		// resizing the chart will resize and redraw every layers
-		chart.Resize()
-	})
-
-	// size it the first time to force a full redraw
-	//	chart.Resize()
-
-	return chart, nil
+	return chart
 }
 
 func getMainDrawArea(cliparea Rect) Rect {
@@ -242,37 +275,19 @@ func getMainDrawArea(cliparea Rect) Rect {
 	return area
 }
 
-// addNewLayer creates a new canvas, inside the masterE div and add it to the stack of layers within the pchart.
+// addNewLayer creates a new layer with its drawing surface on the host, and add it to the stack of layers within the pchart.
 // This new layer is moved and sized according to layoutArea parameter.
 // It's background color is setup if any.
 //
-// The created layer embed the WEBGL 2D drawing context,
-//
 // Return the created layer, or nil if error.
 func (pchart *StockChart) addNewLayer(layerid string, layout layoutT, bgcolor rgb.Color, xrange *timeline.TimeSlice) *Layer {
-	// create a canvas
-	domE := webapi.GetWindow().Document().CreateElement("canvas", &webapi.Union{Value: js.ValueOf("dom.Node")})
-	domE.SetId("canvas" + pchart.ID + strings.ToLower(strings.Trim(layerid, " ")))
-	newE := pchart.masterE.AppendChild(&domE.Node)
-	canvasE := canvas.HTMLCanvasElementFromWrapper(newE)
-	canvasE.AttributeStyleMap().Set("position", &typedom.Union{Value: js.ValueOf(`absolute`)})
-	canvasE.AttributeStyleMap().Set("border", &typedom.Union{Value: js.ValueOf(`none`)})
-	canvasE.AttributeStyleMap().Set("padding", &typedom.Union{Value: js.ValueOf(`0`)})
-	canvasE.AttributeStyleMap().Set("margin", &typedom.Union{Value: js.ValueOf(`0`)})
-
 	// create the layer
-	layer := NewLayer(strings.ToLower(strings.Trim(layerid, " ")), pchart, layout, xrange, *canvasE)
-
-	// set canvas background color or leave it transparent
-	if bgcolor.Alpha() != 0 {
-		layer.canvasE.HTMLElement.AttributeStyleMap().Set("background-color", &typedom.Union{Value: js.ValueOf(bgcolor.Hexa())})
-	}
+	layer := NewLayer(strings.ToLower(strings.Trim(layerid, " ")), pchart, layout, xrange)
+	layer.bgcolor = bgcolor
 
-	// to use a canvas we need to get a 2d or 3d contextto enable drawing, here we use a 2d context
-	// https://developer.mozilla.org/fr/docs/Web/API/HTMLCanvasElement/getContext
-	layer.Ctx2D = canvas.CanvasRenderingContext2DFromWrapper(canvasE.GetContext("2d", "alias:false"))
-	if layer.Ctx2D == nil {
-		log.Println("unable to get the canvas 2D context for drawing")
+	// create its surface and its 2D drawing context
+	if err := pchart.host.newSurface(layer); err != nil {
+		log.Println(err)
 		return nil
 	}
 	return layer
@@ -281,13 +296,13 @@ func (pchart *StockChart) addNewLayer(layerid string, layout layoutT, bgcolor rg
 // resize all layers according to the master element dimensions.
 func (pchart *StockChart) Resize() {
 
-	// get the masterE dimensions
-	cr := pchart.masterE.GetBoundingClientRect()
-	masterx := int(cr.X())
-	mastery := int(cr.Y())
-	masterw := int(cr.Width())
-	masterh := int(cr.Height())
-	if cr.Width() <= 0 || cr.Height() <= 0 {
+	// get the host dimensions
+	master := pchart.host.bounds()
+	masterx := master.O.X
+	mastery := master.O.Y
+	masterw := master.Width
+	masterh := master.Height
+	if masterw <= 0 || masterh <= 0 {
 		log.Printf("chart %q no sizable", pchart.ID)
 		return
 	}
@@ -298,6 +313,27 @@ func (pchart *StockChart) Resize() {
 		margin     int = 3
 	)
 
+	// the chart and its panes share the height above the navbar, panes are stacked below the chart.
+	// panes, with their margins, take maxPanesHeightRate of it at most, their rates are reduced proportionally beyond.
+	graphh := masterh - sizenav - margin
+	panesh := graphh
+	scale, rates := 1.0, 0.0
+	for _, pane := range pchart.panes {
+		rates += pane.HeightRate
+	}
+	if maxh := float64(max(0, int(float64(panesh)*maxPanesHeightRate)-len(pchart.panes)*margin)); rates*float64(panesh) > maxh {
+		scale = maxh / (rates * float64(panesh))
+	}
+	for _, pane := range pchart.panes {
+		pane.height = int(float64(panesh) * pane.HeightRate * scale)
+		graphh -= pane.height + margin
+	}
+	paney := mastery + graphh
+	for _, pane := range pchart.panes {
+		pane.top = paney + margin
+		paney = pane.top + pane.height
+	}
+
 	// relocate and resize every layers according to the master dimensions and their layout
 	for _, layer := range pchart.layers {
 		if layer == nil {
@@ -321,51 +357,72 @@ func (pchart *StockChart) Resize() {
 			x = masterx + masterw - sizeyscale
 			y = mastery
 			w = int(sizeyscale)
-			h = masterh - sizenav - margin
+			h = graphh
 
 		case lAREA_GRAPH:
 			x = masterx
 			y = mastery
 			w = masterw - sizeyscale
-			h = masterh - sizenav - margin
+			h = graphh
+
+		case lAREA_PANEYSCALE:
+			x = masterx + masterw - sizeyscale
+			y = layer.pane.top
+			w = int(sizeyscale)
+			h = layer.pane.height
+
+		case lAREA_PANE:
+			x = masterx
+			y = layer.pane.top
+			w = masterw - sizeyscale
+			h = layer.pane.height
 		}
 		newarea := Rect{O: Point{X: x, Y: y}, Width: w, Height: h}
 		layer.Resize(newarea)
 	}
 }
 
-// Redraw all layers (canvas) of the stockchart.
+// Redraw invalidates all layers (canvas) of the stockchart. They're redrawn at the next frame.
 //
 // Do not need to be called after a resize as layers automatically redrawn themselves
 func (pchart *StockChart) Redraw() {
-	if pchart.isDrawing {
-		log.Println("/!\\ Redraw request canceled. drawing still in progress")
-		return
-	}
-	pchart.isDrawing = true
 	for _, player := range pchart.layers {
 		if player != nil {
-			player.Redraw()
+			player.Invalidate()
 		}
 	}
-	pchart.isDrawing = false
 }
 
-// Redraw all layers (canvas) of the stockchart.
+// RedrawOnlyNeeds requests to redraw, at the next frame, the layers having at least one drawing needing it.
 //
-// Do not need to be called after a resize as layers automatically redrawn themselves
+// Needs are checked when the frame is painted, so bursts of changes lead to a single redraw reflecting the last state.
 func (pchart *StockChart) RedrawOnlyNeeds() {
-	if pchart.isDrawing {
-		log.Println("/!\\ RedrawOnlyNeeds request canceled. drawing still in progress")
+	pchart.checkNeeds = true
+	pchart.requestFrame()
+}
+
+// requestFrame asks the host to call paint at the next frame, unless it's already requested
+func (pchart *StockChart) requestFrame() {
+	if pchart.frameRequested {
 		return
 	}
-	pchart.isDrawing = true
+	pchart.frameRequested = true
+	pchart.host.requestFrame(pchart.paint)
+}
+
+// paint redraws the invalidated layers, and the layers needing it if requested.
+func (pchart *StockChart) paint() {
+	pchart.frameRequested = false
+	checkNeeds := pchart.checkNeeds
+	pchart.checkNeeds = false
 	for _, player := range pchart.layers {
-		if player != nil {
-			player.RedrawOnlyNeeds()
+		if player == nil {
+			continue
+		}
+		if player.dirty || (checkNeeds && player.needRedraw()) {
+			player.Redraw()
 		}
 	}
-	pchart.isDrawing = false
 }
 
 // DoChangeSelTimeSlice updates all drawings to reflect the new timsel.
@@ -384,6 +441,8 @@ func (pchart *StockChart) DoChangeSelTimeSlice(newts timeline.TimeSlice, fNotify
 	if pchart.NotifySelChangeTimeSlice != nil && fNotify {
 		pchart.NotifySelChangeTimeSlice(pchart.selectedTimeSlice)
 	}
+
+	pchart.checkHistory()
 }
 
 // pchart.selectedTimeSlice is udated, not newts
@@ -418,35 +477,19 @@ func (pchart *StockChart) DoChangeTimeZone(localZone bool) {
 	pchart.RedrawOnlyNeeds()
 }
 
-/*
- * Utilities
- */
-
-// getChartElement looks for chartid in the DOM and check it's type <stockchart>
-func getChartElement(chartid string) (*dom.Element, error) {
-	doc := webapi.GetWindow().Document()
-	if doc == nil {
-		return nil, fmt.Errorf("unable to access the html page content")
-	}
-
-	element := doc.GetElementById(chartid)
-	if element == nil {
-		return nil, fmt.Errorf("unable to find %q drawing area element in your html page", chartid)
+// isOnButton returns true if xy is over a button of the hover layer
+func (pchart *StockChart) isOnButton(xy Point) bool {
+	if pchart.latestButton.Width > 0 && xy.IsIn(pchart.latestButton) {
+		return true
 	}
-
-	strname := strings.ToLower(element.NodeName())
-	if strname != "stockchart" {
-		return nil, fmt.Errorf("drawing area element is not a <stockchart>, it should: %s\n", strname)
+	for _, button := range pchart.timeframeButtons {
+		if xy.IsIn(button) {
+			return true
+		}
 	}
-
-	return element, nil
+	return false
 }
 
-// get Mouse position taking into account DevicePixelRatio in case of browser zoom
-func getMouseXY(event *htmlevent.MouseEvent) (xy Point) {
-	dpr := webapi.GetWindow().DevicePixelRatio()
-	dx := float64(event.OffsetX()) * dpr
-	dy := float64(event.OffsetY()) * dpr
-	xy = Point{X: int(dx), Y: int(dy)}
-	return xy
-}
+/*
+ * Utilities
+ */
@@ -0,0 +1,252 @@
+package stockchart
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	timeline "github.com/larry868/timeline/v2"
+)
+
+// Session is a trading session within a day, Open and Close are durations since midnight in the time zone of the calendar.
+// Sessions can not cross midnight, split them in two sessions on consecutive days.
+type Session struct {
+	Open  time.Duration
+	Close time.Duration
+}
+
+// WeekdaySessions returns the same sessions from Monday to Friday, for NewTradingCalendar
+func WeekdaySessions(sessions ...Session) map[time.Weekday][]Session {
+	week := make(map[time.Weekday][]Session, 5)
+	for wd := time.Monday; wd <= time.Friday; wd++ {
+		week[wd] = sessions
+	}
+	return week
+}
+
+// TradingCalendar defines the trading sessions of a market, per weekday, and its holidays.
+//
+// With a trading calendar, the x axis of the chart is driven by the trading time,
+// so the non-trading periods like nights, weekends and holidays are skipped.
+type TradingCalendar struct {
+	location    *time.Location
+	sessions    [7][]Session     // sessions per weekday, indexed by time.Weekday
+	daily       [7]time.Duration // trading time per weekday, indexed by time.Weekday
+	weekPrefix  [8]time.Duration // trading time of the week before each day, from Monday
+	holidays    []int64          // sorted civil day numbers of the holidays
+	holidayTime []time.Duration  // trading time of the holidays before each holiday, one more than holidays
+}
+
+// NewTradingCalendar returns a trading calendar in location, UTC if nil, with its sessions per weekday and its holidays.
+// Only the date of holidays in location matters.
+//
+// Returns an error if a session is invalid, if sessions of a day overlap, or if there is no session at all.
+func NewTradingCalendar(location *time.Location, sessions map[time.Weekday][]Session, holidays ...time.Time) (*TradingCalendar, error) {
+	cal := new(TradingCalendar)
+	cal.location = location
+	if cal.location == nil {
+		cal.location = time.UTC
+	}
+
+	for wd, daysessions := range sessions {
+		if wd < time.Sunday || wd > time.Saturday {
+			return nil, fmt.Errorf("invalid weekday %d", wd)
+		}
+		day := append([]Session(nil), daysessions...)
+		sort.Slice(day, func(i, j int) bool { return day[i].Open < day[j].Open })
+		for i, s := range day {
+			if s.Open < 0 || s.Close > 24*time.Hour || s.Close <= s.Open {
+				return nil, fmt.Errorf("invalid session %s-%s on %s", s.Open, s.Close, wd)
+			}
+			if i > 0 && s.Open < day[i-1].Close {
+				return nil, fmt.Errorf("overlapping sessions on %s", wd)
+			}
+			cal.daily[wd] += s.Close - s.Open
+		}
+		cal.sessions[wd] = day
+	}
+	for i := 0; i < 7; i++ {
+		cal.weekPrefix[i+1] = cal.weekPrefix[i] + cal.daily[(i+1)%7]
+	}
+	if cal.weekPrefix[7] == 0 {
+		return nil, fmt.Errorf("no trading session")
+	}
+
+	days := make(map[int64]bool, len(holidays))
+	for _, h := range holidays {
+		days[civilDay(h.In(cal.location))] = true
+	}
+	for day := range days {
+		cal.holidays = append(cal.holidays, day)
+	}
+	sort.Slice(cal.holidays, func(i, j int) bool { return cal.holidays[i] < cal.holidays[j] })
+	cal.holidayTime = make([]time.Duration, len(cal.holidays)+1)
+	for i, day := range cal.holidays {
+		cal.holidayTime[i+1] = cal.holidayTime[i] + cal.daily[weekdayOf(day)]
+	}
+	return cal, nil
+}
+
+// civilDay returns the number of days since 1970-01-01 of the date of t, in the location of t
+func civilDay(t time.Time) int64 {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / 86400
+}
+
+// weekdayOf returns the weekday of a civil day number, 1970-01-01 is a Thursday
+func weekdayOf(day int64) time.Weekday {
+	return time.Weekday(((day+4)%7 + 7) % 7)
+}
+
+// isHoliday returns true if the civil day is a holiday, and the number of holidays before it
+func (cal *TradingCalendar) isHoliday(day int64) (bool, int) {
+	i := sort.Search(len(cal.holidays), func(i int) bool { return cal.holidays[i] >= day })
+	return i < len(cal.holidays) && cal.holidays[i] == day, i
+}
+
+// clock returns the trading time elapsed between Monday 1969-12-29 and t
+func (cal *TradingCalendar) clock(t time.Time) time.Duration {
+	lt := t.In(cal.location)
+	day := civilDay(lt)
+
+	// full weeks from a Monday, then the days of the week before t
+	rel := day + 3
+	week := rel / 7
+	if rel < 0 && rel%7 != 0 {
+		week--
+	}
+	clock := time.Duration(week)*cal.weekPrefix[7] + cal.weekPrefix[rel-week*7]
+
+	holiday, before := cal.isHoliday(day)
+	clock -= cal.holidayTime[before]
+	if holiday {
+		return clock
+	}
+
+	// the day of t
+	y, m, d := lt.Date()
+	for _, s := range cal.sessions[lt.Weekday()] {
+		open := time.Date(y, m, d, 0, 0, 0, int(s.Open), cal.location)
+		elapsed := lt.Sub(open)
+		if elapsed <= 0 {
+			break
+		}
+		clock += min(elapsed, s.Close-s.Open)
+	}
+	return clock
+}
+
+// IsTrading returns true if t is within a trading session
+func (cal *TradingCalendar) IsTrading(t time.Time) bool {
+	lt := t.In(cal.location)
+	if holiday, _ := cal.isHoliday(civilDay(lt)); holiday {
+		return false
+	}
+	y, m, d := lt.Date()
+	for _, s := range cal.sessions[lt.Weekday()] {
+		open := time.Date(y, m, d, 0, 0, 0, int(s.Open), cal.location)
+		close := time.Date(y, m, d, 0, 0, 0, int(s.Close), cal.location)
+		if !lt.Before(open) && lt.Before(close) {
+			return true
+		}
+	}
+	return false
+}
+
+// TradingTime returns the trading time between from and to, negative if to is before from
+func (cal *TradingCalendar) TradingTime(from time.Time, to time.Time) time.Duration {
+	return cal.clock(to) - cal.clock(from)
+}
+
+// AddTradingTime returns the earliest time when d of trading time has elapsed since t, d can be negative.
+// When the result falls between two sessions, this is the close of the first one.
+func (cal *TradingCalendar) AddTradingTime(t time.Time, d time.Duration) time.Time {
+	target := cal.clock(t) + d
+
+	// bound the result, then search it by dichotomy
+	lo, hi := t, t
+	for step := time.Hour; cal.clock(lo) >= target; step *= 2 {
+		lo = lo.Add(-step)
+	}
+	for step := time.Hour; cal.clock(hi) < target; step *= 2 {
+		hi = hi.Add(step)
+	}
+	for hi.Sub(lo) > 1 {
+		mid := lo.Add(hi.Sub(lo) / 2)
+		if cal.clock(mid) >= target {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return hi
+}
+
+// SetTradingCalendar changes the x axis of all layers, driven by the trading time of cal, or linear if cal is nil.
+// The chart is redrawn at the next frame.
+func (pchart *StockChart) SetTradingCalendar(cal *TradingCalendar) {
+	pchart.calendar = cal
+	pchart.Redraw()
+}
+
+// tradingCalendar returns the calendar driving the x axis of the layer, nil if the axis is linear.
+// The axis is linear if there's no trading time within the xAxisRange.
+func (layer *Layer) tradingCalendar() *TradingCalendar {
+	if layer.chart == nil || layer.chart.calendar == nil || !layer.hasValidXAxisRange() {
+		return nil
+	}
+	if layer.chart.calendar.TradingTime(layer.xAxisRange.From, layer.xAxisRange.To) <= 0 {
+		return nil
+	}
+	return layer.chart.calendar
+}
+
+// xSpan returns the distance between from and to along the x axis of the layer,
+// the trading time with a calendar, negative if to is before from.
+func (layer *Layer) xSpan(from time.Time, to time.Time) time.Duration {
+	if cal := layer.tradingCalendar(); cal != nil {
+		return cal.TradingTime(from, to)
+	}
+	return to.Sub(from)
+}
+
+// xProgress returns the position of at along the x axis of the layer, between 0 and 1 from the start of the xAxisRange
+func (layer *Layer) xProgress(at time.Time) float64 {
+	cal := layer.tradingCalendar()
+	if cal == nil {
+		return layer.xAxisRange.Progress(at)
+	}
+	rate := float64(cal.TradingTime(layer.xAxisRange.From, at)) / float64(cal.TradingTime(layer.xAxisRange.From, layer.xAxisRange.To))
+	return fmin(1, fmax(0, rate))
+}
+
+// xWhatTime returns the time at a position along the x axis of the layer, rate between 0 and 1 from the start of the xAxisRange.
+// The returned time is always within the xAxisRange.
+func (layer *Layer) xWhatTime(rate float64) time.Time {
+	cal := layer.tradingCalendar()
+	if cal == nil {
+		return layer.xAxisRange.WhatTime(rate)
+	}
+	rate = fmin(1, fmax(0, rate))
+	span := cal.TradingTime(layer.xAxisRange.From, layer.xAxisRange.To)
+	return layer.xAxisRange.Bound(cal.AddTradingTime(layer.xAxisRange.From, time.Duration(rate*float64(span))))
+}
+
+// xShiftIn shifts ts by the distance between from and to along the x axis of the layer, keeping ts within the xAxisRange.
+// With a calendar, the trading time of ts is kept.
+func (layer *Layer) xShiftIn(ts *timeline.TimeSlice, from time.Time, to time.Time) {
+	cal := layer.tradingCalendar()
+	if cal == nil {
+		ts.ShiftIn(to.Sub(from), *layer.xAxisRange)
+		return
+	}
+	width := cal.TradingTime(ts.From, ts.To)
+	d := cal.TradingTime(from, to)
+	newfrom, newto := cal.AddTradingTime(ts.From, d), cal.AddTradingTime(ts.To, d)
+	if newfrom.Before(layer.xAxisRange.From) {
+		newfrom, newto = layer.xAxisRange.From, cal.AddTradingTime(layer.xAxisRange.From, width)
+	} else if newto.After(layer.xAxisRange.To) {
+		newfrom, newto = cal.AddTradingTime(layer.xAxisRange.To, -width), layer.xAxisRange.To
+	}
+	ts.From, ts.To = layer.xAxisRange.Bound(newfrom), layer.xAxisRange.Bound(newto)
+}
@@ -0,0 +1,161 @@
+package stockchart
+
+import (
+	"testing"
+	"time"
+)
+
+// testCalendar returns a calendar with sessions from 9:00 to 17:00 UTC, from Monday to Friday
+func testCalendar(t *testing.T, holidays ...time.Time) *TradingCalendar {
+	t.Helper()
+	cal, err := NewTradingCalendar(nil, WeekdaySessions(Session{Open: 9 * time.Hour, Close: 17 * time.Hour}), holidays...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cal
+}
+
+// testSeriesSessions returns the hourly candles of testSeries within the sessions of testCalendar, over two weeks
+func testSeriesSessions() DataList {
+	full := testSeries(24 * 14)
+	series := DataList{Name: full.Name, Precision: full.Precision}
+	for _, item := range full.window(nil) {
+		if wd := item.From.Weekday(); wd == time.Saturday || wd == time.Sunday || item.From.Hour() < 9 || item.From.Hour() >= 17 {
+			continue
+		}
+		series.Append(item)
+	}
+	return series
+}
+
+func TestTradingCalendar(t *testing.T) {
+	est := time.FixedZone("EST", -5*3600)
+	date := func(day int, hour int, min int) time.Time {
+		return time.Date(2024, 1, day, hour, min, 0, 0, est)
+	}
+	// 2024-01-03 is a Wednesday
+	cal, err := NewTradingCalendar(est, WeekdaySessions(Session{Open: 9*time.Hour + 30*time.Minute, Close: 16 * time.Hour}), date(3, 12, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		from, to time.Time
+		want     time.Duration
+	}{
+		{date(5, 15, 0), date(8, 10, 30), 2 * time.Hour},                    // over the weekend
+		{date(2, 16, 0), date(4, 9, 30), 0},                                 // over the holiday
+		{date(8, 0, 0), date(15, 0, 0), 5 * (6*time.Hour + 30*time.Minute)}, // a full week
+		{date(1, 0, 0), date(8, 0, 0), 4 * (6*time.Hour + 30*time.Minute)},  // a week with the holiday
+		{date(8, 10, 30), date(5, 15, 0), -2 * time.Hour},                   // backward
+		{date(6, 10, 0), date(7, 23, 0), 0},                                 // within a weekend
+	} {
+		if get := cal.TradingTime(tc.from, tc.to); get != tc.want {
+			t.Errorf("TradingTime(%s, %s) fails: want %s, get %s", tc.from, tc.to, tc.want, get)
+		}
+	}
+
+	for _, tc := range []struct {
+		at   time.Time
+		want bool
+	}{{date(8, 10, 0), true}, {date(8, 9, 30), true}, {date(8, 16, 0), false}, {date(6, 12, 0), false}, {date(3, 12, 0), false}, {time.Date(2024, 1, 8, 10, 0, 0, 0, time.UTC), false}} {
+		if get := cal.IsTrading(tc.at); get != tc.want {
+			t.Errorf("IsTrading(%s) fails: want %v", tc.at, tc.want)
+		}
+	}
+
+	if get := cal.AddTradingTime(date(5, 15, 0), 2*time.Hour); !get.Equal(date(8, 10, 30)) {
+		t.Errorf("AddTradingTime fails: get %s", get)
+	}
+	if get := cal.AddTradingTime(date(8, 10, 30), -2*time.Hour); !get.Equal(date(5, 15, 0)) {
+		t.Errorf("AddTradingTime backward fails: get %s", get)
+	}
+	if get := cal.AddTradingTime(date(5, 15, 0), time.Hour); !get.Equal(date(5, 16, 0)) {
+		t.Errorf("AddTradingTime to the close fails: get %s", get)
+	}
+
+	for name, sessions := range map[string]map[time.Weekday][]Session{
+		"none":        {},
+		"inverted":    {time.Monday: {{Open: 10 * time.Hour, Close: 9 * time.Hour}}},
+		"after night": {time.Monday: {{Open: 10 * time.Hour, Close: 25 * time.Hour}}},
+		"overlapping": {time.Monday: {{Open: 9 * time.Hour, Close: 12 * time.Hour}, {Open: 11 * time.Hour, Close: 14 * time.Hour}}},
+	} {
+		if _, err := NewTradingCalendar(nil, sessions); err == nil {
+			t.Errorf("NewTradingCalendar %s fails: want an error", name)
+		}
+	}
+}
+
+func TestTradingCalendarDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	cal, err := NewTradingCalendar(ny, WeekdaySessions(Session{Open: 9*time.Hour + 30*time.Minute, Close: 16 * time.Hour}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// daylight saving time starts on Sunday 2024-03-10
+	from := time.Date(2024, 3, 8, 15, 0, 0, 0, ny)
+	to := time.Date(2024, 3, 11, 10, 30, 0, 0, ny)
+	if get := cal.TradingTime(from, to); get != 2*time.Hour {
+		t.Errorf("TradingTime fails: want 2h, get %s", get)
+	}
+	if get := cal.AddTradingTime(from, 2*time.Hour); !get.Equal(to) {
+		t.Errorf("AddTradingTime fails: want %s, get %s", to, get)
+	}
+}
+
+func TestTradingCalendarAxis(t *testing.T) {
+	series := testSeriesSessions()
+	chart, h := newTestChart(series)
+	chart.DoChangeSelTimeSlice(series.TimeSlice(), false)
+	chart.SetTradingCalendar(testCalendar(t))
+	h.flushFrames()
+
+	// the candles are contiguous and of the same width
+	candles := chart.mainCandles
+	var prev *DataStock
+	for _, item := range series.window(nil) {
+		w := candles.xTime(item.To) - candles.xTime(item.From)
+		if w < 8 || w > 10 {
+			t.Fatalf("xTime fails: candle %s width %v", item, w)
+		}
+		if prev != nil && candles.xTime(prev.To) != candles.xTime(item.From) {
+			t.Fatalf("xTime fails: %s and %s are not contiguous", prev, item)
+		}
+		prev = item
+	}
+
+	// back and forth between times and positions
+	at := time.Date(2024, 1, 8, 10, 0, 0, 0, time.UTC)
+	if get := candles.xWhatTime(candles.xProgress(at)); get.Sub(at).Abs() > time.Second {
+		t.Errorf("xWhatTime fails: want %s, get %s", at, get)
+	}
+
+	// shifting the selection keeps its trading time
+	nav := chart.layers[1]
+	sel := series.TimeSlice()
+	sel.To = sel.From.Add(3 * 24 * time.Hour)
+	width := chart.calendar.TradingTime(sel.From, sel.To)
+	nav.xShiftIn(&sel, at, at.Add(24*time.Hour))
+	if get := chart.calendar.TradingTime(sel.From, sel.To); get != width || !sel.From.After(series.Tail.From) {
+		t.Errorf("xShiftIn fails: get %s, trading time %s", sel, get)
+	}
+
+	// grid ticks do not overlap on weekends
+	xgrid := DrawingXGrid{Drawing: Drawing{Layer: chart.layers[4], drawArea: chart.layers[4].ClipArea}}
+	ticks := xgrid.ticks(chart.selectedTimeSlice.GetScanMask(8))
+	for i := 1; i < len(ticks); i++ {
+		if ticks[i].xpos == ticks[i-1].xpos {
+			t.Errorf("ticks fails: %s and %s at the same position", ticks[i-1].at, ticks[i].at)
+		}
+	}
+
+	// back to a linear axis
+	chart.SetTradingCalendar(nil)
+	h.flushFrames()
+	if candles.xTime(prev.To) == candles.xTime(series.Tail.From.Add(5*24*time.Hour)) {
+		t.Errorf("SetTradingCalendar nil fails")
+	}
+}
@@ -0,0 +1,61 @@
+package stockchart
+
+import (
+	"time"
+
+	timeline "github.com/larry868/timeline/v2"
+)
+
+// historyMargin is the distance to the tail, in width of the selected time slice, calling NotifyNeedHistory
+const historyMargin = 0.5
+
+// checkHistory calls NotifyNeedHistory when the start of the selected time slice approaches the tail of the MainSeries.
+// NotifyNeedHistory is called once per tail, so an application without older data simply does not prepend anything.
+func (pchart *StockChart) checkHistory() {
+	tail := pchart.MainSeries.Tail
+	if pchart.NotifyNeedHistory == nil || tail == nil || tail.From.Equal(pchart.historyRequested) {
+		return
+	}
+	sel := pchart.selectedTimeSlice
+	if sel.IsZero() {
+		return
+	}
+	sel.ForceDirection(timeline.Chronological)
+	margin := time.Duration(float64(sel.Duration().Duration) * historyMargin)
+	if sel.From.Sub(tail.From) <= margin {
+		pchart.historyRequested = tail.From
+		pchart.NotifyNeedHistory(tail.From)
+	}
+}
+
+// PrependData links olders data before the tail of the MainSeries, typically in response to NotifyNeedHistory.
+// olders must be chronological and end before the tail, returns an error otherwise and the chart is unchanged.
+// Like DataList.Prepend, the data points are moved from olders, which is emptied.
+//
+// The time range is extended to the past, while the selected time slice is unchanged to keep the view stable.
+// Only the layers showing olders are redrawn, at the next frame.
+//
+// The application can fetch older data asynchronously, but must call PrependData from the goroutine handling the chart.
+func (pchart *StockChart) PrependData(olders *DataList) error {
+	newest := olders.Head
+	if newest == nil {
+		return nil
+	}
+	if err := pchart.MainSeries.Prepend(olders); err != nil {
+		return err
+	}
+
+	tail := pchart.MainSeries.Tail
+	if pchart.timeRange.IsZero() {
+		pchart.SetTimeRange(pchart.MainSeries.TimeSlice(), pchart.extendRate)
+	} else if tail.From.Before(pchart.timeRange.From) {
+		pchart.timeRange.From = tail.From
+	}
+	pchart.invalidateLayer(1)
+	pchart.invalidateLayer(2)
+	pchart.invalidateData(newest)
+
+	// the new tail may be close to the selection too
+	pchart.checkHistory()
+	return nil
+}
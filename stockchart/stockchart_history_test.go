@@ -0,0 +1,111 @@
+package stockchart
+
+import (
+	"testing"
+	"time"
+
+	timeline "github.com/larry868/timeline/v2"
+)
+
+// splitSeries returns the first n data points of series, and the others, in two separate DataLists
+func splitSeries(series DataList, n int) (olders DataList, recents DataList) {
+	olders = DataList{Name: series.Name, Precision: series.Precision}
+	recents = olders
+	for i, item := range series.Window(series.TimeSlice()) {
+		if i < n {
+			olders.Append(item)
+		} else {
+			recents.Append(item)
+		}
+	}
+	return olders, recents
+}
+
+func TestDataListPrepend(t *testing.T) {
+	olders, series := splitSeries(testSeries(30), 10)
+	tail := series.Tail
+	if err := series.Prepend(&olders); err != nil {
+		t.Fatal(err)
+	}
+	if series.Size() != 30 || !series.index.sorted || series.Tail.Next.From.After(tail.From) || tail.Prev == nil || tail.Prev.Next != tail {
+		t.Fatalf("Prepend fails: get %s", series)
+	}
+	if !olders.IsEmpty() {
+		t.Errorf("Prepend fails: olders not emptied")
+	}
+	full := testSeries(30)
+	if get, want := series.DataRange(nil, 0), full.DataRange(nil, 0); get.Low() != want.Low() || get.High() != want.High() {
+		t.Errorf("Prepend fails: want the datarange %s, get %s", want, get)
+	}
+	if get := series.GetDataAt(series.Tail.Middle()); get != series.Tail {
+		t.Errorf("Prepend fails: GetDataAt the tail %s", get)
+	}
+
+	// overlapping
+	overlapping, _ := splitSeries(testSeries(30), 12)
+	if err := series.Prepend(&overlapping); err == nil || series.Size() != 30 {
+		t.Errorf("Prepend overlapping fails: want an error")
+	}
+}
+
+func TestPrependData(t *testing.T) {
+	olders, recents := splitSeries(testSeries(96), 48)
+	chart, h := newTestChart(recents)
+
+	var requests []time.Time
+	chart.NotifyNeedHistory = func(before time.Time) {
+		requests = append(requests, before)
+	}
+
+	// far from the tail
+	sel := timeline.MakeTimeSlice(recents.Head.To.Add(-12*time.Hour), 12*time.Hour)
+	chart.DoChangeSelTimeSlice(sel, true)
+	if len(requests) != 0 {
+		t.Fatalf("NotifyNeedHistory fails: unexpected request %v", requests)
+	}
+
+	// approaching the tail, a single request
+	tail := chart.MainSeries.Tail
+	sel = timeline.MakeTimeSlice(tail.From.Add(4*time.Hour), 12*time.Hour)
+	chart.DoChangeSelTimeSlice(sel, true)
+	sel.Shift(-time.Hour)
+	chart.DoChangeSelTimeSlice(sel, true)
+	sel = chart.selectedTimeSlice
+	if len(requests) != 1 || !requests[0].Equal(tail.From) {
+		t.Fatalf("NotifyNeedHistory fails: want a single request before %s, get %v", tail.From, requests)
+	}
+
+	// prepend, the view is stable
+	resetRecorders(chart, h)
+	timerange := chart.timeRange
+	if err := chart.PrependData(&olders); err != nil {
+		t.Fatal(err)
+	}
+	if olders.Head != nil || olders.Tail != nil {
+		t.Errorf("PrependData fails: olders is not emptied")
+	}
+	if chart.MainSeries.Size() != 96 || !chart.timeRange.From.Equal(chart.MainSeries.Tail.From) || !chart.timeRange.To.Equal(timerange.To) {
+		t.Errorf("PrependData fails: get %s, time range %s", chart.MainSeries, chart.timeRange)
+	}
+	if chart.selectedTimeSlice.Compare(sel) != timeline.EQUAL {
+		t.Errorf("PrependData fails: the selection has changed %s", chart.selectedTimeSlice)
+	}
+	if len(requests) != 1 {
+		t.Errorf("NotifyNeedHistory fails: unexpected request %v", requests)
+	}
+	h.flushFrames()
+	for _, layerid := range []int{1, 2} {
+		if get := chart.recorder(layerid).Count("ClearRect"); get != 1 {
+			t.Errorf("PrependData fails: want layer %q redrawn once, get %d", chart.layers[layerid].Name, get)
+		}
+	}
+	if get := len(chart.recorder(4).Calls); get != 0 {
+		t.Errorf("PrependData fails: the chart layer has been redrawn")
+	}
+
+	// approaching the new tail
+	chart.DoChangeSelTimeSlice(timeline.MakeTimeSlice(chart.MainSeries.Tail.From, 12*time.Hour), true)
+	if len(requests) != 2 || !requests[1].Equal(chart.MainSeries.Tail.From) {
+		t.Errorf("NotifyNeedHistory fails: get %v", requests)
+	}
+}
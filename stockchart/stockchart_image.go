@@ -0,0 +1,113 @@
+package stockchart
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"os"
+
+	"github.com/larry868/rgb"
+)
+
+// imageHost renders the chart offscreen, without any browser.
+// Each layer draws into its own ImageRenderer, then layers are composed into a single image.
+type imageHost struct {
+	width  int     // width of the chart, in css pixels
+	height int     // height of the chart, in css pixels
+	dpr    float64 // the device pixel ratio
+}
+
+func (h *imageHost) bounds() Rect {
+	return Rect{Width: h.width, Height: h.height}
+}
+
+func (h *imageHost) devicePixelRatio() float64 {
+	return h.dpr
+}
+
+func (h *imageHost) newSurface(layer *Layer) error {
+	layer.Ctx2D = NewImageRenderer(0, 0)
+	return nil
+}
+
+func (h *imageHost) resizeSurface(layer *Layer, area Rect, bufwidth int, bufheight int) {
+	layer.Ctx2D.(*ImageRenderer).Resize(bufwidth, bufheight)
+}
+
+func (h *imageHost) bindEvents(layer *Layer, handled evtHandler) {
+}
+
+func (h *imageHost) setCursor(layer *Layer, cursor string) {
+}
+
+// requestFrame calls callback immediately, there's no repaint cycle offscreen
+func (h *imageHost) requestFrame(callback func()) {
+	callback()
+}
+
+// compose stacks the images of all layers, with their background color, into a single image
+func (h *imageHost) compose(layers []*Layer) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, int(math.Abs(float64(h.width)*h.dpr)), int(math.Abs(float64(h.height)*h.dpr))))
+	for _, layer := range layers {
+		if layer == nil {
+			continue
+		}
+		limg := layer.Ctx2D.(*ImageRenderer).Image()
+		o := image.Pt(int(float64(layer.area.O.X)*h.dpr), int(float64(layer.area.O.Y)*h.dpr))
+		r := limg.Bounds().Add(o)
+		if layer.bgcolor.Alpha() != 0 {
+			draw.Draw(img, r, image.NewUniform(toNRGBA(layer.bgcolor)), image.Point{}, draw.Over)
+		}
+		draw.Draw(img, r, limg, image.Point{}, draw.Over)
+	}
+	return img
+}
+
+// NewImageStockChart initialize a stockchart rendered offscreen into an image, without any browser.
+// The chart gets the same layers and drawings than a chart built with NewStockChart.
+//
+// width and height are the size of the chart in css pixels, dpr is the device pixel ratio.
+// The rendered image is width*dpr by height*dpr pixels. dpr is 1 if <= 0.
+func NewImageStockChart(bgcolor rgb.Color, series DataList, extendrate float64, width int, height int, dpr float64) *StockChart {
+	if dpr <= 0 {
+		dpr = 1
+	}
+	return newStockChart("image", &imageHost{width: width, height: height, dpr: dpr}, bgcolor, series, extendrate)
+}
+
+// RenderImage redraws all layers of a chart built with NewImageStockChart and returns the composed image.
+//
+// Returns an error if the chart is not rendered offscreen.
+func (pchart *StockChart) RenderImage() (*image.RGBA, error) {
+	h, ok := pchart.host.(*imageHost)
+	if !ok {
+		return nil, fmt.Errorf("chart %q is not rendered offscreen", pchart.ID)
+	}
+	pchart.Resize()
+	return h.compose(pchart.layers), nil
+}
+
+// WritePNG renders the chart built with NewImageStockChart and writes it in PNG format.
+func (pchart *StockChart) WritePNG(w io.Writer) error {
+	img, err := pchart.RenderImage()
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+// SavePNG renders the chart built with NewImageStockChart and saves it into a PNG file.
+func (pchart *StockChart) SavePNG(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := pchart.WritePNG(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,140 @@
+package stockchart
+
+import (
+	"fmt"
+
+	timeline "github.com/larry868/timeline/v2"
+)
+
+// UpdateHead updates in place the head of the MainSeries with a new tick:
+// price becomes the Close and extends the High or the Low, volume is the cumulated volume of the head.
+//
+// Only the layers showing the head are redrawn, at the next frame.
+// Does nothing if the MainSeries is empty.
+func (pchart *StockChart) UpdateHead(price float64, volume float64) {
+	head := pchart.MainSeries.Head
+	if head == nil {
+		return
+	}
+	head.Close = price
+	if price > head.High {
+		head.High = price
+	}
+	if head.Low == 0 || price < head.Low {
+		head.Low = price
+	}
+	head.Volume = volume
+	pchart.MainSeries.Update(head)
+
+	pchart.invalidateData(head)
+}
+
+// AppendData appends newdata at the head of the MainSeries, newdata must start at or after the end of the current head,
+// returns an error otherwise and the chart is unchanged.
+// If newdata ends after the time range, the time range is extended with the extend rate of the chart.
+//
+// The MainSeries of the chart is a copy of the series given to the chart, so live data must be appended with AppendData,
+// not to the original series.
+//
+// Only the layers showing newdata are redrawn, at the next frame.
+func (pchart *StockChart) AppendData(newdata *DataStock) error {
+	if head := pchart.MainSeries.Head; head != nil && newdata.From.Before(head.To) {
+		return fmt.Errorf("unable to append to %q: starts at %s before the end of the head %s", pchart.MainSeries.Name, newdata.From, head.To)
+	}
+	pchart.MainSeries.Append(newdata)
+	pchart.dataAdded(newdata)
+	return nil
+}
+
+// dataAdded extends the time range if newdata, just linked to the MainSeries, is out of it,
+// and invalidates the layers showing newdata.
+//
+// If newdata is the new head and the previous head was in view, the selected time slice slides to show it, preserving its width.
+func (pchart *StockChart) dataAdded(newdata *DataStock) {
+	sel := pchart.selectedTimeSlice
+	follow := pchart.autoFollow && newdata == pchart.MainSeries.Head && !sel.IsZero() && (newdata.Prev == nil || !sel.To.Before(newdata.Prev.To))
+
+	if pchart.timeRange.IsZero() || newdata.To.After(pchart.timeRange.To) || newdata.From.Before(pchart.timeRange.From) {
+		pchart.SetTimeRange(pchart.MainSeries.TimeSlice(), pchart.extendRate)
+		pchart.invalidateLayer(1)
+		pchart.invalidateLayer(2)
+	}
+
+	if follow && newdata.To.After(sel.To) {
+		shift := newdata.To.Sub(sel.To)
+		sel.From, sel.To = sel.From.Add(shift), newdata.To
+		pchart.DoChangeSelTimeSlice(sel, true)
+		pchart.invalidateLayer(2)
+	}
+	pchart.invalidateData(newdata)
+}
+
+// SetAutoFollow turns on or off the auto-follow mode, on by default.
+//
+// In auto-follow mode, when a new data is appended to the MainSeries while the head is in view,
+// the selected time slice slides with the head, preserving its width.
+// If the user has moved the selection away from the head, the selection stays and a "jump to latest" button appears on the chart.
+func (pchart *StockChart) SetAutoFollow(on bool) {
+	pchart.autoFollow = on
+}
+
+// IsHeadInView returns true if the end of the selected time slice shows the head of the MainSeries, or if the MainSeries is empty
+func (pchart *StockChart) IsHeadInView() bool {
+	head := pchart.MainSeries.Head
+	return head == nil || pchart.selectedTimeSlice.IsZero() || !pchart.selectedTimeSlice.To.Before(head.To)
+}
+
+// DoJumpToLatest moves the selected time slice to end with the head of the MainSeries, preserving its width.
+// Then the chart follows the head again.
+func (pchart *StockChart) DoJumpToLatest(fNotify bool) {
+	if pchart.IsHeadInView() {
+		return
+	}
+	pchart.DoChangeSelTimeSlice(pchart.latestTimeSlice(), fNotify)
+}
+
+// latestTimeSlice returns the selected time slice moved to end with the head of the MainSeries
+func (pchart *StockChart) latestTimeSlice() timeline.TimeSlice {
+	sel := pchart.selectedTimeSlice
+	sel.ForceDirection(timeline.Chronological)
+	shift := pchart.MainSeries.Head.To.Sub(sel.To)
+	sel.From, sel.To = sel.From.Add(shift), sel.To.Add(shift)
+	return sel
+}
+
+// invalidateData notifies the drawings of the change of data, and invalidates the layers showing data.
+// Layers depending on the data range, like the yscale, are checked at the next frame.
+func (pchart *StockChart) invalidateData(data *DataStock) {
+	// drawings caching values computed from the data
+	for _, layer := range pchart.layers {
+		if layer == nil {
+			continue
+		}
+		for _, drawing := range layer.drawings {
+			if drawing.OnChangeData != nil {
+				drawing.OnChangeData(data)
+			}
+		}
+	}
+
+	// the navbar shows the whole time range
+	pchart.invalidateLayer(1)
+
+	// the chart shows the selected time slice only
+	sel := pchart.selectedTimeSlice
+	if (sel.WhereIs(data.From)|sel.WhereIs(data.To))&timeline.TS_IN > 0 {
+		pchart.invalidateLayer(4)
+		for _, pane := range pchart.panes {
+			pane.graph.Invalidate()
+		}
+	}
+
+	pchart.RedrawOnlyNeeds()
+}
+
+// invalidateLayer invalidates the layer layerid, if any
+func (pchart *StockChart) invalidateLayer(layerid int) {
+	if layer := pchart.layers[layerid]; layer != nil {
+		layer.Invalidate()
+	}
+}
@@ -0,0 +1,210 @@
+package stockchart
+
+import (
+	"testing"
+	"time"
+
+	timeline "github.com/larry868/timeline/v2"
+)
+
+// resetRecorders flushes the pending frames and forgets all recorded calls
+func resetRecorders(chart *StockChart, h *fakeHost) {
+	h.flushFrames()
+	for i := range chart.layers {
+		chart.recorder(i).Reset()
+	}
+}
+
+func TestUpdateHead(t *testing.T) {
+	chart, h := newTestChart(testSeries(48))
+	resetRecorders(chart, h)
+
+	head := chart.MainSeries.Head
+	high := head.High
+	chart.UpdateHead(high+50, 1000)
+	if head.Close != high+50 || head.High != high+50 || head.Volume != 1000 {
+		t.Fatalf("UpdateHead fails: get %s", head)
+	}
+	if get := chart.MainSeries.DataRange(nil, 0).High(); get != high+50 {
+		t.Errorf("UpdateHead fails: want the new high %v in the datarange, get %v", high+50, get)
+	}
+
+	// a lower tick keeps the high
+	chart.UpdateHead(head.Low-1, 1100)
+	if head.High != high+50 || head.Low != head.Close {
+		t.Errorf("UpdateHead fails: get %s", head)
+	}
+
+	h.flushFrames()
+	for _, layerid := range []int{1, 3, 4} {
+		if get := chart.recorder(layerid).Count("ClearRect"); get != 1 {
+			t.Errorf("UpdateHead fails: want layer %q redrawn once, get %d", chart.layers[layerid].Name, get)
+		}
+	}
+	for _, layerid := range []int{0, 2, 5} {
+		if get := len(chart.recorder(layerid).Calls); get != 0 {
+			t.Errorf("UpdateHead fails: layer %q has been redrawn", chart.layers[layerid].Name)
+		}
+	}
+}
+
+func TestAppendData(t *testing.T) {
+	chart, h := newTestChart(testSeries(48))
+
+	// zoom on the beginning of the series
+	sel := timeline.MakeTimeSlice(chart.MainSeries.Tail.From, 12*time.Hour)
+	chart.DoChangeSelTimeSlice(sel, false)
+	resetRecorders(chart, h)
+	timerange := chart.timeRange
+
+	newdata := new(DataStock)
+	newdata.TimeSlice = timeline.MakeTimeSlice(chart.MainSeries.Head.To, time.Hour)
+	newdata.Open, newdata.Close, newdata.Low, newdata.High = 150, 151, 149, 152
+	chart.AppendData(newdata)
+
+	if chart.MainSeries.Head != newdata || chart.MainSeries.Size() != 49 {
+		t.Fatalf("AppendData fails: get %s", chart.MainSeries)
+	}
+	if !chart.timeRange.To.Equal(newdata.To) || !chart.timeRange.From.Equal(timerange.From) {
+		t.Errorf("AppendData fails: want the time range extended to %s, get %s", newdata.To, chart.timeRange)
+	}
+	if chart.selectedTimeSlice.Compare(sel) != timeline.EQUAL {
+		t.Errorf("AppendData fails: the selection has changed %s", chart.selectedTimeSlice)
+	}
+
+	// the new candle is out of the selection, the chart layer is unchanged
+	h.flushFrames()
+	for _, layerid := range []int{1, 2} {
+		if get := chart.recorder(layerid).Count("ClearRect"); get != 1 {
+			t.Errorf("AppendData fails: want layer %q redrawn once, get %d", chart.layers[layerid].Name, get)
+		}
+	}
+	if get := len(chart.recorder(4).Calls); get != 0 {
+		t.Errorf("AppendData fails: the chart layer has been redrawn")
+	}
+
+	// a stale or duplicate data is rejected
+	for _, from := range []time.Time{newdata.From, newdata.From.Add(-time.Hour), newdata.To.Add(-time.Minute)} {
+		stale := &DataStock{TimeSlice: timeline.MakeTimeSlice(from, time.Hour), Open: 1, High: 1, Low: 1, Close: 1}
+		if err := chart.AppendData(stale); err == nil || chart.MainSeries.Head != newdata || chart.MainSeries.Size() != 49 {
+			t.Errorf("AppendData at %s fails: want an error, get %s", from, chart.MainSeries)
+		}
+	}
+}
+
+func TestAppendDataExtendRate(t *testing.T) {
+	h := newFakeHost(800, 500)
+	chart := newStockChart("test", h, 0, testSeries(10), 0.5)
+	chart.Resize()
+
+	// within the extension, the time range does not change
+	timerange := chart.timeRange
+	newdata := new(DataStock)
+	newdata.TimeSlice = timeline.MakeTimeSlice(chart.MainSeries.Head.To, time.Hour)
+	chart.AppendData(newdata)
+	if chart.timeRange.Compare(timerange) != timeline.EQUAL {
+		t.Errorf("AppendData fails: want the time range unchanged %s, get %s", timerange, chart.timeRange)
+	}
+
+	// beyond it, the time range is extended again
+	for i := 0; i < 5; i++ {
+		newdata := new(DataStock)
+		newdata.TimeSlice = timeline.MakeTimeSlice(chart.MainSeries.Head.To, time.Hour)
+		chart.AppendData(newdata)
+	}
+	want := chart.MainSeries.Tail.From.Add(16 * time.Hour * 3 / 2)
+	if !chart.timeRange.To.Equal(want) {
+		t.Errorf("AppendData fails: want the time range extended to %s, get %s", want, chart.timeRange)
+	}
+}
+
+// newHourData returns a new data point of an hour, following the head of the series
+func newHourData(series DataList) *DataStock {
+	newdata := new(DataStock)
+	newdata.TimeSlice = timeline.MakeTimeSlice(series.Head.To, time.Hour)
+	newdata.Open, newdata.Close, newdata.Low, newdata.High = 100, 101, 99, 102
+	return newdata
+}
+
+func TestAutoFollow(t *testing.T) {
+	chart, h := newTestChart(testSeries(48))
+	var notified int
+	chart.NotifySelChangeTimeSlice = func(ts timeline.TimeSlice) { notified++ }
+
+	// the last 12 hours are in view
+	sel := timeline.MakeTimeSlice(chart.MainSeries.Head.To.Add(-12*time.Hour), 12*time.Hour)
+	chart.DoChangeSelTimeSlice(sel, false)
+	if !chart.IsHeadInView() {
+		t.Fatalf("IsHeadInView fails")
+	}
+
+	newdata := newHourData(chart.MainSeries)
+	chart.AppendData(newdata)
+	if !chart.selectedTimeSlice.To.Equal(newdata.To) || chart.selectedTimeSlice.Duration().Duration != 12*time.Hour || notified != 1 {
+		t.Errorf("auto-follow fails: want the selection sliding to %s, get %s", newdata.To, chart.selectedTimeSlice)
+	}
+	h.flushFrames()
+	if get := chart.recorder(5).Filter("FillText"); len(get) != 0 {
+		t.Errorf("auto-follow fails: unexpected jump button %v", get)
+	}
+
+	// off
+	chart.SetAutoFollow(false)
+	sel = chart.selectedTimeSlice
+	chart.AppendData(newHourData(chart.MainSeries))
+	if chart.selectedTimeSlice.Compare(sel) != timeline.EQUAL || chart.IsHeadInView() {
+		t.Errorf("auto-follow off fails: the selection has changed %s", chart.selectedTimeSlice)
+	}
+	chart.SetAutoFollow(true)
+	chart.DoJumpToLatest(false)
+	if !chart.IsHeadInView() || chart.selectedTimeSlice.Duration().Duration != 12*time.Hour {
+		t.Errorf("DoJumpToLatest fails: get %s", chart.selectedTimeSlice)
+	}
+}
+
+func TestJumpToLatest(t *testing.T) {
+	chart, h := newTestChart(testSeries(48))
+
+	resetRecorders(chart, h)
+
+	// the user moves away from the head, the button appears
+	sel := timeline.MakeTimeSlice(chart.MainSeries.Tail.From, 12*time.Hour)
+	chart.DoChangeSelTimeSlice(sel, true)
+	h.flushFrames()
+	hover := chart.layers[5]
+	if chart.latestButton.Width == 0 || chart.recorder(5).Count("FillText") != 1 {
+		t.Fatalf("jump button fails: not drawn %v", chart.recorder(5).Calls)
+	}
+
+	// new data does not move the selection, the button stays
+	resetRecorders(chart, h)
+	chart.AppendData(newHourData(chart.MainSeries))
+	h.flushFrames()
+	if chart.selectedTimeSlice.Compare(sel) != timeline.EQUAL {
+		t.Errorf("auto-follow fails: the selection has moved %s", chart.selectedTimeSlice)
+	}
+	if len(chart.recorder(5).Calls) != 0 {
+		t.Errorf("jump button fails: unexpected redraw of the hover layer")
+	}
+	button := chart.latestButton
+
+	// clicking it moves the selection to the head, without selecting a candle
+	hover.dispatchMouseEvent(evt_Click, button.Middle(), &MouseEvent{})
+	h.flushFrames()
+	if !chart.IsHeadInView() || !chart.selectedTimeSlice.To.Equal(chart.MainSeries.Head.To) || chart.selectedTimeSlice.Duration().Duration != 12*time.Hour {
+		t.Errorf("jump button fails: get %s", chart.selectedTimeSlice)
+	}
+	if chart.selectedData != nil {
+		t.Errorf("jump button fails: unexpected selected data %s", chart.selectedData)
+	}
+	if chart.latestButton.Width != 0 {
+		t.Errorf("jump button fails: still visible")
+	}
+
+	// and the chart follows again
+	newdata := newHourData(chart.MainSeries)
+	chart.AppendData(newdata)
+	if !chart.selectedTimeSlice.To.Equal(newdata.To) {
+		t.Errorf("auto-follow fails after jump: get %s", chart.selectedTimeSlice)
+	}
+}
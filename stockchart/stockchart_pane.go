@@ -0,0 +1,127 @@
+package stockchart
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/larry868/datarange"
+	"github.com/larry868/rgb"
+)
+
+// Pane is an area stacked below the chart, above the navbar, sharing the selected time slice of the chart.
+// A pane has its own drawings, its own grid and its own y scale, autoscaled to the extremes of its drawings.
+//
+// Panes are stacked from top to bottom in the order they have been added.
+type Pane struct {
+	Name       string  // the name of the pane, shown in the unit of its y scale
+	HeightRate float64 // the height of the pane, as a rate of the height shared by the chart and its panes, reduced if all panes exceed maxPanesHeightRate
+
+	graph      *Layer              // the layer of the drawings of the pane
+	yscale     *Layer              // the layer of the y scale of the pane
+	yAxisRange datarange.DataRange // the yAxisRange calculated by the YGrid of the pane, used by the drawings of the pane
+
+	top    int // the position of the pane within the host, in css pixels, calculated by Resize
+	height int // the height of the pane, in css pixels, calculated by Resize
+}
+
+// the default height of a pane, as a rate of the height shared by the chart and its panes
+const defaultPaneHeightRate = 0.2
+
+// the maximum height of all panes, with their margins, as a rate of the height shared by the chart and its panes
+const maxPanesHeightRate = 0.6
+
+func getPaneDrawArea(cliparea Rect) Rect {
+	return cliparea.Shrink(0, 5)
+}
+
+// AddPane adds a new pane below the chart and the other panes, with its grid and its y scale, but without any drawings.
+// heightRate is the height of the pane, as a rate of the height shared by the chart and its panes, 0.2 if <= 0.
+//
+// The chart is resized and redrawn. Returns nil if the layers of the pane can't be created.
+func (pchart *StockChart) AddPane(name string, heightRate float64) *Pane {
+	if heightRate <= 0 {
+		heightRate = defaultPaneHeightRate
+	}
+	pane := &Pane{Name: name, HeightRate: heightRate}
+	id := len(pchart.layers)
+
+	// the pane layer, with its grid
+	pane.graph = pchart.addNewLayer(fmt.Sprintf("%d-%s", id, name), lAREA_PANE, rgb.White, &pchart.selectedTimeSlice)
+	if pane.graph == nil {
+		return nil
+	}
+	pane.graph.pane = pane
+	dr := pane.graph.AddDrawing(&NewDrawingYGrid(&pchart.MainSeries, false).Drawing, rgb.None, true)
+	dr.DrawArea = getPaneDrawArea
+	xgrid := NewDrawingXGrid(&pchart.MainSeries, false, true)
+	xgrid.fLinesOnly = true
+	pane.graph.AddDrawing(&xgrid.Drawing, rgb.None, true)
+
+	// the yscale layer of the pane
+	pane.yscale = pchart.addNewLayer(fmt.Sprintf("%d-%s-yscale", id+1, name), lAREA_PANEYSCALE, rgb.White, &pchart.selectedTimeSlice)
+	if pane.yscale == nil {
+		return nil
+	}
+	pane.yscale.pane = pane
+	dr = pane.yscale.AddDrawing(&NewDrawingYGrid(&pchart.MainSeries, true).Drawing, rgb.White, true)
+	dr.DrawArea = getPaneDrawArea
+
+	pchart.layers = append(pchart.layers, pane.graph, pane.yscale)
+	pchart.panes = append(pchart.panes, pane)
+	pchart.Resize()
+	return pane
+}
+
+// Panes returns the panes stacked below the chart, from top to bottom
+func (pchart *StockChart) Panes() []*Pane {
+	return pchart.panes
+}
+
+// AddVolumePane moves the volume bars of the MainSeries from the bottom of the chart to a new pane, with a y scale of the volumes.
+// heightRate is the height of the pane, as a rate of the height shared by the chart and its panes, 0.2 if <= 0.
+//
+// Returns the volume pane, the existing one if the volume bars have already been moved.
+func (pchart *StockChart) AddVolumePane(heightRate float64) *Pane {
+	if pchart.mainBars.Layer != nil && pchart.mainBars.pane != nil {
+		return pchart.mainBars.pane
+	}
+	pane := pchart.AddPane("volume", heightRate)
+	if pane == nil {
+		return nil
+	}
+	if chart := pchart.mainBars.Layer; chart != nil {
+		chart.removeDrawing(&pchart.mainBars.Drawing)
+		chart.Invalidate()
+	}
+	pane.AddDrawing(&pchart.mainBars.Drawing)
+	return pane
+}
+
+// AddDrawing adds dr on top of the drawings of the pane, within the y scale of the pane.
+// The drawing should provide YExtremes to be included in the y scale.
+func (pane *Pane) AddDrawing(dr *Drawing) {
+	pane.graph.AddDrawing(dr, rgb.None, true)
+	dr.DrawArea = getPaneDrawArea
+	dr.drawArea = dr.DrawArea(pane.graph.ClipArea)
+	pane.graph.Invalidate()
+	pane.yscale.Invalidate()
+}
+
+// yRange returns the data range of the extremes of the drawings of the pane within the selected time slice.
+// Returns a zero range if no drawing of the pane has extremes.
+func (pane *Pane) yRange() datarange.DataRange {
+	sel := pane.graph.chart.selectedTimeSlice
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, dr := range pane.graph.drawings {
+		if dr.YExtremes == nil || !dr.hasNonEmptySeries() {
+			continue
+		}
+		if l, h := dr.YExtremes(sel); !math.IsNaN(l) && !math.IsNaN(h) {
+			low, high = math.Min(low, l), math.Max(high, h)
+		}
+	}
+	if low > high {
+		return datarange.DataRange{}
+	}
+	return datarange.Make(low, high, -4, pane.Name)
+}
@@ -0,0 +1,114 @@
+package stockchart
+
+import (
+	"testing"
+
+	"github.com/larry868/rgb"
+)
+
+func TestPaneLayout(t *testing.T) {
+	chart, h := newTestChart(testSeries(100))
+	h.flushFrames()
+	graph := chart.layers[4].area
+
+	volume := chart.AddVolumePane(0.2)
+	other := chart.AddPane("other", 0)
+	h.flushFrames()
+	if len(chart.Panes()) != 2 || chart.layers[6].pane != volume || chart.layers[9].pane != other {
+		t.Fatalf("AddPane fails: %d panes, %d layers", len(chart.Panes()), len(chart.layers))
+	}
+	if other.HeightRate != defaultPaneHeightRate {
+		t.Errorf("AddPane fails: want the default height rate, get %v", other.HeightRate)
+	}
+
+	// the chart, the volume pane and the other pane share the height above the navbar, 427 pixels
+	shrunk := chart.layers[4].area
+	if shrunk.O.Y != graph.O.Y || shrunk.Height != 427-2*(85+3) {
+		t.Errorf("Resize fails: the chart area is %v", shrunk)
+	}
+	if chart.layers[3].area.Height != shrunk.Height {
+		t.Errorf("Resize fails: the yscale area is %v", chart.layers[3].area)
+	}
+	for i, pane := range []*Pane{volume, other} {
+		want := Rect{O: Point{X: 0, Y: shrunk.Height + 3 + i*(85+3)}, Width: 720, Height: 85}
+		if pane.graph.area != want {
+			t.Errorf("Resize fails: pane %q at %v, want %v", pane.Name, pane.graph.area, want)
+		}
+		if yscale := pane.yscale.area; yscale.O.X != 720 || yscale.O.Y != want.O.Y || yscale.Height != want.Height {
+			t.Errorf("Resize fails: the yscale of pane %q at %v", pane.Name, yscale)
+		}
+	}
+
+	// the volume bars moved from the chart to the pane
+	for _, dr := range chart.layers[4].drawings {
+		if dr == &chart.mainBars.Drawing {
+			t.Errorf("AddVolumePane fails: the bars are still in the chart")
+		}
+	}
+	if chart.AddVolumePane(0.5) != volume || len(chart.Panes()) != 2 {
+		t.Errorf("AddVolumePane twice fails")
+	}
+}
+
+func TestPaneOverflow(t *testing.T) {
+	chart, h := newTestChart(testSeries(100))
+	h.height = 400
+	for i := 0; i < 6; i++ {
+		chart.AddRSI(14, rgb.Blue)
+	}
+	h.flushFrames()
+
+	// 6 panes at 0.2 exceed the height shared with the chart, 327 pixels, they're reduced to 60% of it
+	graph := chart.layers[4].area
+	if float64(graph.Height) < 327*(1-maxPanesHeightRate) || chart.layers[3].area.Height != graph.Height {
+		t.Errorf("Resize overflow fails: the chart area is %v", graph)
+	}
+	bottom := graph.O.Y + graph.Height
+	for _, pane := range chart.Panes() {
+		area := pane.graph.area
+		if area.Height <= 0 || area.Height != chart.Panes()[0].graph.area.Height || area.O.Y != bottom+3 {
+			t.Errorf("Resize overflow fails: pane %q at %v", pane.Name, area)
+		}
+		bottom = area.O.Y + area.Height
+	}
+	if navbar := chart.layers[1].area; bottom > navbar.O.Y {
+		t.Errorf("Resize overflow fails: the panes end at %d, below the navbar %v", bottom, navbar)
+	}
+}
+
+func TestPaneYScale(t *testing.T) {
+	chart, h := newTestChart(testSeries(100))
+	h.flushFrames()
+	main := chart.yAxisRange
+
+	volume := chart.AddVolumePane(0.2)
+	h.flushFrames()
+	if !chart.yAxisRange.Equal(main) {
+		t.Errorf("AddVolumePane fails: the y range of the chart changes from %v to %v", main, chart.yAxisRange)
+	}
+	_, vrange := chart.mainBars.volumes(chart.selectedTimeSlice)
+	if volume.yAxisRange.Low() != 0 || volume.yAxisRange.High() < vrange.High() {
+		t.Errorf("AddVolumePane fails: the y range of the pane is %v, want 0-%v", volume.yAxisRange, vrange.High())
+	}
+	if chart.recorder(6).Count("FillRect") == 0 || chart.recorder(7).Count("FillText") == 0 {
+		t.Errorf("AddVolumePane fails: the pane is not drawn")
+	}
+
+	// an empty pane has no y scale
+	empty := chart.AddPane("empty", 0.1)
+	h.flushFrames()
+	if empty.yAxisRange.Delta() != 0 {
+		t.Errorf("AddPane fails: get y range %v", empty.yAxisRange)
+	}
+
+	// live data redraws the panes
+	resetRecorders(chart, h)
+	chart.UpdateHead(chart.MainSeries.Head.Close, vrange.High()*10)
+	h.flushFrames()
+	if chart.recorder(6).Count("ClearRect") != 1 || chart.recorder(7).Count("ClearRect") != 1 {
+		t.Errorf("UpdateHead fails: the volume pane is not redrawn")
+	}
+	if volume.yAxisRange.High() < vrange.High()*10 {
+		t.Errorf("UpdateHead fails: the y range of the pane is %v", volume.yAxisRange)
+	}
+}
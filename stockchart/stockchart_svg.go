@@ -0,0 +1,71 @@
+package stockchart
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// WriteSVG writes a single SVG document reproducing all layers of the chart for the current selectedTimeSlice:
+// candles, grids, volume bars, navbar and subcharts.
+//
+// Every layer is redrawn with an SVGRenderer, then its own renderer is restored. The hover layer, with the selected data and the buttons, is not exported.
+// Coordinates are expressed in drawing buffer pixels, the document size is expressed in css pixels.
+func (pchart *StockChart) WriteSVG(w io.Writer) error {
+	// layout the chart if it has never been sized
+	if pchart.layers[0] == nil || pchart.layers[0].ClipArea.Width == 0 {
+		pchart.Resize()
+	}
+
+	master := pchart.host.bounds()
+	dpr := pchart.host.devicePixelRatio()
+	_, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %s %s">`+"\n",
+		master.Width, master.Height, svgNum(float64(master.Width)*dpr), svgNum(float64(master.Height)*dpr))
+	if err != nil {
+		return err
+	}
+
+	for i, layer := range pchart.layers {
+		// the hover layer only shows the interactions with the chart
+		if layer == nil || i == 5 {
+			continue
+		}
+
+		// redraw the layer with an svg renderer, ids are prefixed by the chart id to be unique in a page showing several charts
+		idpfx := fmt.Sprintf("%s-l%d", pchart.ID, i)
+		svg := NewSVGRenderer(idpfx)
+		ctx2D := layer.Ctx2D
+		layer.Ctx2D = svg
+		layer.Redraw()
+		layer.Ctx2D = ctx2D
+
+		// translate the layer within the document and clip it to its area
+		ox := float64(layer.area.O.X-master.O.X) * dpr
+		oy := float64(layer.area.O.Y-master.O.Y) * dpr
+		clip := svgRectAttr(0, 0, float64(layer.ClipArea.Width), float64(layer.ClipArea.Height))
+		fmt.Fprintf(w, `<g id=%q transform="translate(%s %s)">`, layer.Name, svgNum(ox), svgNum(oy))
+		fmt.Fprintf(w, `<clipPath id="%sclip"><rect %s/></clipPath><g clip-path="url(#%sclip)">`, idpfx, clip, idpfx)
+		if layer.bgcolor.Alpha() != 0 {
+			fmt.Fprintf(w, `<rect %s %s/>`, clip, svgFillAttr(layer.bgcolor))
+		}
+		if _, err := fmt.Fprintf(w, "%s</g></g>\n", svg.String()); err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintln(w, "</svg>")
+	return err
+}
+
+// SaveSVG writes the SVG document of the chart into a file, see WriteSVG.
+func (pchart *StockChart) SaveSVG(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := pchart.WriteSVG(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,31 @@
+package stockchart
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteSVG(t *testing.T) {
+	chart, h := newTestChart(testSeries(48))
+	h.flushFrames()
+	chart.selectedData = chart.MainSeries.Head
+
+	var sb strings.Builder
+	if err := chart.WriteSVG(&sb); err != nil {
+		t.Fatal(err)
+	}
+	svg := sb.String()
+	if !strings.HasPrefix(svg, "<svg ") || !strings.HasSuffix(svg, "</svg>\n") {
+		t.Fatalf("WriteSVG fails: not an svg document")
+	}
+	for _, layer := range chart.layers {
+		if exported := strings.Contains(svg, `<g id="`+layer.Name+`"`); exported != (layer != chart.layers[5]) {
+			t.Errorf("WriteSVG fails: layer %q exported=%v", layer.Name, exported)
+		}
+	}
+
+	// the ids of the clip paths are unique among charts
+	if !strings.Contains(svg, `<clipPath id="test-l4clip`) || strings.Contains(svg, `<clipPath id="l`) {
+		t.Errorf("WriteSVG fails: clip paths not prefixed by the chart id")
+	}
+}
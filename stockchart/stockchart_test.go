@@ -0,0 +1,275 @@
+package stockchart
+
+import (
+	"testing"
+	"time"
+
+	timeline "github.com/larry868/timeline/v2"
+)
+
+// xCandle returns the x position of the middle of the ith candle of a test chart showing the whole series
+func xCandle(chart *StockChart, i int) int {
+	drawarea := chart.layers[5].ClipArea
+	return drawarea.O.X + int(float64(drawarea.Width)*(float64(i)+0.5)/float64(chart.MainSeries.Size()))
+}
+
+func TestChartRedraw(t *testing.T) {
+	chart, h := newTestChart(testSeries(48))
+
+	if chart.selectedTimeSlice.Compare(chart.MainSeries.TimeSlice()) != timeline.EQUAL {
+		t.Fatalf("selectedTimeSlice fails: want %s, get %s", chart.MainSeries.TimeSlice(), chart.selectedTimeSlice)
+	}
+
+	// every layer is cleared before being drawn
+	for i, layer := range chart.layers {
+		rec := chart.recorder(i)
+		if len(rec.Calls) == 0 || rec.Calls[0].Name != "ClearRect" {
+			t.Fatalf("layer %q redraw fails: first call is not ClearRect: %v", layer.Name, rec.Calls)
+		}
+	}
+
+	// the chart layer draws at least a body and a wick per candle
+	if get := chart.recorder(4).Count("FillRect"); get < 2*48 {
+		t.Errorf("chart layer redraw fails: want at least %d FillRect, get %d", 2*48, get)
+	}
+
+	// the navbar draws the series as a single filled path
+	if get := chart.recorder(1).Count("Fill"); get != 1 {
+		t.Errorf("navbar layer redraw fails: want 1 Fill, get %d", get)
+	}
+
+	// nothing is hovered
+	if get := len(chart.recorder(5).Calls); get != 1 {
+		t.Errorf("hover layer redraw fails: want only ClearRect, get %v", chart.recorder(5).Calls)
+	}
+
+	// only the time selector and the hover layers catch events
+	if get := h.handled["2-timeselector"]; get&evt_MouseDown == 0 || get&evt_Wheel == 0 {
+		t.Errorf("timeselector events fails: get %08b", get)
+	}
+	if get := h.handled["5-hover"]; get&evt_Click == 0 || get&evt_MouseMove == 0 {
+		t.Errorf("hover events fails: get %08b", get)
+	}
+	if len(h.handled) != 2 {
+		t.Errorf("events fails: want 2 layers handling events, get %v", h.handled)
+	}
+
+	// a new redraw replays the same calls
+	before := append([]RenderCall{}, chart.recorder(4).Calls...)
+	chart.recorder(4).Reset()
+	chart.Redraw()
+	h.flushFrames()
+	after := chart.recorder(4).Calls
+	if len(before) != len(after) {
+		t.Errorf("Redraw fails: want %d calls, get %d", len(before), len(after))
+	}
+}
+
+func TestChartEmptySeries(t *testing.T) {
+	chart, _ := newTestChart(DataList{})
+	for i, layer := range chart.layers {
+		rec := chart.recorder(i)
+		if get := len(rec.Calls); get != 1 {
+			t.Errorf("layer %q redraw fails: want only ClearRect, get %v", layer.Name, rec.Calls)
+		}
+	}
+	chart.layers[5].dispatchMouseEvent(evt_Click, Point{X: 10, Y: 10}, &MouseEvent{})
+	if chart.selectedData != nil {
+		t.Errorf("click fails: want no selected data, get %s", chart.selectedData)
+	}
+}
+
+func TestHoverMouseMove(t *testing.T) {
+	chart, _ := newTestChart(testSeries(48))
+	hover := chart.layers[5]
+	rec := chart.recorder(5)
+
+	rec.Reset()
+	hover.dispatchMouseEvent(evt_MouseMove, Point{X: xCandle(chart, 10), Y: 100}, &MouseEvent{})
+	texts := rec.Filter("FillText")
+	if len(texts) != 1 || texts[0].Args[0] != "2024, Jan, Mon 01, 10:30" {
+		t.Fatalf("hover fails: want the 10:30 label, get %v", texts)
+	}
+
+	// moving over the same candle does not redraw anything
+	rec.Reset()
+	hover.dispatchMouseEvent(evt_MouseMove, Point{X: xCandle(chart, 10) + 1, Y: 100}, &MouseEvent{})
+	if len(rec.Calls) != 0 {
+		t.Errorf("hover fails: want no call, get %v", rec.Calls)
+	}
+
+	// leaving clears the layer
+	rec.Reset()
+	hover.dispatchMouseEvent(evt_MouseLeave, Point{X: -1, Y: 100}, &MouseEvent{})
+	if rec.Count("ClearRect") != 1 || rec.Count("FillText") != 0 {
+		t.Errorf("hover leave fails: get %v", rec.Calls)
+	}
+}
+
+func TestClickSelectData(t *testing.T) {
+	chart, _ := newTestChart(testSeries(48))
+	var notified []*DataStock
+	chart.NotifySelChangeData = func(data *DataStock) {
+		notified = append(notified, data)
+	}
+	hover := chart.layers[5]
+
+	want := chart.MainSeries.Tail
+	for i := 0; i < 10; i++ {
+		want = want.Next
+	}
+	hover.dispatchMouseEvent(evt_Click, Point{X: xCandle(chart, 10), Y: 100}, &MouseEvent{})
+	if chart.selectedData != want {
+		t.Fatalf("click fails: want %s, get %s", want, chart.selectedData)
+	}
+	if len(notified) != 1 || notified[0] != want {
+		t.Errorf("click fails: want a single notification, get %v", notified)
+	}
+
+	// clicking the same candle again does not notify
+	hover.dispatchMouseEvent(evt_Click, Point{X: xCandle(chart, 10), Y: 100}, &MouseEvent{})
+	if len(notified) != 1 {
+		t.Errorf("click fails: unexpected notification %v", notified)
+	}
+
+	// shift click unselects
+	hover.dispatchMouseEvent(evt_Click, Point{X: xCandle(chart, 20), Y: 100}, &MouseEvent{ShiftKey: true})
+	if chart.selectedData != nil {
+		t.Errorf("shift click fails: want no selected data, get %s", chart.selectedData)
+	}
+	if len(notified) != 2 || notified[1] != nil {
+		t.Errorf("shift click fails: want a nil notification, get %v", notified)
+	}
+}
+
+func TestWheelSelTimeSlice(t *testing.T) {
+	chart, _ := newTestChart(testSeries(48))
+	var notified []timeline.TimeSlice
+	chart.NotifySelChangeTimeSlice = func(ts timeline.TimeSlice) {
+		notified = append(notified, ts)
+	}
+	timeselector := chart.layers[2]
+	full := chart.selectedTimeSlice
+
+	// zoom in
+	timeselector.dispatchWheelEvent(&WheelEvent{MouseEvent: MouseEvent{ShiftKey: true}, DeltaY: -100})
+	zoomed := chart.selectedTimeSlice
+	if zoomed.Duration().Duration >= full.Duration().Duration {
+		t.Fatalf("wheel zoom fails: want shorter than %s, get %s", full, zoomed)
+	}
+	if len(notified) != 1 || notified[0].Compare(zoomed) != timeline.EQUAL {
+		t.Errorf("wheel zoom fails: want a single notification, get %v", notified)
+	}
+
+	// the chart layer follows the selection
+	rec := chart.recorder(4)
+	rec.Reset()
+	chart.layers[4].Redraw()
+	if rec.Count("FillRect") == 0 {
+		t.Errorf("chart layer fails: not redrawn after zoom")
+	}
+
+	// shift to the past
+	timeselector.dispatchWheelEvent(&WheelEvent{DeltaY: 100})
+	shifted := chart.selectedTimeSlice
+	if !shifted.From.Before(zoomed.From) || shifted.Duration().Duration != zoomed.Duration().Duration {
+		t.Errorf("wheel shift fails: want %s shifted to the past, get %s", zoomed, shifted)
+	}
+	if len(notified) != 2 {
+		t.Errorf("wheel shift fails: want 2 notifications, get %d", len(notified))
+	}
+
+	// no move
+	timeselector.dispatchWheelEvent(&WheelEvent{})
+	if len(notified) != 2 {
+		t.Errorf("wheel fails: unexpected notification %v", notified)
+	}
+}
+
+func TestTimeSelectorDrag(t *testing.T) {
+	chart, h := newTestChart(testSeries(48))
+	timeselector := chart.layers[2]
+	drawing := timeselector.drawings[0]
+	var button Rect
+	for _, call := range chart.recorder(2).Filter("StrokeRect") {
+		// the first stroked rect is the left button
+		button = Rect{O: Point{X: int(call.Args[0].(float64)), Y: int(call.Args[1].(float64))}, Width: int(call.Args[2].(float64)), Height: int(call.Args[3].(float64))}
+		break
+	}
+	if button.Width == 0 {
+		t.Fatalf("timeselector fails: left button not drawn")
+	}
+	xy := Point{X: button.O.X + button.Width/2, Y: button.O.Y + button.Height/2}
+
+	// hovering the button changes the cursor
+	timeselector.dispatchMouseEvent(evt_MouseMove, xy, &MouseEvent{})
+	if get := h.cursors["2-timeselector"]; get != "col-resize" {
+		t.Errorf("timeselector hover fails: want col-resize cursor, get %q", get)
+	}
+
+	// drag the left button to the middle of the navbar
+	from := chart.selectedTimeSlice.From
+	timeselector.dispatchMouseDownMoveUp(xy, Point{X: drawing.drawArea.O.X + drawing.drawArea.Width/2, Y: xy.Y})
+	want := from.Add(chart.timeRange.Duration().Duration / 2)
+	if get := chart.selectedTimeSlice.From; get.Sub(want).Abs() > time.Minute*10 {
+		t.Errorf("timeselector drag fails: want From at %s, get %s", want, get)
+	}
+	if !chart.selectedTimeSlice.To.Equal(chart.timeRange.To) {
+		t.Errorf("timeselector drag fails: To has changed %s", chart.selectedTimeSlice.To)
+	}
+}
+
+// dispatchMouseDownMoveUp simulates a drag with the main button, from start to end
+func (layer *Layer) dispatchMouseDownMoveUp(start Point, end Point) {
+	layer.dispatchMouseEvent(evt_MouseDown, start, &MouseEvent{})
+	layer.dispatchMouseEvent(evt_MouseMove, end, &MouseEvent{})
+	layer.dispatchMouseEvent(evt_MouseUp, end, &MouseEvent{})
+}
+
+func TestRedrawCoalescing(t *testing.T) {
+	chart, h := newTestChart(testSeries(48))
+	h.flushFrames()
+	for i := range chart.layers {
+		chart.recorder(i).Reset()
+	}
+
+	// a burst of changes requests a single frame
+	full := chart.selectedTimeSlice
+	var last timeline.TimeSlice
+	for i := 1; i <= 10; i++ {
+		last = timeline.MakeTimeSlice(full.From.Add(time.Duration(i)*time.Hour), 12*time.Hour)
+		chart.DoChangeSelTimeSlice(last, false)
+		chart.DoChangeSelData(chart.MainSeries.Tail.Next, false)
+	}
+	if len(h.frames) != 1 {
+		t.Fatalf("coalescing fails: want 1 frame requested, get %d", len(h.frames))
+	}
+	if get := len(chart.recorder(4).Calls); get != 0 {
+		t.Fatalf("coalescing fails: the chart layer is drawn before the frame")
+	}
+
+	// the frame draws the last state
+	h.flushFrames()
+	if get := chart.recorder(4).Count("ClearRect"); get != 1 {
+		t.Errorf("coalescing fails: want the chart layer redrawn once, get %d", get)
+	}
+	candles := chart.layers[4].drawings[4]
+	if candles.NeedRedraw() {
+		t.Errorf("coalescing fails: the candles do not reflect the last selection")
+	}
+
+	// nothing left to draw
+	h.flushFrames()
+	if len(h.frames) != 0 {
+		t.Errorf("coalescing fails: unexpected frame requested")
+	}
+
+	// invalidating a layer redraws it even if none of its drawings need it
+	chart.recorder(1).Reset()
+	chart.layers[1].Invalidate()
+	chart.layers[1].Invalidate()
+	h.flushFrames()
+	if get := chart.recorder(1).Count("ClearRect"); get != 1 {
+		t.Errorf("Invalidate fails: want the navbar redrawn once, get %d", get)
+	}
+}
@@ -0,0 +1,188 @@
+package stockchart
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	timeline "github.com/larry868/timeline/v2"
+)
+
+// minTimeframeCandles is the minimum number of candles in the selected time slice after switching the timeframe
+const minTimeframeCandles = 10
+
+// Timeframe is a precision of candles the chart can switch to
+type Timeframe struct {
+	Label     string        // the label of the timeframe on the selector, like "5m"
+	Precision time.Duration // the precision of the candles
+	Alignment Alignment     // the alignment of the candles
+}
+
+// DefaultTimeframes are the timeframes of the selector if none are given to SetTimeframes
+var DefaultTimeframes = []Timeframe{
+	{Label: "1m", Precision: time.Minute},
+	{Label: "5m", Precision: 5 * time.Minute},
+	{Label: "15m", Precision: 15 * time.Minute},
+	{Label: "1h", Precision: time.Hour},
+	{Label: "4h", Precision: 4 * time.Hour},
+	{Label: "1D", Precision: 24 * time.Hour, Alignment: Alignment{Calendar: CalendarDay}}}
+
+// SetTimeframes allows the chart to switch between timeframes, aggregating base on the fly.
+// base is the series at the base resolution, timeframes finer than its precision are ignored.
+// The chart keeps the data points of base, live data are linked after its head when switching the timeframe.
+// If timeframes is nil, the DefaultTimeframes are used.
+//
+// The chart switches to the finest timeframe, and the selector shows all of them at the top right of the chart if showSelector.
+//
+// Returns an error if none of the timeframes can be built from base.
+func (pchart *StockChart) SetTimeframes(base DataList, timeframes []Timeframe, showSelector bool) error {
+	if timeframes == nil {
+		timeframes = DefaultTimeframes
+	}
+	var valids []Timeframe
+	for _, tf := range timeframes {
+		if tf.Precision >= base.Precision {
+			valids = append(valids, tf)
+		}
+	}
+	if len(valids) == 0 {
+		return fmt.Errorf("unable to set timeframes: all are finer than %q at %s", base.Name, base.Precision)
+	}
+
+	pchart.baseSeries = base
+	pchart.timeframes = valids
+	pchart.showTimeframes = showSelector
+	pchart.timeframe = nil
+	return pchart.DoChangeTimeframe(valids[0].Precision, false)
+}
+
+// Timeframe returns the current timeframe of the chart, nil if timeframes are not set.
+func (pchart *StockChart) Timeframe() *Timeframe {
+	return pchart.timeframe
+}
+
+// DoChangeTimeframe switches the MainSeries to the timeframe of precision, aggregating the base series given to SetTimeframes.
+//
+// The selected time slice keeps its center and its width, unless it's narrower than a few candles or out of the new time range.
+// The selected data becomes the candle containing its middle.
+// All layers are redrawn at the next frame.
+//
+// Live data, appended with AppendData, UpdateHead or an aggregator of the chart after the end of the base series,
+// are moved to the base series before switching, at the precision they've been received, so they're kept in every timeframe.
+//
+// Returns an error if precision is not one of the timeframes of the chart.
+func (pchart *StockChart) DoChangeTimeframe(precision time.Duration, fNotify bool) error {
+	var timeframe *Timeframe
+	for i := range pchart.timeframes {
+		if pchart.timeframes[i].Precision == precision {
+			timeframe = &pchart.timeframes[i]
+			break
+		}
+	}
+	if timeframe == nil {
+		return fmt.Errorf("unable to change the timeframe to %s: unknown timeframe", precision)
+	}
+	if timeframe == pchart.timeframe {
+		return nil
+	}
+	pchart.syncLiveData()
+	series, err := pchart.baseSeries.Resample(timeframe.Precision, timeframe.Alignment)
+	if err != nil {
+		return err
+	}
+	pchart.timeframe = timeframe
+
+	// change the series, referenced by all drawings of the main series
+	oldsel := pchart.selectedTimeSlice
+	olddata := pchart.selectedData
+	pchart.MainSeries = series
+
+	// reset the time range
+	trange := pchart.MainSeries.TimeSlice()
+	if trange.Duration().IsFinite && pchart.extendRate > 0 {
+		trange.ExtendTo(trange.Duration().Adjust(pchart.extendRate).Duration)
+	}
+	pchart.timeRange.From = trange.From
+	pchart.timeRange.To = trange.To
+
+	// keep the selection centered, showing a few candles at least, and shift it within the new time range
+	if !oldsel.IsZero() && oldsel.Duration().IsFinite {
+		oldsel.ForceDirection(timeline.Chronological)
+		half := max(oldsel.Duration().Duration, minTimeframeCandles*timeframe.Precision) / 2
+		center := oldsel.Middle()
+		oldsel.From, oldsel.To = center.Add(-half), center.Add(half)
+		if d := pchart.timeRange.From.Sub(oldsel.From); d > 0 {
+			oldsel.Shift(d)
+		} else if d := pchart.timeRange.To.Sub(oldsel.To); d < 0 {
+			oldsel.Shift(d)
+		}
+	}
+	pchart.setSelTimeSlice(oldsel)
+
+	// remap the selected data
+	pchart.selectedData = nil
+	if olddata != nil {
+		pchart.selectedData = pchart.MainSeries.GetDataAt(olddata.Middle())
+	}
+
+	pchart.Redraw()
+
+	if fNotify {
+		if pchart.NotifySelChangeTimeSlice != nil {
+			pchart.NotifySelChangeTimeSlice(pchart.selectedTimeSlice)
+		}
+		if pchart.NotifySelChangeData != nil && olddata != nil {
+			pchart.NotifySelChangeData(pchart.selectedData)
+		}
+		if pchart.NotifyChangeTimeframe != nil {
+			pchart.NotifyChangeTimeframe(*timeframe)
+		}
+	}
+	return nil
+}
+
+// syncLiveData appends to the base series the live data of the MainSeries received after the end of the base series.
+//
+// The candle of the MainSeries containing the head of the base series may have been updated in place,
+// its changes since the resampling become a new candle of the base series, up to the end of the candle.
+func (pchart *StockChart) syncLiveData() {
+	if pchart.timeframe == nil {
+		return
+	}
+	base := &pchart.baseSeries
+	var end time.Time
+	if base.Head != nil {
+		end = base.Head.To
+	}
+	for _, item := range pchart.MainSeries.window(nil) {
+		switch {
+		case !item.From.Before(end):
+			// received after the end of the base series
+			data := &DataStock{TimeSlice: item.TimeSlice, Open: item.Open, High: item.High, Low: item.Low, Close: item.Close, Volume: item.Volume}
+			base.Append(data)
+
+		case item.To.After(end):
+			// the candle of the head of the base series, compared to the aggregation of the base series
+			aggregated := DataStock{High: math.Inf(-1), Low: math.Inf(1)}
+			for _, b := range base.Window(timeline.TimeSlice{From: item.From, To: end}) {
+				if !b.From.Before(item.From) && b.From.Before(end) {
+					aggregated.High, aggregated.Low = max(aggregated.High, b.High), min(aggregated.Low, b.Low)
+					aggregated.Close = b.Close
+					aggregated.Volume += b.Volume
+				}
+			}
+			if item.Close == aggregated.Close && item.High <= aggregated.High && item.Low >= aggregated.Low && item.Volume <= aggregated.Volume {
+				continue
+			}
+			data := &DataStock{TimeSlice: timeline.TimeSlice{From: end, To: item.To}, Open: aggregated.Close, Close: item.Close, Volume: max(0, item.Volume-aggregated.Volume)}
+			data.High, data.Low = max(data.Open, data.Close), min(data.Open, data.Close)
+			if item.High > aggregated.High {
+				data.High = item.High
+			}
+			if item.Low < aggregated.Low {
+				data.Low = item.Low
+			}
+			base.Append(data)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package stockchart
+
+import (
+	"testing"
+	"time"
+
+	timeline "github.com/larry868/timeline/v2"
+)
+
+func TestChangeTimeframe(t *testing.T) {
+	base := testSeriesPrecision(3*24*60, time.Minute)
+	chart, h := newTestChart(base)
+	if err := chart.SetTimeframes(base, nil, false); err != nil {
+		t.Fatal(err)
+	}
+	if chart.Timeframe().Label != "1m" || chart.MainSeries.Size() != 3*24*60 {
+		t.Fatalf("SetTimeframes fails: get %v %s", chart.Timeframe(), chart.MainSeries)
+	}
+	var notifiedts, notifieddata, notifiedtf int
+	chart.NotifySelChangeTimeSlice = func(ts timeline.TimeSlice) { notifiedts++ }
+	chart.NotifySelChangeData = func(data *DataStock) { notifieddata++ }
+	chart.NotifyChangeTimeframe = func(tf Timeframe) { notifiedtf++ }
+
+	// 2 hours around the noon of the second day, and a selected candle at 12:07
+	noon := base.Tail.From.Add(36 * time.Hour)
+	chart.DoChangeSelTimeSlice(timeline.MakeTimeSlice(noon.Add(-time.Hour), 2*time.Hour), false)
+	chart.DoChangeSelData(chart.MainSeries.GetDataAt(noon.Add(7*time.Minute+30*time.Second)), false)
+
+	if err := chart.DoChangeTimeframe(5*time.Minute, true); err != nil {
+		t.Fatal(err)
+	}
+	if chart.Timeframe().Label != "5m" || chart.MainSeries.Size() != 3*24*12 || chart.MainSeries.Precision != 5*time.Minute {
+		t.Errorf("DoChangeTimeframe fails: get %s", chart.MainSeries)
+	}
+	if !chart.selectedTimeSlice.Middle().Equal(noon) || chart.selectedTimeSlice.Duration().Duration != 2*time.Hour {
+		t.Errorf("DoChangeTimeframe fails: want the selection centered on %s, get %s", noon, chart.selectedTimeSlice)
+	}
+	if data := chart.selectedData; data == nil || !data.From.Equal(noon.Add(5*time.Minute)) || data != chart.MainSeries.GetDataAt(data.Middle()) {
+		t.Errorf("DoChangeTimeframe fails: want the selected data remapped, get %s", data)
+	}
+	if notifiedts != 1 || notifieddata != 1 || notifiedtf != 1 {
+		t.Errorf("DoChangeTimeframe fails: get %d, %d, %d notifications", notifiedts, notifieddata, notifiedtf)
+	}
+	h.flushFrames()
+	if data := chart.recorder(4).Filter("FillText"); len(data) == 0 {
+		t.Errorf("DoChangeTimeframe fails: chart layer not redrawn")
+	}
+
+	// the selection is too narrow for daily candles, it's extended within the time range
+	if err := chart.DoChangeTimeframe(24*time.Hour, false); err != nil {
+		t.Fatal(err)
+	}
+	if chart.MainSeries.Size() != 3 || chart.selectedTimeSlice.Compare(chart.timeRange) != timeline.EQUAL {
+		t.Errorf("DoChangeTimeframe daily fails: get %s, selection %s", chart.MainSeries, chart.selectedTimeSlice)
+	}
+	if data := chart.selectedData; data == nil || !data.From.Equal(base.Tail.From.Add(24*time.Hour)) {
+		t.Errorf("DoChangeTimeframe daily fails: get %s", data)
+	}
+
+	// the base series is unchanged
+	if base.Size() != 3*24*60 || chart.baseSeries.Head != base.Head {
+		t.Errorf("DoChangeTimeframe fails: base series changed")
+	}
+
+	if err := chart.DoChangeTimeframe(2*time.Hour, false); err == nil {
+		t.Errorf("DoChangeTimeframe fails: want an error for an unknown timeframe")
+	}
+}
+
+func TestChangeTimeframeLiveData(t *testing.T) {
+	// the base series ends at 01:02, within the 5m candle of 01:00
+	base := testSeriesPrecision(62, time.Minute)
+	chart, _ := newTestChart(base)
+	if err := chart.SetTimeframes(base, nil, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := chart.DoChangeTimeframe(5*time.Minute, false); err != nil {
+		t.Fatal(err)
+	}
+	headfrom := chart.MainSeries.Head.From
+	headvolume := chart.MainSeries.Head.Volume
+
+	// live data at 5m: the head is updated, then a new candle is appended
+	chart.UpdateHead(500, headvolume+50)
+	last := &DataStock{TimeSlice: timeline.MakeTimeSlice(chart.MainSeries.Head.To, 5*time.Minute), Open: 500, High: 510, Low: 490, Close: 505, Volume: 70}
+	chart.AppendData(last)
+
+	// switching back and forth keeps them
+	for _, precision := range []time.Duration{time.Minute, 5 * time.Minute} {
+		if err := chart.DoChangeTimeframe(precision, false); err != nil {
+			t.Fatal(err)
+		}
+		if head := chart.MainSeries.Head; !head.From.Equal(last.From) || head.Close != 505 || head.High != 510 || head.Volume != 70 {
+			t.Errorf("DoChangeTimeframe %s fails: appended data lost, get %s", precision, head)
+		}
+		if !chart.timeRange.To.After(last.From) {
+			t.Errorf("DoChangeTimeframe %s fails: time range %s", precision, chart.timeRange)
+		}
+	}
+	if chart.MainSeries.Size() != 14 {
+		t.Errorf("DoChangeTimeframe fails: want 14 candles, get %s", chart.MainSeries)
+	}
+	if head := chart.MainSeries.GetDataAt(headfrom.Add(time.Minute)); head == nil || head.Close != 500 || head.High != 500 || head.Volume != headvolume+50 {
+		t.Errorf("DoChangeTimeframe fails: updated head lost, get %s", head)
+	}
+
+	// switching without live data changes nothing
+	if err := chart.DoChangeTimeframe(time.Minute, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := chart.DoChangeTimeframe(5*time.Minute, false); err != nil {
+		t.Fatal(err)
+	}
+	if chart.MainSeries.Size() != 14 || chart.baseSeries.Size() != 64 {
+		t.Errorf("DoChangeTimeframe fails: get %s, base %s", chart.MainSeries, &chart.baseSeries)
+	}
+}
+
+func TestTimeframeSelector(t *testing.T) {
+	base := testSeries(48)
+	chart, h := newTestChart(base)
+
+	// finer timeframes are ignored
+	if err := chart.SetTimeframes(base, nil, true); err != nil {
+		t.Fatal(err)
+	}
+	if len(chart.timeframes) != 3 || chart.Timeframe().Label != "1h" {
+		t.Fatalf("SetTimeframes fails: get %v", chart.timeframes)
+	}
+	var notified []Timeframe
+	chart.NotifyChangeTimeframe = func(tf Timeframe) { notified = append(notified, tf) }
+
+	h.flushFrames()
+	if get := chart.recorder(5).Count("FillText"); get != 3 || len(chart.timeframeButtons) != 3 {
+		t.Fatalf("timeframe selector fails: want 3 buttons, get %d", get)
+	}
+
+	// clicking the 4h button
+	hover := chart.layers[5]
+	hover.dispatchMouseEvent(evt_Click, chart.timeframeButtons[1].Middle(), &MouseEvent{})
+	if chart.Timeframe().Label != "4h" || chart.MainSeries.Size() != 12 {
+		t.Errorf("timeframe selector fails: get %v", chart.Timeframe())
+	}
+	if len(notified) != 1 || notified[0].Label != "4h" || chart.selectedData != nil {
+		t.Errorf("timeframe selector fails: get %v notifications, selected data %s", notified, chart.selectedData)
+	}
+
+	// clicking the current one does nothing
+	hover.dispatchMouseEvent(evt_Click, chart.timeframeButtons[1].Middle(), &MouseEvent{})
+	if len(notified) != 1 {
+		t.Errorf("timeframe selector fails: unexpected notification")
+	}
+
+	// the cursor changes over the buttons
+	hover.dispatchMouseEvent(evt_MouseMove, chart.timeframeButtons[2].Middle(), &MouseEvent{})
+	if get := h.cursors[hover.Name]; get != "pointer" {
+		t.Errorf("timeframe selector fails: want the pointer cursor, get %q", get)
+	}
+
+	// hidden selector
+	chart.SetTimeframes(base, nil, false)
+	h.flushFrames()
+	if len(chart.timeframeButtons) != 0 {
+		t.Errorf("timeframe selector fails: buttons still visible")
+	}
+
+	if err := chart.SetTimeframes(base, []Timeframe{{Label: "1m", Precision: time.Minute}}, true); err == nil {
+		t.Errorf("SetTimeframes fails: want an error")
+	}
+}
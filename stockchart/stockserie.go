@@ -3,6 +3,7 @@ package stockchart
 import (
 	"fmt"
 	"log"
+	"math"
 	"time"
 
 	"github.com/larry868/datarange"
@@ -27,12 +28,14 @@ type DataStock struct {
 	Prev *DataStock `json:"-"` // going to the tail
 }
 
+// the colors of rising, falling and unchanged candles
+const (
+	greenCandle   = rgb.Color(0x7dce13ff)
+	redCandle     = rgb.Color(0xb20016ff)
+	neutralCandle = rgb.Gray
+)
+
 func (ds DataStock) CandleColor() (candleColor rgb.Color) {
-	const (
-		greenCandle   = rgb.Color(0x7dce13ff)
-		redCandle     = rgb.Color(0xb20016ff)
-		neutralCandle = rgb.Gray
-	)
 	candleColor = neutralCandle
 	if ds.Close > ds.Open {
 		candleColor = greenCandle
@@ -52,11 +55,16 @@ func (dp *DataStock) String() string {
 
 // DataList is a time ordered chained list of DataPoint.
 // We assume that ordered points are linked in chronological order
+//
+// Data points are also stored contiguously in an index, maintained by Append and Insert,
+// allowing lookups by time in O(log n). Call Reindex after changing the links directly.
 type DataList struct {
 	Name      string        // the name of the data list, usually the name of the pair and its precision
 	Precision time.Duration // the precision of this serie, used for drawing candles
 	Tail      *DataStock    // the tail !-----...
 	Head      *DataStock    // ...-----! the head
+
+	index *dataIndex // contiguous storage of data points, nil until the first Append, Insert or Reindex
 }
 
 func (dl DataList) String() string {
@@ -69,6 +77,9 @@ func (dl DataList) IsEmpty() bool {
 }
 
 func (pdl DataList) Size() (size int) {
+	if pdl.index.isValidFor(pdl) {
+		return len(pdl.index.items)
+	}
 	scan := pdl.Head
 	for scan != nil {
 		size++
@@ -82,6 +93,8 @@ func (pdl DataList) Size() (size int) {
 
 // Append a dataPoint to the head
 func (dl *DataList) Append(newdata *DataStock) {
+	wasValid := dl.index.isValidFor(*dl)
+
 	// add the data point to the list
 	newdata.Next = nil
 	newdata.Prev = dl.Head
@@ -95,14 +108,18 @@ func (dl *DataList) Append(newdata *DataStock) {
 	}
 	// update head
 	dl.Head = newdata
+
+	dl.indexAppend(newdata, wasValid)
 }
 
 // Insert a dataPoint at the right position according to dates
 // backward lookup to find the insert position
 func (dl *DataList) Insert(newdata *DataStock) {
+	wasValid := dl.index.isValidFor(*dl)
 	if dl.Head == nil || dl.Tail == nil {
 		dl.Tail = newdata
 		dl.Head = newdata
+		dl.Reindex()
 		return
 	}
 
@@ -122,6 +139,7 @@ func (dl *DataList) Insert(newdata *DataStock) {
 				scan.Next.Prev = newdata
 			}
 			scan.Next = newdata
+			dl.indexInsert(newdata, wasValid)
 			return
 		}
 		scan = scan.Prev
@@ -131,10 +149,52 @@ func (dl *DataList) Insert(newdata *DataStock) {
 	newdata.Next = dl.Tail
 	newdata.Prev = nil
 	dl.Tail = newdata
+	dl.indexInsert(newdata, wasValid)
+}
+
+// Prepend links all data points of olders before the tail, in one go.
+// olders must be chronological and end before the start of the tail, returns an error otherwise and dl is unchanged.
+//
+// olders is emptied, its data points now belong to dl.
+func (dl *DataList) Prepend(olders *DataList) error {
+	if olders.IsEmpty() {
+		return nil
+	}
+	if dl.Tail != nil && olders.Head.To.After(dl.Tail.From) {
+		return fmt.Errorf("unable to prepend %q: ends at %s after the tail %s", olders.Name, olders.Head.To, dl.Tail.From)
+	}
+	if !olders.index.isValidFor(*olders) {
+		olders.Reindex()
+	}
+	if !olders.index.sorted {
+		return fmt.Errorf("unable to prepend %q: not chronological", olders.Name)
+	}
+	wasValid := dl.index.isValidFor(*dl)
+
+	// link
+	olders.Head.Next = dl.Tail
+	if dl.Tail != nil {
+		dl.Tail.Prev = olders.Head
+	} else {
+		dl.Head = olders.Head
+	}
+	dl.Tail = olders.Tail
+	olders.Tail.Prev = nil
+
+	dl.indexPrepend(olders.index.items, wasValid)
+	*olders = DataList{Name: olders.Name, Precision: olders.Precision}
+	return nil
 }
 
 // return the dataPoint at t time, nil if no points found
 func (dl DataList) GetDataAt(t time.Time) (data *DataStock) {
+	if dl.index.isValidFor(dl) && dl.index.sorted {
+		i := dl.index.searchTo(t)
+		if i < len(dl.index.items) && !dl.index.items[i].From.After(t) {
+			return dl.index.items[i]
+		}
+		return nil
+	}
 	item := dl.Tail
 	for item != nil {
 		if (t.Equal(item.TimeSlice.From) || t.After(item.TimeSlice.From)) && (t.Equal(item.TimeSlice.To) || t.Before(item.TimeSlice.To)) {
@@ -157,58 +217,52 @@ func (dl DataList) TimeSlice() timeline.TimeSlice {
 	return ts
 }
 
-// DataRange returns the data boundaries of the DataList, scanning all datapoint between the timeslice boundaries
+// DataRange returns the data boundaries of the DataList, for all datapoints overlapping the timeslice
 //
 //	ts == nil scan all data points between the Head and the Tail:
 //	if maxSteps == 0 the returned datarange doesn't have any stepzise.
 //	if maxSteps > 0 the returned datarange gets a stepzise and boudaries are rounded.
 //
+// Zero lows are ignored. Computed in O(log n) if the DataList is indexed and sorted chronologically.
+//
 // returns an empty datarange if the list is empty or if missing head or tail.
 func (dl DataList) DataRange(ts *timeline.TimeSlice, maxSteps uint) (dr datarange.DataRange) {
-	var low, high float64
-	item := dl.Tail
-	for item != nil {
-		// if ts == nil || ((item.TimeSlice.From.Equal(ts.From) || item.TimeSlice.From.After(ts.From)) && (item.TimeSlice.To.Equal(ts.To) || item.TimeSlice.To.Before(ts.To))) {
-		if ts == nil || ((ts.WhereIs(item.From)|ts.WhereIs(item.To))&timeline.TS_IN > 0) {
-			if low == 0 || item.Low < low {
-				low = item.Low
-			}
-			if item.High > high {
-				high = item.High
-			}
-		}
-		if item == dl.Head {
-			break
-		}
-		item = item.Next
-	}
-
-	dr = datarange.Make(low, high, -float64(maxSteps), dl.Name)
+	e := dl.extremes(ts)
+	dr = datarange.Make(e.low, math.Max(0, e.high), -float64(maxSteps), dl.Name)
 	return dr
 }
 
-// VolumeDataRange returns the data boundaries of the DataList, scanning all datapoint between the timeslice boundaries
+// VolumeDataRange returns the data boundaries of the DataList, for all datapoints overlapping the timeslice
 //
 //	ts == nil scan all data points between the Head and the Tail:
 //	if maxSteps == 0 the returned datarange doesn t have any stepzise.
 //	if maxSteps > 0 the returned datarange gets a stepzise and boudaries are rounded.
 //
+// Zero volumes are ignored for the low boundary. Computed in O(log n) if the DataList is indexed and sorted chronologically.
+//
 // returns an empty datarange if the list is empty or if missing head or tail.
 func (dl DataList) VolumeDataRange(ts *timeline.TimeSlice, maxSteps uint) (dr datarange.DataRange) {
-	var low, high float64
+	e := dl.extremes(ts)
+	dr = datarange.Make(e.vlow, math.Max(0, e.vhigh), -float64(maxSteps), dl.Name)
+	return dr
+}
+
+// window returns the data points overlapping ts, or all data points if ts is nil
+func (dl DataList) window(ts *timeline.TimeSlice) []*DataStock {
+	if ts != nil {
+		return dl.Window(*ts)
+	}
+	if dl.index.isValidFor(dl) {
+		return dl.index.items
+	}
+	var all []*DataStock
 	item := dl.Tail
 	for item != nil {
-		if ts == nil || ((ts.WhereIs(item.From)|ts.WhereIs(item.To))&timeline.TS_IN > 0) {
-			if low == 0 || item.Volume < low {
-				low = item.Volume
-			}
-			if item.Volume > high {
-				high = item.Volume
-			}
+		all = append(all, item)
+		if item == dl.Head {
+			break
 		}
 		item = item.Next
 	}
-
-	dr = datarange.Make(low, high, -float64(maxSteps), dl.Name)
-	return dr
+	return all
 }
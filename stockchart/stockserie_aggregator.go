@@ -0,0 +1,137 @@
+package stockchart
+
+import (
+	"fmt"
+	"time"
+
+	timeline "github.com/larry868/timeline/v2"
+)
+
+// Aggregator builds the candles of a DataList from raw trades, at the Precision of the DataList.
+//
+// Candles are aligned on the candles of the DataList, every Precision from the start of the head,
+// so daily or offset candles keep their boundaries. The first candle of an empty DataList starts at the tick time
+// truncated to the precision, since the zero time, so daily candles start at midnight UTC.
+// A tick crossing the boundary of the head closes it and opens a new candle. Periods without any tick get no candle.
+//
+// Late ticks, older than the last one, are aggregated into the candle they belong to, updating its High, Low and Volume.
+// They change the Close of the head only if they're more recent than its Close, and never the Open and the Close of older candles.
+// If their candle does not exist yet, it's inserted into the DataList, unless it overlaps an existing candle.
+type Aggregator struct {
+	Series *DataList // the DataList to feed, with a Precision > 0
+
+	headOpen  time.Time // the time of the tick setting the Open of the head
+	headClose time.Time // the time of the tick setting the Close of the head
+
+	NotifyChange func(data *DataStock, isnew bool) // function called everytime a candle changes or a new one is linked to the DataList, if not nil
+}
+
+// NewAggregator returns an aggregator feeding series
+func NewAggregator(series *DataList) *Aggregator {
+	return &Aggregator{Series: series}
+}
+
+// NewAggregator returns an aggregator feeding the MainSeries of the chart.
+// Every tick updates the chart, redrawing only the affected layers at the next frame.
+func (pchart *StockChart) NewAggregator() *Aggregator {
+	agg := NewAggregator(&pchart.MainSeries)
+	agg.NotifyChange = func(data *DataStock, isnew bool) {
+		if isnew {
+			pchart.dataAdded(data)
+		} else {
+			pchart.invalidateData(data)
+		}
+	}
+	return agg
+}
+
+// AddTick aggregates a trade of size at price, at time t.
+//
+// Returns the candle updated or created, and isnew if the candle has been created.
+// Returns an error if the DataList has no precision, or if the candle of a late tick can't be inserted
+// because it overlaps an existing candle. In case of error the DataList is unchanged and NotifyChange is not called.
+func (agg *Aggregator) AddTick(t time.Time, price float64, size float64) (data *DataStock, isnew bool, err error) {
+	dl := agg.Series
+	if dl.Precision <= 0 {
+		return nil, false, fmt.Errorf("unable to aggregate ticks into %q: no precision", dl.Name)
+	}
+
+	switch head := dl.Head; {
+	case head != nil && !t.Before(head.From) && t.Before(head.To):
+		// the most frequent case, the tick belongs to the head
+		data = head
+		if t.Before(agg.headOpen) {
+			data.Open, agg.headOpen = price, t
+		}
+		if !t.Before(agg.headClose) {
+			data.Close, agg.headClose = price, t
+		}
+		agg.extend(data, price, size)
+		dl.Update(data)
+
+	case head == nil || !t.Before(head.To):
+		// boundary crossing, open a new candle
+		data, isnew = agg.newCandle(t, price, size), true
+		dl.Append(data)
+		agg.headOpen, agg.headClose = t, t
+
+	default:
+		// late tick, in an existing candle
+		for _, candle := range dl.Window(timeline.TimeSlice{From: t, To: t}) {
+			if !t.Before(candle.From) && t.Before(candle.To) {
+				data = candle
+			}
+		}
+		if data != nil {
+			agg.extend(data, price, size)
+			dl.Update(data)
+			break
+		}
+
+		// or in a missing candle
+		data, isnew = agg.newCandle(t, price, size), true
+		for _, candle := range dl.Window(data.TimeSlice) {
+			if candle.From.Before(data.To) && data.From.Before(candle.To) {
+				return nil, false, fmt.Errorf("unable to insert the candle of the tick at %s into %q: it overlaps %s", t, dl.Name, candle.TimeSlice)
+			}
+		}
+		dl.Insert(data)
+	}
+
+	if agg.NotifyChange != nil {
+		agg.NotifyChange(data, isnew)
+	}
+	return data, isnew, nil
+}
+
+// candleFrom returns the start of the candle of a tick at t, aligned every Precision on the start of the head,
+// or truncated to the precision since the zero time if the DataList is empty.
+func (agg *Aggregator) candleFrom(t time.Time) time.Time {
+	precision := agg.Series.Precision
+	head := agg.Series.Head
+	if head == nil {
+		return t.Truncate(precision)
+	}
+	d := t.Sub(head.From)
+	n := d / precision
+	if d%precision < 0 {
+		n--
+	}
+	return head.From.Add(n * precision)
+}
+
+// newCandle returns the candle of the single tick at t
+func (agg *Aggregator) newCandle(t time.Time, price float64, size float64) *DataStock {
+	data := new(DataStock)
+	data.TimeSlice = timeline.MakeTimeSlice(agg.candleFrom(t), agg.Series.Precision)
+	data.Open, data.High, data.Low, data.Close = price, price, price, price
+	data.Volume = size
+	return data
+}
+
+// extend aggregates the price and the size of a tick into data
+func (agg *Aggregator) extend(data *DataStock, price float64, size float64) {
+	data.High = max(data.High, price)
+	data.Low = minNotZero(data.Low, price)
+	data.Volume += size
+}
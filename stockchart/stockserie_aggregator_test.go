@@ -0,0 +1,141 @@
+package stockchart
+
+import (
+	"testing"
+	"time"
+
+	timeline "github.com/larry868/timeline/v2"
+)
+
+func TestAggregator(t *testing.T) {
+	series := DataList{Name: "TEST", Precision: time.Minute}
+	agg := NewAggregator(&series)
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	at := func(s int) time.Time { return start.Add(time.Duration(s) * time.Second) }
+
+	var notified int
+	agg.NotifyChange = func(data *DataStock, isnew bool) { notified++ }
+
+	ticks := []struct {
+		s           int
+		price, size float64
+	}{
+		{5, 100, 1}, {20, 104, 2}, {30, 98, 1}, {59, 101, 3}, // 10:00
+		{60, 102, 1}, {90, 103, 1}, // 10:01, on the boundary
+		{185, 99, 2}, // 10:03, no tick at 10:02
+	}
+	for _, tick := range ticks {
+		if _, _, err := agg.AddTick(at(tick.s), tick.price, tick.size); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if series.Size() != 3 || notified != len(ticks) {
+		t.Fatalf("AddTick fails: want 3 candles and %d notifications, get %d and %d", len(ticks), series.Size(), notified)
+	}
+	first := series.Tail
+	if first.Open != 100 || first.High != 104 || first.Low != 98 || first.Close != 101 || first.Volume != 7 || !first.From.Equal(start) || !first.To.Equal(at(60)) {
+		t.Errorf("AddTick fails: get %s", first)
+	}
+	if second := first.Next; second.Open != 102 || second.Close != 103 || second.Volume != 2 {
+		t.Errorf("AddTick boundary fails: get %s", second)
+	}
+
+	// late tick in the head, older than its close
+	data, isnew, _ := agg.AddTick(at(182), 97, 1)
+	if data != series.Head || isnew || data.Open != 97 || data.Close != 99 || data.Low != 97 || data.Volume != 3 {
+		t.Errorf("AddTick late in head fails: get %s", data)
+	}
+
+	// late tick in an older candle, open and close are unchanged
+	data, isnew, _ = agg.AddTick(at(10), 110, 5)
+	if data != first || isnew || data.Open != 100 || data.Close != 101 || data.High != 110 || data.Volume != 12 {
+		t.Errorf("AddTick late fails: get %s", data)
+	}
+	if get := series.DataRange(nil, 0).High(); get != 110 {
+		t.Errorf("AddTick late fails: want the datarange high 110, get %v", get)
+	}
+
+	// late tick in a missing candle, and before the tail
+	data, isnew, _ = agg.AddTick(at(150), 95, 1)
+	if !isnew || data.Prev != first.Next || data.Next != series.Head || !data.From.Equal(at(120)) {
+		t.Errorf("AddTick late missing fails: get %s", data)
+	}
+	data, isnew, _ = agg.AddTick(at(-30), 90, 1)
+	if !isnew || series.Tail != data || series.Size() != 5 || !series.index.sorted {
+		t.Errorf("AddTick late before tail fails: get %s", series)
+	}
+
+	// no precision
+	if _, _, err := NewAggregator(&DataList{}).AddTick(start, 1, 1); err == nil {
+		t.Errorf("AddTick without precision fails: want an error")
+	}
+}
+
+func TestAggregatorAlignment(t *testing.T) {
+	// daily candles opening at 09:30 UTC
+	series := DataList{Name: "TEST", Precision: 24 * time.Hour}
+	open := time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC)
+	for _, day := range []int{0, 1, 3} {
+		series.Append(&DataStock{TimeSlice: timeline.MakeTimeSlice(open.AddDate(0, 0, day), 24*time.Hour), Open: 100, High: 101, Low: 99, Close: 100, Volume: 1})
+	}
+	agg := NewAggregator(&series)
+	var notified int
+	agg.NotifyChange = func(data *DataStock, isnew bool) { notified++ }
+
+	// late tick in the missing candle of the 3rd of January
+	data, isnew, err := agg.AddTick(time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC), 98, 1)
+	if err != nil || !isnew || !data.From.Equal(open.AddDate(0, 0, 2)) {
+		t.Fatalf("AddTick late fails: get %s, %v", data, err)
+	}
+	if found, _, _ := agg.AddTick(time.Date(2024, 1, 4, 8, 0, 0, 0, time.UTC), 97, 1); found != data || data.Low != 97 {
+		t.Fatalf("AddTick late fails: get %s", found)
+	}
+	if series.Size() != 4 || notified != 2 {
+		t.Errorf("AddTick late fails: %d candles, %d notifications", series.Size(), notified)
+	}
+
+	// a new head keeps the alignment
+	if data, _, _ := agg.AddTick(time.Date(2024, 1, 7, 12, 0, 0, 0, time.UTC), 98, 1); !data.From.Equal(open.AddDate(0, 0, 6)) {
+		t.Errorf("AddTick new head fails: get %s", data)
+	}
+
+	// a late tick whose candle overlaps an irregular candle is rejected
+	irregular := DataList{Name: "TEST", Precision: time.Hour}
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	irregular.Append(&DataStock{TimeSlice: timeline.MakeTimeSlice(start, time.Hour), Open: 1, High: 1, Low: 1, Close: 1})
+	irregular.Append(&DataStock{TimeSlice: timeline.MakeTimeSlice(start.Add(105*time.Minute), time.Hour), Open: 1, High: 1, Low: 1, Close: 1})
+	agg = NewAggregator(&irregular)
+	notified = 0
+	agg.NotifyChange = func(data *DataStock, isnew bool) { notified++ }
+	if data, isnew, err := agg.AddTick(start.Add(70*time.Minute), 2, 1); err == nil || data != nil || isnew || notified != 0 || irregular.Size() != 2 {
+		t.Errorf("AddTick overlapping fails: get %s, %v, %v", data, isnew, err)
+	}
+}
+
+func TestChartAggregator(t *testing.T) {
+	chart, h := newTestChart(testSeriesPrecision(30, time.Minute))
+	resetRecorders(chart, h)
+	agg := chart.NewAggregator()
+
+	// a tick in the head redraws the chart layer only
+	head := chart.MainSeries.Head
+	agg.AddTick(head.From.Add(time.Second), head.High+10, 1)
+	if head.High != chart.MainSeries.DataRange(nil, 0).High() {
+		t.Errorf("chart AddTick fails: get %s", head)
+	}
+	h.flushFrames()
+	if chart.recorder(4).Count("ClearRect") != 1 || len(chart.recorder(2).Calls) != 0 {
+		t.Errorf("chart AddTick fails: wrong layers redrawn")
+	}
+
+	// a new candle extends the time range
+	resetRecorders(chart, h)
+	data, _, _ := agg.AddTick(head.To.Add(time.Second), 100, 1)
+	if chart.MainSeries.Head != data || !chart.timeRange.To.Equal(data.To) {
+		t.Errorf("chart AddTick new fails: get %s", chart.timeRange)
+	}
+	h.flushFrames()
+	if chart.recorder(2).Count("ClearRect") != 1 {
+		t.Errorf("chart AddTick new fails: timeselector not redrawn")
+	}
+}
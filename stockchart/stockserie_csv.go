@@ -0,0 +1,376 @@
+package stockchart
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	timeline "github.com/larry868/timeline/v2"
+)
+
+// TimeFormat is the format of the timestamps in a CSV file
+type TimeFormat int
+
+const (
+	TimeAuto      TimeFormat = iota // detected when reading: unix seconds or milliseconds for numbers, RFC3339 or a date otherwise. RFC3339 when writing
+	TimeUnix                        // unix time in seconds
+	TimeUnixMilli                   // unix time in milliseconds
+	TimeRFC3339                     // RFC3339, with nanoseconds if any
+)
+
+// CSVColumns are the positions of the columns in a CSV file, starting at 0. Volume is optional and -1 if missing.
+type CSVColumns struct {
+	Time, Open, High, Low, Close, Volume int
+}
+
+// DefaultCSVColumns is the usual order of OHLCV columns
+var DefaultCSVColumns = CSVColumns{Time: 0, Open: 1, High: 2, Low: 3, Close: 4, Volume: 5}
+
+// CSVFormat defines the format of a CSV file of data points, the zero value is a usual comma separated file
+type CSVFormat struct {
+	Delimiter  rune           // the field delimiter, a comma if zero
+	Columns    *CSVColumns    // the positions of the columns. If nil, mapped from the header names if any, DefaultCSVColumns otherwise
+	TimeFormat TimeFormat     // the format of the timestamps
+	TimeLayout string         // if not empty, the layout of the timestamps like time.Parse, overrides TimeFormat
+	Location   *time.Location // the time zone of timestamps without zone, UTC if nil
+	Precision  time.Duration  // the duration of the data points, the smallest step between timestamps if zero
+	NoHeader   bool           // do not write the header
+}
+
+// CSVError is an invalid line of a CSV file
+type CSVError struct {
+	Line int   // the line number, starting at 1
+	Err  error // the error
+}
+
+func (e *CSVError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+func (e *CSVError) Unwrap() error {
+	return e.Err
+}
+
+// csvHeaderNames are the accepted names of the columns in a header, lowercase
+var csvHeaderNames = map[string]string{
+	"time": "time", "timestamp": "time", "date": "time", "datetime": "time", "t": "time",
+	"open": "open", "o": "open",
+	"high": "high", "h": "high",
+	"low": "low", "l": "low",
+	"close": "close", "c": "close",
+	"volume": "volume", "vol": "volume", "v": "volume"}
+
+// columnsFromHeader maps the columns from the header names, returns false if required columns are missing
+func columnsFromHeader(header []string) (CSVColumns, bool) {
+	found := map[string]int{}
+	for i, name := range header {
+		if col, ok := csvHeaderNames[strings.ToLower(strings.TrimSpace(name))]; ok {
+			if _, dup := found[col]; !dup {
+				found[col] = i
+			}
+		}
+	}
+	cols := CSVColumns{Volume: -1}
+	for name, pcol := range map[string]*int{"time": &cols.Time, "open": &cols.Open, "high": &cols.High, "low": &cols.Low, "close": &cols.Close, "volume": &cols.Volume} {
+		i, ok := found[name]
+		if !ok && name != "volume" {
+			return cols, false
+		}
+		if ok {
+			*pcol = i
+		}
+	}
+	return cols, true
+}
+
+// parseTime parses a timestamp according to the format
+func (format CSVFormat) parseTime(field string) (time.Time, error) {
+	loc := format.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	field = strings.TrimSpace(field)
+	if format.TimeLayout != "" {
+		return time.ParseInLocation(format.TimeLayout, field, loc)
+	}
+	switch format.TimeFormat {
+	case TimeUnix, TimeUnixMilli:
+		n, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid unix time %q", field)
+		}
+		if format.TimeFormat == TimeUnixMilli {
+			return time.UnixMilli(n).In(loc), nil
+		}
+		return time.Unix(n, 0).In(loc), nil
+	case TimeRFC3339:
+		return time.Parse(time.RFC3339Nano, field)
+	}
+
+	// auto detection, milliseconds are 13 digits numbers
+	if n, err := strconv.ParseInt(field, 10, 64); err == nil {
+		if n > 1e11 || n < -1e11 {
+			return time.UnixMilli(n).In(loc), nil
+		}
+		return time.Unix(n, 0).In(loc), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, field); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{time.DateTime, "2006-01-02 15:04", time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, field, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", field)
+}
+
+// formatTime formats a timestamp according to the format
+func (format CSVFormat) formatTime(t time.Time) string {
+	if format.Location != nil {
+		t = t.In(format.Location)
+	}
+	if format.TimeLayout != "" {
+		return t.Format(format.TimeLayout)
+	}
+	switch format.TimeFormat {
+	case TimeUnix:
+		return strconv.FormatInt(t.Unix(), 10)
+	case TimeUnixMilli:
+		return strconv.FormatInt(t.UnixMilli(), 10)
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// csvRow is a valid line of a CSV file
+type csvRow struct {
+	line int
+	at   time.Time
+	data *DataStock
+}
+
+// parseRow parses and validates a record
+func (format CSVFormat) parseRow(record []string, cols CSVColumns) (row csvRow, err error) {
+	field := func(i int) (string, error) {
+		if i < 0 || i >= len(record) {
+			return "", fmt.Errorf("missing column %d", i+1)
+		}
+		return record[i], nil
+	}
+	number := func(i int, name string) (float64, error) {
+		f, err := field(i)
+		if err != nil {
+			return 0, err
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return 0, fmt.Errorf("invalid %s %q", name, f)
+		}
+		return v, nil
+	}
+
+	f, err := field(cols.Time)
+	if err != nil {
+		return row, err
+	}
+	if row.at, err = format.parseTime(f); err != nil {
+		return row, err
+	}
+	row.data = new(DataStock)
+	if row.data.Open, err = number(cols.Open, "open"); err != nil {
+		return row, err
+	}
+	if row.data.High, err = number(cols.High, "high"); err != nil {
+		return row, err
+	}
+	if row.data.Low, err = number(cols.Low, "low"); err != nil {
+		return row, err
+	}
+	if row.data.Close, err = number(cols.Close, "close"); err != nil {
+		return row, err
+	}
+	if cols.Volume >= 0 {
+		if row.data.Volume, err = number(cols.Volume, "volume"); err != nil {
+			return row, err
+		}
+	}
+
+	d := row.data
+	switch {
+	case d.High < max(d.Open, d.Close, d.Low):
+		return row, fmt.Errorf("high %v lower than open, close or low", d.High)
+	case d.Low > min(d.Open, d.Close):
+		return row, fmt.Errorf("low %v higher than open or close", d.Low)
+	case d.Volume < 0:
+		return row, fmt.Errorf("negative volume %v", d.Volume)
+	}
+	return row, nil
+}
+
+// ReadCSV reads OHLCV data points from a CSV file. The timestamp of a line is the start of its data point.
+//
+// The first line is a header if its timestamp is not valid. If format.Columns is nil, columns are mapped from the header names,
+// like time, open, high, low, close and volume, or in this default order without header.
+//
+// Lines must be in chronological order, and start after the end of the previous data point if format.Precision is set. Invalid lines are skipped and reported with their line number in the returned error,
+// joining a CSVError per line, while valid lines are returned in the DataList.
+func ReadCSV(r io.Reader, name string, format CSVFormat) (DataList, error) {
+	dl := DataList{Name: name, Precision: format.Precision}
+
+	reader := csv.NewReader(r)
+	if format.Delimiter != 0 {
+		reader.Comma = format.Delimiter
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	var errs []error
+	var rows []csvRow
+	cols := DefaultCSVColumns
+	if format.Columns != nil {
+		cols = *format.Columns
+	}
+	first := true
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			var perr *csv.ParseError
+			if errors.As(err, &perr) {
+				line = perr.Line
+				err = perr.Err
+			}
+			errs = append(errs, &CSVError{Line: line, Err: err})
+			continue
+		}
+
+		// header detection
+		if first {
+			first = false
+			header := append([]string(nil), record...)
+			hcols := cols
+			if format.Columns == nil {
+				if mapped, ok := columnsFromHeader(header); ok {
+					hcols = mapped
+				}
+			}
+			if hcols.Time >= len(header) {
+				hcols.Time = cols.Time
+			}
+			if hcols.Time < len(header) {
+				if _, err := format.parseTime(header[hcols.Time]); err != nil {
+					cols = hcols
+					continue
+				}
+			}
+		}
+
+		row, err := format.parseRow(record, cols)
+		if err != nil {
+			errs = append(errs, &CSVError{Line: line, Err: err})
+			continue
+		}
+		if n := len(rows); n > 0 && !row.at.After(rows[n-1].at) {
+			errs = append(errs, &CSVError{Line: line, Err: fmt.Errorf("timestamp %s not after the previous one at line %d", row.at, rows[n-1].line)})
+			continue
+		}
+		if n := len(rows); n > 0 && dl.Precision > 0 && row.at.Before(rows[n-1].at.Add(dl.Precision)) {
+			errs = append(errs, &CSVError{Line: line, Err: fmt.Errorf("timestamp %s overlaps the data point at line %d, lasting %s", row.at, rows[n-1].line, dl.Precision)})
+			continue
+		}
+		row.line = line
+		rows = append(rows, row)
+	}
+
+	// precision
+	if dl.Precision <= 0 {
+		if len(rows) < 2 {
+			if len(rows) == 1 {
+				errs = append(errs, fmt.Errorf("unable to deduce the precision from a single line"))
+			}
+			return dl, errors.Join(errs...)
+		}
+		// the smallest step, the others being gaps like week-ends
+		dl.Precision = rows[1].at.Sub(rows[0].at)
+		for i := 2; i < len(rows); i++ {
+			dl.Precision = min(dl.Precision, rows[i].at.Sub(rows[i-1].at))
+		}
+	}
+
+	for _, row := range rows {
+		row.data.TimeSlice = timeline.MakeTimeSlice(row.at, dl.Precision)
+		dl.Append(row.data)
+	}
+	return dl, errors.Join(errs...)
+}
+
+// WriteCSV writes the data points of dl overlapping ts, or all data points if ts is nil, in a CSV file.
+// The timestamp of a line is the start of its data point. If format.Columns is nil, DefaultCSVColumns are used.
+//
+// A header with the names time, open, high, low, close and volume is written first, unless format.NoHeader.
+func (dl DataList) WriteCSV(w io.Writer, ts *timeline.TimeSlice, format CSVFormat) error {
+	writer := csv.NewWriter(w)
+	if format.Delimiter != 0 {
+		writer.Comma = format.Delimiter
+	}
+	cols := DefaultCSVColumns
+	if format.Columns != nil {
+		cols = *format.Columns
+	}
+	nbcols := max(cols.Time, cols.Open, cols.High, cols.Low, cols.Close, cols.Volume) + 1
+	record := make([]string, nbcols)
+	fill := func(fields map[int]string) {
+		for i := range record {
+			record[i] = ""
+		}
+		for i, f := range fields {
+			if i >= 0 {
+				record[i] = f
+			}
+		}
+	}
+	number := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+
+	if !format.NoHeader {
+		fill(map[int]string{cols.Time: "time", cols.Open: "open", cols.High: "high", cols.Low: "low", cols.Close: "close", cols.Volume: "volume"})
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	for _, item := range dl.window(ts) {
+		fill(map[int]string{
+			cols.Time:   format.formatTime(item.From),
+			cols.Open:   number(item.Open),
+			cols.High:   number(item.High),
+			cols.Low:    number(item.Low),
+			cols.Close:  number(item.Close),
+			cols.Volume: number(item.Volume)})
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteCSV writes the data points of the MainSeries in a CSV file, only the ones in the selected time slice if visibleOnly.
+// See DataList.WriteCSV.
+func (pchart *StockChart) WriteCSV(w io.Writer, visibleOnly bool, format CSVFormat) error {
+	var ts *timeline.TimeSlice
+	if visibleOnly {
+		sel := pchart.selectedTimeSlice
+		ts = &sel
+	}
+	return pchart.MainSeries.WriteCSV(w, ts, format)
+}
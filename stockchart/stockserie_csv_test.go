@@ -0,0 +1,135 @@
+package stockchart
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	timeline "github.com/larry868/timeline/v2"
+)
+
+func TestReadCSV(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		csv    string
+		format CSVFormat
+	}{
+		{"header rfc3339", "time,open,high,low,close,volume\n2024-01-01T00:00:00Z,100,104,99,103,10\n2024-01-01T01:00:00Z,103,105,101,102,20\n", CSVFormat{}},
+		{"unix seconds", "1704067200,100,104,99,103,10\n1704070800,103,105,101,102,20\n", CSVFormat{}},
+		{"unix millis", "1704067200000;100;104;99;103;10\n1704070800000;103;105;101;102;20\n", CSVFormat{Delimiter: ';'}},
+		{"mapped header", "Date,Volume,Close,High,Low,Open\n2024-01-01 00:00:00,10,103,104,99,100\n2024-01-01 01:00:00,20,102,105,101,103\n", CSVFormat{}},
+		{"columns and layout", "x,01/01/2024 00h,100,104,99,103,10\nx,01/01/2024 01h,103,105,101,102,20\n",
+			CSVFormat{Columns: &CSVColumns{Time: 1, Open: 2, High: 3, Low: 4, Close: 5, Volume: 6}, TimeLayout: "01/02/2006 15h"}},
+	}
+	for _, tc := range tests {
+		dl, err := ReadCSV(strings.NewReader(tc.csv), "TEST", tc.format)
+		if err != nil {
+			t.Errorf("%s: ReadCSV fails: %s", tc.name, err)
+			continue
+		}
+		if dl.Size() != 2 || dl.Precision != time.Hour || !dl.Tail.From.Equal(start) || !dl.Head.To.Equal(start.Add(2*time.Hour)) {
+			t.Errorf("%s: ReadCSV fails: get %s", tc.name, dl)
+			continue
+		}
+		if d := dl.Tail; d.Open != 100 || d.High != 104 || d.Low != 99 || d.Close != 103 || d.Volume != 10 || dl.Head.Volume != 20 {
+			t.Errorf("%s: ReadCSV fails: get %s", tc.name, d)
+		}
+	}
+}
+
+func TestReadCSVErrors(t *testing.T) {
+	csv := `time,open,high,low,close
+2024-01-01T00:00:00Z,100,104,99,103
+2024-01-01T01:00:00Z,abc,105,101,102
+2024-01-01T02:00:00Z,103,100,101,102
+2024-01-01T03:00:00Z,103,105,101
+2024-01-01T04:00:00Z,103,105,101,102
+2024-01-01T03:30:00Z,103,105,101,102
+yesterday,103,105,101,102
+`
+	dl, err := ReadCSV(strings.NewReader(csv), "TEST", CSVFormat{Precision: time.Hour})
+	if dl.Size() != 2 || dl.Head.Volume != 0 {
+		t.Errorf("ReadCSV fails: want the 2 valid lines, get %s", dl)
+	}
+	var lines []int
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var csverr *CSVError
+		if !errors.As(e, &csverr) {
+			t.Fatalf("ReadCSV fails: unexpected error %s", e)
+		}
+		lines = append(lines, csverr.Line)
+	}
+	if want := []int{3, 4, 5, 7, 8}; len(lines) != len(want) || lines[0] != 3 || lines[4] != 8 {
+		t.Errorf("ReadCSV fails: want errors at lines %v, get %v: %s", want, lines, err)
+	}
+	if !strings.Contains(err.Error(), "line 4: high 100") {
+		t.Errorf("ReadCSV fails: get %s", err)
+	}
+
+	// a data point starting before the end of the previous one
+	if dl, err := ReadCSV(strings.NewReader("1704067200,100,104,99,103,10\n1704069000,103,105,101,102,20\n1704070800,103,105,101,102,30\n"), "TEST", CSVFormat{Precision: time.Hour}); dl.Size() != 2 || dl.Head.Volume != 30 || !strings.Contains(fmt.Sprint(err), "line 2: timestamp") {
+		t.Errorf("ReadCSV overlapping fails: get %s, %v", dl, err)
+	}
+
+	// a single line without precision
+	if _, err := ReadCSV(strings.NewReader("1704067200,100,104,99,103,10\n"), "TEST", CSVFormat{}); err == nil {
+		t.Errorf("ReadCSV fails: want an error without precision")
+	}
+}
+
+func TestReadCSVPrecision(t *testing.T) {
+	// daily candles from Friday, a week-end, then Monday to Wednesday
+	csv := "2024-01-05,100,104,99,103,10\n2024-01-08,103,105,101,102,20\n2024-01-09,102,106,100,105,30\n2024-01-10,105,107,104,106,40\n"
+	dl, err := ReadCSV(strings.NewReader(csv), "TEST", CSVFormat{TimeLayout: "2006-01-02"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dl.Size() != 4 || dl.Precision != 24*time.Hour {
+		t.Fatalf("ReadCSV fails: get %s", dl)
+	}
+	for d := dl.Tail; d != nil; d = d.Next {
+		if d.Duration().Duration != 24*time.Hour || (d.Next != nil && d.Next.From.Before(d.To)) {
+			t.Errorf("ReadCSV fails: get %s", d)
+		}
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	series := testSeries(24)
+	var buf bytes.Buffer
+	if err := series.WriteCSV(&buf, nil, CSVFormat{}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 25 || lines[0] != "time,open,high,low,close,volume" || lines[1] != "2024-01-01T00:00:00Z,100,105,99,104,100" {
+		t.Fatalf("WriteCSV fails: get %q", lines[:2])
+	}
+
+	// round trip
+	for _, format := range []CSVFormat{{}, {TimeFormat: TimeUnixMilli, Delimiter: '\t'}, {TimeFormat: TimeUnix, NoHeader: true, Columns: &CSVColumns{Time: 5, Open: 4, High: 3, Low: 2, Close: 1, Volume: 0}}} {
+		buf.Reset()
+		series.WriteCSV(&buf, nil, format)
+		dl, err := ReadCSV(&buf, "TEST", format)
+		if err != nil {
+			t.Fatalf("round trip %v fails: %s", format, err)
+		}
+		for a, b := series.Tail, dl.Tail; a != nil; a, b = a.Next, b.Next {
+			if b == nil || a.TimeSlice.Compare(b.TimeSlice) != timeline.EQUAL || a.Open != b.Open || a.High != b.High || a.Low != b.Low || a.Close != b.Close || a.Volume != b.Volume {
+				t.Fatalf("round trip %v fails: want %s, get %s", format, a, b)
+			}
+		}
+	}
+
+	// the visible slice of the chart
+	chart, _ := newTestChart(series)
+	chart.DoChangeSelTimeSlice(timeline.MakeTimeSlice(series.Tail.From.Add(2*time.Hour+time.Minute), 3*time.Hour), false)
+	buf.Reset()
+	chart.WriteCSV(&buf, true, CSVFormat{NoHeader: true})
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 4 || !strings.HasPrefix(lines[0], "2024-01-01T02:00:00Z,") {
+		t.Errorf("chart WriteCSV fails: get %q", lines)
+	}
+}
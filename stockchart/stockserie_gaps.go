@@ -0,0 +1,119 @@
+package stockchart
+
+import (
+	"github.com/larry868/rgb"
+	timeline "github.com/larry868/timeline/v2"
+)
+
+// Gap is a space between two consecutive data points of a DataList, where at least one data point of the precision is missing.
+// Without precision, any space is a gap.
+type Gap struct {
+	timeline.TimeSlice            // from the end of the data point before, to the start of the data point after
+	Missing            int        // the number of missing data points of the precision, 0 without precision
+	Before             *DataStock // the data point before the gap
+	After              *DataStock // the data point after the gap
+}
+
+// isGap returns true if there's a gap between prev and next, next following prev
+func (dl DataList) isGap(prev *DataStock, next *DataStock) bool {
+	space := next.From.Sub(prev.To)
+	if dl.Precision > 0 {
+		return space >= dl.Precision
+	}
+	return space > 0
+}
+
+// Gaps returns the gaps between the data points overlapping ts, or between all data points if ts is nil.
+// Data points must be in chronological order.
+func (dl DataList) Gaps(ts *timeline.TimeSlice) (gaps []Gap) {
+	items := dl.window(ts)
+	for i := 1; i < len(items); i++ {
+		prev, next := items[i-1], items[i]
+		if !dl.isGap(prev, next) {
+			continue
+		}
+		gap := Gap{TimeSlice: timeline.TimeSlice{From: prev.To, To: next.From}, Before: prev, After: next}
+		if dl.Precision > 0 {
+			gap.Missing = int(next.From.Sub(prev.To) / dl.Precision)
+		}
+		gaps = append(gaps, gap)
+	}
+	return gaps
+}
+
+// Gaps returns the gaps of the MainSeries, only the ones in the selected time slice if visibleOnly.
+func (pchart *StockChart) Gaps(visibleOnly bool) []Gap {
+	var ts *timeline.TimeSlice
+	if visibleOnly {
+		sel := pchart.selectedTimeSlice
+		ts = &sel
+	}
+	return pchart.MainSeries.Gaps(ts)
+}
+
+// SetGapStyle changes how the gaps of the MainSeries are rendered by the candles of the chart and the series of the navbar.
+// The chart is redrawn at the next frame.
+func (pchart *StockChart) SetGapStyle(style GapStyle) {
+	if pchart.mainCandles != nil {
+		pchart.mainCandles.GapStyle = style
+	}
+	if pchart.navSeries != nil {
+		pchart.navSeries.GapStyle = style
+	}
+	pchart.Redraw()
+}
+
+// GapStyle defines how drawings render the gaps of their series
+type GapStyle int
+
+const (
+	GS_None   GapStyle = 0 // nothing special, gaps are empty space between candles and bridged by the series line
+	GS_Break  GapStyle = 1 // a dashed vertical line marks the gap between candles, the series line is interrupted
+	GS_Band   GapStyle = 2 // a shaded band covers the gap
+	GS_Bridge GapStyle = 3 // a dashed line joins the close before the gap to the open after it
+)
+
+// isAxisGap returns true if there's a gap between prev and next taking space along the x axis.
+// With a trading calendar, gaps within non-trading periods are skipped.
+func (drawing *Drawing) isAxisGap(prev *DataStock, next *DataStock) bool {
+	return drawing.series.isGap(prev, next) && drawing.xSpan(prev.To, next.From) > 0
+}
+
+// drawGaps renders the gaps between items according to style, behind the data points.
+// yprice returns the y position of a price, for bridges.
+func (drawing *Drawing) drawGaps(items []*DataStock, style GapStyle, color rgb.Color, yprice func(price float64) float64) {
+	if style == GS_None || len(items) < 2 {
+		return
+	}
+	top := float64(drawing.drawArea.O.Y)
+	bottom := float64(drawing.drawArea.End().Y)
+	drawing.Ctx2D.SetLineWidth(1)
+	for i := 1; i < len(items); i++ {
+		prev, next := items[i-1], items[i]
+		if prev.IsInfinite() || next.IsInfinite() || !drawing.isAxisGap(prev, next) {
+			continue
+		}
+		xfrom, xto := drawing.xTime(prev.To), drawing.xTime(next.From)
+		switch style {
+		case GS_Band:
+			drawing.Ctx2D.SetFillStyle(color.Opacify(0.15))
+			drawing.Ctx2D.FillRect(xfrom, top, fmax(1, xto-xfrom), bottom-top)
+		case GS_Break:
+			xmiddle := drawing.xTime(prev.To.Add(next.From.Sub(prev.To) / 2))
+			drawing.Ctx2D.SetStrokeStyle(color)
+			drawing.Ctx2D.SetLineDash([]float64{3, 3})
+			drawing.Ctx2D.BeginPath()
+			drawing.Ctx2D.MoveTo(xmiddle-0.5, top)
+			drawing.Ctx2D.LineTo(xmiddle-0.5, bottom)
+			drawing.Ctx2D.Stroke()
+		case GS_Bridge:
+			drawing.Ctx2D.SetStrokeStyle(color)
+			drawing.Ctx2D.SetLineDash([]float64{3, 3})
+			drawing.Ctx2D.BeginPath()
+			drawing.Ctx2D.MoveTo(xfrom, yprice(prev.Close))
+			drawing.Ctx2D.LineTo(xto, yprice(next.Open))
+			drawing.Ctx2D.Stroke()
+		}
+	}
+	drawing.Ctx2D.SetLineDash([]float64{})
+}
@@ -0,0 +1,101 @@
+package stockchart
+
+import (
+	"testing"
+	"time"
+)
+
+// testSeriesGaps returns 48 hourly candles without the ones from 10:00 to 15:00 and from 30:00 to 32:00
+func testSeriesGaps() DataList {
+	full := testSeries(48)
+	series := DataList{Name: full.Name, Precision: full.Precision}
+	for i, item := range full.window(nil) {
+		if (i >= 10 && i < 16) || (i >= 30 && i < 33) {
+			continue
+		}
+		series.Append(item)
+	}
+	return series
+}
+
+func TestGaps(t *testing.T) {
+	series := testSeriesGaps()
+	start := series.Tail.From
+	gaps := series.Gaps(nil)
+	if len(gaps) != 2 {
+		t.Fatalf("Gaps fails: want 2 gaps, get %v", gaps)
+	}
+	if g := gaps[0]; !g.From.Equal(start.Add(10*time.Hour)) || !g.To.Equal(start.Add(16*time.Hour)) || g.Missing != 6 || g.Before.To != g.From || g.After.From != g.To {
+		t.Errorf("Gaps fails: get %s missing:%d", g.TimeSlice, g.Missing)
+	}
+	if gaps[1].Missing != 3 {
+		t.Errorf("Gaps fails: want 3 missing, get %d", gaps[1].Missing)
+	}
+
+	// within a time slice
+	chart, _ := newTestChart(series)
+	if get := chart.Gaps(false); len(get) != 2 {
+		t.Errorf("chart Gaps fails: get %v", get)
+	}
+	sel := chart.selectedTimeSlice
+	sel.From = start.Add(20 * time.Hour)
+	chart.DoChangeSelTimeSlice(sel, false)
+	if get := chart.Gaps(true); len(get) != 1 || get[0].Missing != 3 {
+		t.Errorf("chart Gaps visible fails: get %v", get)
+	}
+
+	// a space shorter than the precision is not a gap
+	series = testSeries(3)
+	series.Head.From = series.Head.From.Add(30 * time.Minute)
+	if get := series.Gaps(nil); len(get) != 0 {
+		t.Errorf("Gaps fails: get %v", get)
+	}
+	series.Precision = 0
+	if get := series.Gaps(nil); len(get) != 1 || get[0].Missing != 0 {
+		t.Errorf("Gaps without precision fails: get %v", get)
+	}
+}
+
+func TestDrawGaps(t *testing.T) {
+	chart, h := newTestChart(testSeriesGaps())
+	resetRecorders(chart, h)
+
+	// the navbar line is interrupted twice
+	chart.SetGapStyle(GS_Break)
+	h.flushFrames()
+	if get := chart.recorder(1).Count("Fill"); get != 3 {
+		t.Errorf("series GS_Break fails: want 3 filled areas, get %d", get)
+	}
+	// 2 dashed lines mark the gaps between candles
+	dashes := 0
+	for _, call := range chart.recorder(4).Filter("SetLineDash") {
+		if len(call.Args[0].([]float64)) > 0 {
+			dashes++
+		}
+	}
+	if dashes != 2 {
+		t.Errorf("candles GS_Break fails: want 2 dashed lines, get %d", dashes)
+	}
+
+	// shaded bands
+	resetRecorders(chart, h)
+	chart.SetGapStyle(GS_Band)
+	h.flushFrames()
+	bands := 0
+	for _, call := range chart.recorder(4).Filter("FillRect") {
+		if call.Args[3].(float64) == float64(chart.mainCandles.drawArea.Height) {
+			bands++
+		}
+	}
+	if bands != 2 || chart.recorder(1).Count("Fill") != 1 {
+		t.Errorf("GS_Band fails: want 2 bands and a single area, get %d and %d", bands, chart.recorder(1).Count("Fill"))
+	}
+
+	// bridges
+	resetRecorders(chart, h)
+	chart.SetGapStyle(GS_Bridge)
+	h.flushFrames()
+	if get := chart.recorder(4).Count("Stroke"); get < 2 {
+		t.Errorf("GS_Bridge fails: want 2 bridges, get %d strokes", get)
+	}
+}
@@ -0,0 +1,205 @@
+package stockchart
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	timeline "github.com/larry868/timeline/v2"
+)
+
+// dataIndex is the contiguous storage of the data points of a DataList, in the order of the links from the Tail to the Head.
+// It allows lookup by time with a binary search, as long as the data points are sorted chronologically.
+type dataIndex struct {
+	items  []*DataStock // the data points, from the tail to the head
+	sorted bool         // true if From and To of items are both in chronological order
+	tree   *rangeTree   // the extremes of the items, for autoscaling
+}
+
+// isValidFor returns true if the index still corresponds to the boundaries of the DataList.
+//
+// The index is shared by the copies of a DataList. It's no longer valid for a copy which has not been updated,
+// neither if the links have been changed directly. In such cases DataList functions scan the links.
+func (idx *dataIndex) isValidFor(dl DataList) bool {
+	if idx == nil {
+		return false
+	}
+	if len(idx.items) == 0 {
+		return dl.Head == nil && dl.Tail == nil
+	}
+	return idx.items[0] == dl.Tail && idx.items[len(idx.items)-1] == dl.Head
+}
+
+// isChronological returns true if b can follow a in a sorted index
+func isChronological(a *DataStock, b *DataStock) bool {
+	return !b.From.Before(a.From) && !b.To.Before(a.To)
+}
+
+// searchTo returns the position of the first item ending at or after t, len(items) if none
+func (idx *dataIndex) searchTo(t time.Time) int {
+	return sort.Search(len(idx.items), func(i int) bool {
+		return !idx.items[i].To.Before(t)
+	})
+}
+
+// searchFrom returns the position of the first item starting after t, len(items) if none
+func (idx *dataIndex) searchFrom(t time.Time) int {
+	return sort.Search(len(idx.items), func(i int) bool {
+		return idx.items[i].From.After(t)
+	})
+}
+
+// Reindex rebuilds the index of the DataList scanning the links from the Tail to the Head.
+//
+// Append and Insert maintain the index, so Reindex is required only after changing the Next and Prev links directly.
+func (dl *DataList) Reindex() {
+	idx := &dataIndex{sorted: true}
+	item := dl.Tail
+	for item != nil {
+		if n := len(idx.items); n > 0 && !isChronological(idx.items[n-1], item) {
+			idx.sorted = false
+		}
+		idx.items = append(idx.items, item)
+		if item == dl.Head {
+			break
+		}
+		item = item.Next
+	}
+	idx.tree = newRangeTree(idx.items)
+	dl.index = idx
+}
+
+// indexAppend updates the index after newdata has been appended to the head.
+// wasValid is the validity of the index before linking newdata.
+func (dl *DataList) indexAppend(newdata *DataStock, wasValid bool) {
+	if !wasValid {
+		dl.Reindex()
+		return
+	}
+	if n := len(dl.index.items); n > 0 && !isChronological(dl.index.items[n-1], newdata) {
+		dl.index.sorted = false
+	}
+	dl.index.items = append(dl.index.items, newdata)
+	dl.index.tree.update(dl.index.items, len(dl.index.items)-1)
+}
+
+// indexInsert updates the index after newdata has been inserted.
+// wasValid is the validity of the index before linking newdata.
+func (dl *DataList) indexInsert(newdata *DataStock, wasValid bool) {
+	if !wasValid || !dl.index.sorted {
+		dl.Reindex()
+		return
+	}
+	idx := dl.index
+	pos := idx.searchTo(newdata.To)
+	idx.items = slices.Insert(idx.items, pos, newdata)
+	if (pos > 0 && !isChronological(idx.items[pos-1], newdata)) || (pos < len(idx.items)-1 && !isChronological(newdata, idx.items[pos+1])) {
+		idx.sorted = false
+	}
+	idx.tree.update(idx.items, pos)
+}
+
+// indexPrepend updates the index after the chronological olders have been linked before the tail.
+// wasValid is the validity of the index before linking olders.
+func (dl *DataList) indexPrepend(olders []*DataStock, wasValid bool) {
+	if !wasValid {
+		dl.Reindex()
+		return
+	}
+	idx := dl.index
+	if len(idx.items) > 0 && !isChronological(olders[len(olders)-1], idx.items[0]) {
+		idx.sorted = false
+	}
+	idx.items = append(slices.Clip(olders), idx.items...)
+	idx.tree = newRangeTree(idx.items)
+}
+
+// bounds returns the positions [first, last) of the items overlapping the chronological ts.
+// The index must be sorted.
+func (idx *dataIndex) bounds(ts timeline.TimeSlice) (first int, last int) {
+	first, last = 0, len(idx.items)
+	if !ts.From.IsZero() {
+		first = idx.searchTo(ts.From)
+	}
+	if !ts.To.IsZero() {
+		last = idx.searchFrom(ts.To)
+	}
+	return first, last
+}
+
+// extremes returns the data boundaries of the data points overlapping ts, or of all data points if ts is nil.
+// Uses the range tree if the DataList is indexed and sorted, otherwise scans the data points.
+func (dl DataList) extremes(ts *timeline.TimeSlice) extremes {
+	if dl.index.isValidFor(dl) {
+		if ts == nil {
+			return dl.index.tree.query(0, len(dl.index.items))
+		}
+		if dl.index.sorted && !ts.IsZero() {
+			cts := *ts
+			cts.ForceDirection(timeline.Chronological)
+			first, last := dl.index.bounds(cts)
+			return dl.index.tree.query(first, max(first, last))
+		}
+	}
+	e := noExtremes
+	for _, item := range dl.window(ts) {
+		e = e.merge(makeExtremes(item))
+	}
+	return e
+}
+
+// Update refreshes the index after the values of data have been changed in place, like the Close of the head updated by a new tick.
+// The timeslice and the links of data must be unchanged.
+func (dl *DataList) Update(data *DataStock) {
+	if !dl.index.isValidFor(*dl) {
+		dl.Reindex()
+		return
+	}
+	idx := dl.index
+	pos := len(idx.items) - 1
+	if data != dl.Head {
+		pos = -1
+		if idx.sorted {
+			pos = idx.searchTo(data.To)
+		}
+		if pos < 0 || pos >= len(idx.items) || idx.items[pos] != data {
+			pos = slices.Index(idx.items, data)
+		}
+	}
+	if pos < 0 {
+		return
+	}
+	idx.tree.update(idx.items[:pos+1], pos)
+}
+
+// Window returns the data points overlapping ts, boundaries included, from the tail to the head.
+//
+// The returned slice is shared with the DataList storage and must not be modified.
+// A zero boundary of ts is infinite. Returns nil if ts is zero or if there's no data point in the window.
+func (dl DataList) Window(ts timeline.TimeSlice) []*DataStock {
+	if ts.IsZero() {
+		return nil
+	}
+	ts.ForceDirection(timeline.Chronological)
+	if dl.index.isValidFor(dl) && dl.index.sorted {
+		first, last := dl.index.bounds(ts)
+		if first >= last {
+			return nil
+		}
+		return dl.index.items[first:last:last]
+	}
+
+	// not indexed, or not sorted, scan the links
+	var window []*DataStock
+	item := dl.Tail
+	for item != nil {
+		if (ts.From.IsZero() || !item.To.Before(ts.From)) && (ts.To.IsZero() || !item.From.After(ts.To)) {
+			window = append(window, item)
+		}
+		if item == dl.Head {
+			break
+		}
+		item = item.Next
+	}
+	return window
+}
@@ -0,0 +1,163 @@
+package stockchart
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	timeline "github.com/larry868/timeline/v2"
+)
+
+// jsonDataList is the verbose JSON form of a DataList, data points are ordered from the tail to the head
+type jsonDataList struct {
+	Name      string       `json:"name"`
+	Precision string       `json:"precision"`
+	Data      []*DataStock `json:"data"`
+}
+
+// jsonColumns are the data points of the compact JSON form of a DataList.
+// Times and durations are unix milliseconds.
+type jsonColumns struct {
+	Time      []int64   `json:"t"`
+	Duration  []int64   `json:"d,omitempty"` // only if a duration differs from the precision
+	Open      []float64 `json:"o"`
+	High      []float64 `json:"h"`
+	Low       []float64 `json:"l"`
+	Close     []float64 `json:"c"`
+	Volume    []float64 `json:"v"`
+	Label     []string  `json:"label,omitempty"` // only if a data point has a label
+	precision time.Duration
+}
+
+// jsonCompactDataList is the compact JSON form of a DataList
+type jsonCompactDataList struct {
+	Name      string      `json:"name"`
+	Precision string      `json:"precision"`
+	Columns   jsonColumns `json:"columns"`
+}
+
+// MarshalJSON returns the verbose JSON form of dl, an object with its name, its precision and the array of its data points from the tail to the head:
+//
+//	{"name":"BTCUSD","precision":"1h0m0s","data":[{"timeslice":{"From":"2024-01-01T00:00:00Z","To":"2024-01-01T01:00:00Z"},"open":100,...},...]}
+//
+// See MarshalJSONCompact for large lists.
+func (dl DataList) MarshalJSON() ([]byte, error) {
+	jdl := jsonDataList{Name: dl.Name, Precision: dl.Precision.String(), Data: dl.window(nil)}
+	if jdl.Data == nil {
+		jdl.Data = []*DataStock{}
+	}
+	return json.Marshal(jdl)
+}
+
+// MarshalJSONCompact returns the compact JSON form of dl, an object with its name, its precision and its data points in columns,
+// with times in unix milliseconds:
+//
+//	{"name":"BTCUSD","precision":"1h0m0s","columns":{"t":[1704067200000,...],"o":[100,...],"h":[...],"l":[...],"c":[...],"v":[...]}}
+//
+// The durations of data points are given in a "d" column only if one of them differs from the precision,
+// and labels in a "label" column only if one data point has a label.
+func (dl DataList) MarshalJSONCompact() ([]byte, error) {
+	items := dl.window(nil)
+	cols := jsonColumns{
+		Time:   make([]int64, len(items)),
+		Open:   make([]float64, len(items)),
+		High:   make([]float64, len(items)),
+		Low:    make([]float64, len(items)),
+		Close:  make([]float64, len(items)),
+		Volume: make([]float64, len(items))}
+	var durations []int64
+	var labels []string
+	for i, item := range items {
+		cols.Time[i] = item.From.UnixMilli()
+		cols.Open[i], cols.High[i], cols.Low[i], cols.Close[i], cols.Volume[i] = item.Open, item.High, item.Low, item.Close, item.Volume
+		if d := item.To.Sub(item.From); d != dl.Precision && durations == nil {
+			durations = make([]int64, len(items))
+			for j := 0; j < i; j++ {
+				durations[j] = items[j].To.Sub(items[j].From).Milliseconds()
+			}
+		}
+		if durations != nil {
+			durations[i] = item.To.Sub(item.From).Milliseconds()
+		}
+		if item.Label != "" && labels == nil {
+			labels = make([]string, len(items))
+		}
+		if labels != nil {
+			labels[i] = item.Label
+		}
+	}
+	cols.Duration = durations
+	cols.Label = labels
+	return json.Marshal(jsonCompactDataList{Name: dl.Name, Precision: dl.Precision.String(), Columns: cols})
+}
+
+// UnmarshalJSON decodes the verbose or the compact JSON form of a DataList, replacing the content of dl.
+// Data points must be in chronological order.
+func (dl *DataList) UnmarshalJSON(data []byte) error {
+	var probe struct {
+		Name      string          `json:"name"`
+		Precision string          `json:"precision"`
+		Data      json.RawMessage `json:"data"`
+		Columns   json.RawMessage `json:"columns"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return err
+	}
+	precision, err := time.ParseDuration(probe.Precision)
+	if probe.Precision != "" && err != nil {
+		return fmt.Errorf("unable to unmarshal the DataList %q: invalid precision %q", probe.Name, probe.Precision)
+	}
+
+	var items []*DataStock
+	switch {
+	case len(probe.Columns) > 0 && !bytes.Equal(probe.Columns, []byte("null")):
+		cols := jsonColumns{precision: precision}
+		if err := json.Unmarshal(probe.Columns, &cols); err != nil {
+			return err
+		}
+		if items, err = cols.items(); err != nil {
+			return fmt.Errorf("unable to unmarshal the DataList %q: %w", probe.Name, err)
+		}
+	case len(probe.Data) > 0:
+		if err := json.Unmarshal(probe.Data, &items); err != nil {
+			return err
+		}
+	}
+
+	result := DataList{Name: probe.Name, Precision: precision}
+	for i, item := range items {
+		if item == nil {
+			return fmt.Errorf("unable to unmarshal the DataList %q: null data point at %d", probe.Name, i)
+		}
+		if i > 0 && !item.From.After(items[i-1].From) {
+			return fmt.Errorf("unable to unmarshal the DataList %q: data point %d at %s not after the previous one", probe.Name, i, item.From)
+		}
+		result.Append(item)
+	}
+	*dl = result
+	return nil
+}
+
+// items returns the data points of the columns
+func (cols jsonColumns) items() ([]*DataStock, error) {
+	n := len(cols.Time)
+	if len(cols.Open) != n || len(cols.High) != n || len(cols.Low) != n || len(cols.Close) != n || len(cols.Volume) != n ||
+		(cols.Duration != nil && len(cols.Duration) != n) || (cols.Label != nil && len(cols.Label) != n) {
+		return nil, fmt.Errorf("columns of different lengths")
+	}
+	items := make([]*DataStock, n)
+	for i := range items {
+		d := cols.precision
+		if cols.Duration != nil {
+			d = time.Duration(cols.Duration[i]) * time.Millisecond
+		}
+		item := &DataStock{Open: cols.Open[i], High: cols.High[i], Low: cols.Low[i], Close: cols.Close[i], Volume: cols.Volume[i]}
+		item.TimeSlice = timeline.MakeTimeSlice(time.UnixMilli(cols.Time[i]).UTC(), d)
+		if cols.Label != nil {
+			item.Label = cols.Label[i]
+		}
+		items[i] = item
+	}
+	return items, nil
+}
@@ -0,0 +1,93 @@
+package stockchart
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	timeline "github.com/larry868/timeline/v2"
+)
+
+// equalDataLists returns an error message if a and b have different names, precisions or data points
+func equalDataLists(a DataList, b DataList) string {
+	if a.Name != b.Name || a.Precision != b.Precision || a.Size() != b.Size() {
+		return "different lists: " + a.String() + " " + b.String()
+	}
+	for x, y := a.Tail, b.Tail; x != nil; x, y = x.Next, y.Next {
+		if x.TimeSlice.Compare(y.TimeSlice) != timeline.EQUAL || x.Label != y.Label ||
+			x.Open != y.Open || x.High != y.High || x.Low != y.Low || x.Close != y.Close || x.Volume != y.Volume {
+			return "different data: " + x.String() + " " + y.String()
+		}
+	}
+	return ""
+}
+
+func TestDataListJSON(t *testing.T) {
+	series := testSeries(24)
+	series.Tail.Next.Label = "event"
+
+	verbose, err := json.Marshal(series)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compact, err := series.MarshalJSONCompact()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(verbose), `"precision":"1h0m0s","data":[{"timeslice":`) {
+		t.Errorf("MarshalJSON fails: get %.100s", verbose)
+	}
+	if !strings.Contains(string(compact), `"columns":{"t":[1704067200000,1704070800000,`) || strings.Contains(string(compact), `"d":`) || !strings.Contains(string(compact), `"label":["","event",""`) {
+		t.Errorf("MarshalJSONCompact fails: get %.200s", compact)
+	}
+	if len(compact) > len(verbose)/2 {
+		t.Errorf("MarshalJSONCompact fails: %d bytes, not compact compared to %d", len(compact), len(verbose))
+	}
+
+	for _, data := range [][]byte{verbose, compact} {
+		var dl DataList
+		if err := json.Unmarshal(data, &dl); err != nil {
+			t.Fatal(err)
+		}
+		if msg := equalDataLists(series, dl); msg != "" {
+			t.Errorf("UnmarshalJSON fails: %s", msg)
+		}
+		if dl.GetDataAt(dl.Head.Middle()) != dl.Head || dl.DataRange(nil, 0).High() != series.DataRange(nil, 0).High() {
+			t.Errorf("UnmarshalJSON fails: not indexed")
+		}
+	}
+
+	// a data point with its own duration
+	monthly, _ := testSeries(24*70).Resample(30*24*time.Hour, Alignment{Calendar: CalendarMonth})
+	compact, _ = monthly.MarshalJSONCompact()
+	var dl DataList
+	if err := json.Unmarshal(compact, &dl); err != nil || !strings.Contains(string(compact), `"d":[2678400000,`) {
+		t.Fatalf("UnmarshalJSON durations fails: %v %s", err, compact)
+	}
+	if msg := equalDataLists(monthly, dl); msg != "" {
+		t.Errorf("UnmarshalJSON durations fails: %s", msg)
+	}
+
+	// an empty list
+	empty, _ := json.Marshal(DataList{Name: "EMPTY"})
+	if err := json.Unmarshal(empty, &dl); err != nil || !dl.IsEmpty() || dl.Name != "EMPTY" {
+		t.Errorf("UnmarshalJSON empty fails: %v %s", err, empty)
+	}
+}
+
+func TestDataListJSONErrors(t *testing.T) {
+	for _, data := range []string{
+		`{"name":"X","precision":"1x"}`,
+		`{"name":"X","precision":"1m","columns":{"t":[1,2],"o":[1],"h":[1,2],"l":[1,2],"c":[1,2],"v":[1,2]}}`,
+		`{"name":"X","precision":"1m","columns":{"t":[60000,0],"o":[1,1],"h":[1,2],"l":[1,2],"c":[1,2],"v":[1,2]}}`,
+		`{"name":"X","precision":"1m","data":[null]}`,
+	} {
+		dl := testSeries(2)
+		if err := json.Unmarshal([]byte(data), &dl); err == nil {
+			t.Errorf("UnmarshalJSON %s fails: want an error", data)
+		} else if dl.Size() != 2 {
+			t.Errorf("UnmarshalJSON %s fails: the list has changed", data)
+		}
+	}
+}
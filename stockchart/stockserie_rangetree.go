@@ -0,0 +1,96 @@
+package stockchart
+
+import "math"
+
+// extremes are the data boundaries of a set of data points.
+// Like DataRange, zero values are ignored for the lows.
+type extremes struct {
+	low, high   float64 // lowest Low and highest High
+	vlow, vhigh float64 // lowest and highest Volume
+}
+
+// noExtremes is the neutral value of extremes, for an empty set of data points
+var noExtremes = extremes{high: math.Inf(-1), vhigh: math.Inf(-1)}
+
+func makeExtremes(data *DataStock) extremes {
+	return extremes{low: data.Low, high: data.High, vlow: data.Volume, vhigh: data.Volume}
+}
+
+// minNotZero returns the lowest of a and b, ignoring zero values
+func minNotZero(a float64, b float64) float64 {
+	if a == 0 {
+		return b
+	}
+	if b == 0 {
+		return a
+	}
+	return math.Min(a, b)
+}
+
+func (e extremes) merge(other extremes) extremes {
+	return extremes{
+		low:   minNotZero(e.low, other.low),
+		high:  math.Max(e.high, other.high),
+		vlow:  minNotZero(e.vlow, other.vlow),
+		vhigh: math.Max(e.vhigh, other.vhigh)}
+}
+
+// rangeTree is a segment tree answering the extremes of any range of the indexed data points in O(log n).
+// It's maintained incrementally when data points are appended, inserted or updated.
+type rangeTree struct {
+	leaves int        // number of leaves, a power of 2
+	nodes  []extremes // nodes[1] is the root, leaves start at nodes[leaves]
+}
+
+// newRangeTree builds the tree of items in O(n)
+func newRangeTree(items []*DataStock) *rangeTree {
+	tree := &rangeTree{leaves: 1}
+	for tree.leaves < len(items) {
+		tree.leaves *= 2
+	}
+	tree.nodes = make([]extremes, 2*tree.leaves)
+	for i := range tree.nodes {
+		tree.nodes[i] = noExtremes
+	}
+	tree.update(items, 0)
+	return tree
+}
+
+// update refreshes the leaves of items from the position from, and their parents.
+//
+// The tree is rebuilt if items do not fit anymore in its leaves.
+func (tree *rangeTree) update(items []*DataStock, from int) {
+	if len(items) > tree.leaves {
+		*tree = *newRangeTree(items)
+		return
+	}
+	if from >= len(items) {
+		return
+	}
+	lo, hi := tree.leaves+from, tree.leaves+len(items)-1
+	for i := lo; i <= hi; i++ {
+		tree.nodes[i] = makeExtremes(items[i-tree.leaves])
+	}
+	for lo > 1 {
+		lo, hi = lo/2, hi/2
+		for i := lo; i <= hi; i++ {
+			tree.nodes[i] = tree.nodes[2*i].merge(tree.nodes[2*i+1])
+		}
+	}
+}
+
+// query returns the extremes of the items in the range [from, to)
+func (tree *rangeTree) query(from int, to int) extremes {
+	result := noExtremes
+	for lo, hi := from+tree.leaves, to+tree.leaves; lo < hi; lo, hi = lo/2, hi/2 {
+		if lo&1 == 1 {
+			result = result.merge(tree.nodes[lo])
+			lo++
+		}
+		if hi&1 == 1 {
+			hi--
+			result = result.merge(tree.nodes[hi])
+		}
+	}
+	return result
+}
@@ -0,0 +1,117 @@
+package stockchart
+
+import (
+	"fmt"
+	"time"
+
+	timeline "github.com/larry868/timeline/v2"
+)
+
+// Calendar defines calendar buckets for resampling
+type Calendar int
+
+const (
+	CalendarNone  Calendar = iota // fixed buckets of the precision
+	CalendarDay                   // a bucket per day, from midnight to midnight
+	CalendarWeek                  // a bucket per week, from monday midnight
+	CalendarMonth                 // a bucket per month, from the first day at midnight
+)
+
+// Alignment defines the boundaries of the buckets of a resampled DataList
+type Alignment struct {
+	Calendar Calendar       // calendar buckets, CalendarNone for fixed buckets of the precision
+	Location *time.Location // the time zone of the day boundaries, UTC if nil
+}
+
+// bucket returns the time slice of the bucket starting at or before t
+func (alignment Alignment) bucket(t time.Time, precision time.Duration) (bucket timeline.TimeSlice) {
+	loc := alignment.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	lt := t.In(loc)
+	midnight := time.Date(lt.Year(), lt.Month(), lt.Day(), 0, 0, 0, 0, loc)
+	switch alignment.Calendar {
+	case CalendarDay:
+		bucket.From = midnight
+		bucket.To = midnight.AddDate(0, 0, 1)
+	case CalendarWeek:
+		bucket.From = midnight.AddDate(0, 0, -(int(lt.Weekday())+6)%7)
+		bucket.To = bucket.From.AddDate(0, 0, 7)
+	case CalendarMonth:
+		bucket.From = time.Date(lt.Year(), lt.Month(), 1, 0, 0, 0, 0, loc)
+		bucket.To = bucket.From.AddDate(0, 1, 0)
+	default:
+		if precision >= 24*time.Hour {
+			// buckets of whole days, aligned on the days elapsed since the 1st January 1970
+			days := int(precision / (24 * time.Hour))
+			elapsed := int(time.Date(lt.Year(), lt.Month(), lt.Day(), 0, 0, 0, 0, time.UTC).Unix() / (24 * 3600))
+			bucket.From = midnight.AddDate(0, 0, -(elapsed % days))
+			bucket.To = bucket.From.AddDate(0, 0, days)
+			break
+		}
+		// buckets are aligned on the local midnight, and cut at the next one on days lasting 23 or 25 hours
+		bucket.From = midnight.Add(t.Sub(midnight) / precision * precision)
+		bucket.To = bucket.From.Add(precision)
+		if next := midnight.AddDate(0, 0, 1); bucket.To.After(next) {
+			bucket.To = next
+		}
+	}
+	bucket.From = bucket.From.In(t.Location())
+	bucket.To = bucket.To.In(t.Location())
+	return bucket
+}
+
+// Resample returns a new DataList aggregating the data points of dl into coarser buckets.
+//
+// With CalendarNone, buckets last precision from the midnight of alignment.Location, if precision divides a day.
+// On days lasting 23 or 25 hours, the buckets after the time change are shifted by an hour on the wall clock, and the last bucket of the day ends at the next midnight.
+// A precision of several days makes buckets of as many days of alignment.Location, aligned on the days elapsed since the 1st January 1970.
+// With a calendar alignment, buckets are days, weeks or months of alignment.Location, so a day lasts 23 or 25 hours when the time zone changes its offset,
+// and precision is only the Precision of the new DataList.
+//
+// A data point belongs to the bucket of its start, so precision should be a multiple of the precision of dl.
+// Buckets get the Open of their first data point, the Close of the last one, the highest High, the lowest Low and the sum of Volumes.
+// Buckets without any data point are skipped. The last bucket covers its whole duration, even if the data are not complete yet.
+//
+// Returns an error if precision is finer than the precision of dl, if precision lasts a day or more without being whole days with CalendarNone,
+// or if dl is not chronological.
+func (dl DataList) Resample(precision time.Duration, alignment Alignment) (DataList, error) {
+	resampled := DataList{Name: dl.Name, Precision: precision}
+	if precision <= 0 || precision < dl.Precision {
+		return resampled, fmt.Errorf("unable to resample %q at %s: must be coarser than %s", dl.Name, precision, dl.Precision)
+	}
+	if alignment.Calendar == CalendarNone && precision >= 24*time.Hour && precision%(24*time.Hour) != 0 {
+		return resampled, fmt.Errorf("unable to resample %q at %s: must be whole days", dl.Name, precision)
+	}
+
+	var bucket *DataStock
+	for _, item := range dl.window(nil) {
+		ts := alignment.bucket(item.From, precision)
+		if bucket != nil && ts.From.Before(bucket.From) {
+			return DataList{Name: dl.Name, Precision: precision}, fmt.Errorf("unable to resample %q: not chronological at %s", dl.Name, item.From)
+		}
+		if bucket != nil && ts.From.Equal(bucket.From) {
+			bucket.Close = item.Close
+			bucket.High = max(bucket.High, item.High)
+			bucket.Low = minNotZero(bucket.Low, item.Low)
+			bucket.Volume += item.Volume
+			continue
+		}
+		// the previous bucket is complete
+		if bucket != nil {
+			resampled.Append(bucket)
+		}
+		bucket = &DataStock{
+			TimeSlice: ts,
+			Open:      item.Open,
+			Low:       item.Low,
+			High:      item.High,
+			Close:     item.Close,
+			Volume:    item.Volume}
+	}
+	if bucket != nil {
+		resampled.Append(bucket)
+	}
+	return resampled, nil
+}
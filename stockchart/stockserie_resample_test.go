@@ -0,0 +1,189 @@
+package stockchart
+
+import (
+	"testing"
+	"time"
+
+	timeline "github.com/larry868/timeline/v2"
+)
+
+func TestResample(t *testing.T) {
+	series := testSeriesPrecision(60, time.Minute)
+	resampled, err := series.Resample(5*time.Minute, Alignment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resampled.Size() != 12 || resampled.Precision != 5*time.Minute || resampled.Name != series.Name {
+		t.Fatalf("Resample fails: get %s", resampled)
+	}
+
+	// compare with the aggregation of every 5 data points
+	items := series.window(nil)
+	i := 0
+	for bucket := resampled.Tail; bucket != nil; bucket = bucket.Next {
+		group := items[5*i : 5*i+5]
+		want := DataStock{Open: group[0].Open, Close: group[4].Close, Low: group[0].Low}
+		want.From, want.To = group[0].From, group[4].To
+		for _, item := range group {
+			want.High = max(want.High, item.High)
+			want.Low = min(want.Low, item.Low)
+			want.Volume += item.Volume
+		}
+		if bucket.Open != want.Open || bucket.Close != want.Close || bucket.High != want.High || bucket.Low != want.Low || bucket.Volume != want.Volume ||
+			!bucket.From.Equal(want.From) || !bucket.To.Equal(want.To) {
+			t.Errorf("Resample fails at %d: want %s, get %s", i, &want, bucket)
+		}
+		i++
+	}
+	if get := resampled.DataRange(nil, 0); get.Low() != series.DataRange(nil, 0).Low() || get.High() != series.DataRange(nil, 0).High() {
+		t.Errorf("Resample fails: want the datarange %s, get %s", series.DataRange(nil, 0), get)
+	}
+
+	// finer precision
+	if _, err := resampled.Resample(time.Minute, Alignment{}); err == nil {
+		t.Errorf("Resample fails: want an error for a finer precision")
+	}
+}
+
+func TestResampleCalendar(t *testing.T) {
+	// 70 days of hourly candles from Monday, January 1st 2024 UTC
+	series := testSeries(70 * 24)
+
+	daily, _ := series.Resample(24*time.Hour, Alignment{Calendar: CalendarDay})
+	if daily.Size() != 70 || daily.Tail.Volume != sumVolumes(series.window(nil)[:24]) {
+		t.Errorf("Resample daily fails: get %s", daily)
+	}
+
+	// days start at midnight in the time zone, 5 hours after the UTC midnight
+	est := time.FixedZone("EST", -5*3600)
+	daily, _ = series.Resample(24*time.Hour, Alignment{Calendar: CalendarDay, Location: est})
+	if daily.Size() != 71 || daily.Tail.Duration().Duration != 24*time.Hour || !daily.Tail.Next.From.Equal(series.Tail.From.Add(5*time.Hour)) {
+		t.Errorf("Resample daily EST fails: get %s, second day %s", daily, daily.Tail.Next)
+	}
+	if get := sumVolumes(daily.window(nil)); get != sumVolumes(series.window(nil)) {
+		t.Errorf("Resample daily EST fails: volumes differ %v", get)
+	}
+
+	// fixed buckets are aligned on the midnight of the time zone too
+	fourhours, _ := series.Resample(4*time.Hour, Alignment{Location: est})
+	if from := fourhours.Tail.Next.From.In(est); from.Hour()%4 != 0 || from.Minute() != 0 {
+		t.Errorf("Resample 4h EST fails: get %s", from)
+	}
+
+	weekly, _ := series.Resample(7*24*time.Hour, Alignment{Calendar: CalendarWeek})
+	if weekly.Size() != 10 || weekly.Tail.From.Weekday() != time.Monday {
+		t.Errorf("Resample weekly fails: get %s", weekly)
+	}
+	weekly, _ = series.Resample(7*24*time.Hour, Alignment{Calendar: CalendarWeek, Location: est})
+	if weekly.Size() != 11 || weekly.Tail.Next.From.In(est).Weekday() != time.Monday || weekly.Tail.Next.From.In(est).Hour() != 0 {
+		t.Errorf("Resample weekly EST fails: get %s", weekly)
+	}
+
+	monthly, _ := series.Resample(30*24*time.Hour, Alignment{Calendar: CalendarMonth})
+	if monthly.Size() != 3 || monthly.Tail.Duration().Duration != 31*24*time.Hour || monthly.Tail.Next.Duration().Duration != 29*24*time.Hour {
+		t.Errorf("Resample monthly fails: get %s", monthly)
+	}
+}
+
+func TestResampleDST(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip("no time zone database")
+	}
+	// the 31st March 2024 lasts 23 hours in Paris
+	series := testSeries(100 * 24)
+	daily, _ := series.Resample(24*time.Hour, Alignment{Calendar: CalendarDay, Location: paris})
+	dst := time.Date(2024, 3, 31, 0, 0, 0, 0, paris)
+	if daily.GetDataAt(dst.Add(time.Hour)) == nil {
+		t.Fatalf("Resample DST fails: missing the 31st March")
+	}
+	for day := daily.Tail; day != nil; day = day.Next {
+		if from := day.From.In(paris); from.Hour() != 0 {
+			t.Errorf("Resample DST fails: day starting at %s", from)
+		}
+		if day.From.Equal(dst) && day.Duration().Duration != 23*time.Hour {
+			t.Errorf("Resample DST fails: get %s", day)
+		}
+	}
+}
+
+func TestResampleDSTFixed(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip("no time zone database")
+	}
+	// hourly data over the 31st March and the 27th October 2024, lasting 23 and 25 hours in Paris
+	for _, start := range []time.Time{time.Date(2024, 3, 30, 0, 0, 0, 0, time.UTC), time.Date(2024, 10, 26, 0, 0, 0, 0, time.UTC)} {
+		series := DataList{Name: "TEST", Precision: time.Hour}
+		for i := 0; i < 72; i++ {
+			series.Append(&DataStock{TimeSlice: timeline.MakeTimeSlice(start.Add(time.Duration(i)*time.Hour), time.Hour), Open: 1, High: 1, Low: 1, Close: 1, Volume: 1})
+		}
+		resampled, err := series.Resample(4*time.Hour, Alignment{Location: paris})
+		if err != nil {
+			t.Fatalf("Resample DST from %s fails: %v", start, err)
+		}
+		if get := sumVolumes(resampled.window(nil)); get != 72 {
+			t.Errorf("Resample DST from %s fails: want the volume 72, get %v", start, get)
+		}
+		for bucket := resampled.Tail; bucket != nil; bucket = bucket.Next {
+			if next := bucket.Next; next != nil && next.From.Before(bucket.To) {
+				t.Errorf("Resample DST from %s fails: %s overlaps %s", start, bucket, next)
+			}
+			if from := bucket.From.In(paris); from.Hour() == 0 && from.Minute() == 0 {
+				continue
+			}
+			if prev := bucket.Prev; prev != nil && prev.From.In(paris).Day() == bucket.From.In(paris).Day() && bucket.From.Sub(prev.From) != 4*time.Hour {
+				t.Errorf("Resample DST from %s fails: %s after %s", start, bucket, prev)
+			}
+		}
+	}
+}
+
+func TestResampleDays(t *testing.T) {
+	// 20 days of hourly candles from Monday, January 1st 2024 UTC, 19723 days after the 1st January 1970
+	series := testSeries(20 * 24)
+	twodays, err := series.Resample(48*time.Hour, Alignment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if twodays.Size() != 11 || !twodays.Tail.From.Equal(time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)) || twodays.Precision != 48*time.Hour || sumVolumes(twodays.window(nil)) != sumVolumes(series.window(nil)) {
+		t.Fatalf("Resample 2 days fails: get %s", twodays)
+	}
+	for bucket := twodays.Tail; bucket != nil; bucket = bucket.Next {
+		if bucket.Duration().Duration != 48*time.Hour || bucket.From.Hour() != 0 {
+			t.Errorf("Resample 2 days fails: get %s", bucket)
+		}
+	}
+
+	// buckets keep the same alignment whatever the first data point
+	threedays, _ := series.Resample(72*time.Hour, Alignment{})
+	later, _ := DataList{Name: "TEST", Precision: time.Hour, Tail: series.window(nil)[24], Head: series.Head}.Resample(72*time.Hour, Alignment{})
+	if !threedays.Tail.From.Equal(time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)) || !later.Tail.From.Equal(threedays.Tail.From) {
+		t.Errorf("Resample 3 days fails: get %s and %s", threedays.Tail, later.Tail)
+	}
+
+	// whole days of the time zone, lasting 23 or 25 hours over a time change
+	if paris, err := time.LoadLocation("Europe/Paris"); err == nil {
+		series := testSeries(100 * 24)
+		weekly, _ := series.Resample(7*24*time.Hour, Alignment{Location: paris})
+		for bucket := weekly.Tail.Next; bucket != nil; bucket = bucket.Next {
+			if from := bucket.From.In(paris); from.Hour() != 0 || !bucket.From.Equal(bucket.Prev.To) || from.Weekday() != weekly.Tail.Next.From.In(paris).Weekday() {
+				t.Errorf("Resample 7 days in Paris fails: get %s", bucket)
+			}
+		}
+	}
+
+	if _, err := series.Resample(36*time.Hour, Alignment{}); err == nil {
+		t.Errorf("Resample 36h fails: want an error")
+	}
+	if _, err := series.Resample(36*time.Hour, Alignment{Calendar: CalendarDay}); err != nil {
+		t.Errorf("Resample 36h with a calendar fails: %v", err)
+	}
+}
+
+func sumVolumes(items []*DataStock) (sum float64) {
+	for _, item := range items {
+		sum += item.Volume
+	}
+	return sum
+}
@@ -0,0 +1,220 @@
+package stockchart
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	timeline "github.com/larry868/timeline/v2"
+)
+
+// scanDataAt is the lookup by time following the links, the reference for the indexed lookup
+func scanDataAt(dl DataList, t time.Time) *DataStock {
+	for item := dl.Tail; item != nil; item = item.Next {
+		if !t.Before(item.From) && !t.After(item.To) {
+			return item
+		}
+	}
+	return nil
+}
+
+func TestDataListGetDataAt(t *testing.T) {
+	series := testSeries(100)
+	if series.Size() != 100 {
+		t.Fatalf("Size fails: want 100, get %d", series.Size())
+	}
+	start := series.Tail.From
+	for d := -time.Hour; d <= 101*time.Hour; d += 17 * time.Minute {
+		at := start.Add(d)
+		if want, get := scanDataAt(series, at), series.GetDataAt(at); want != get {
+			t.Errorf("GetDataAt %s fails: want %s, get %s", at, want, get)
+		}
+	}
+}
+
+func TestDataListInsert(t *testing.T) {
+	full := testSeries(30)
+	var items []*DataStock
+	for item := full.Tail; item != nil; item = item.Next {
+		items = append(items, item)
+	}
+
+	// insert every third items first, then the others
+	var series DataList
+	for pass := 0; pass < 3; pass++ {
+		for i := pass; i < len(items); i += 3 {
+			item := *items[i]
+			item.Next, item.Prev = nil, nil
+			series.Insert(&item)
+		}
+	}
+	if series.Size() != 30 || !series.index.sorted {
+		t.Fatalf("Insert fails: want 30 sorted items, get %d sorted:%v", series.Size(), series.index.sorted)
+	}
+	i := 0
+	for item := series.Tail; item != nil; item = item.Next {
+		if series.index.items[i] != item || !item.From.Equal(items[i].From) {
+			t.Fatalf("Insert fails at %d: index and links differ", i)
+		}
+		i++
+	}
+
+	// a duplicate is rejected
+	dup := *items[10]
+	dup.Next, dup.Prev = nil, nil
+	series.Insert(&dup)
+	if series.Size() != 30 || series.GetDataAt(items[10].Middle()) == &dup {
+		t.Errorf("Insert duplicate fails: get size %d", series.Size())
+	}
+}
+
+func TestDataListWindow(t *testing.T) {
+	series := testSeries(48)
+	start := series.Tail.From
+
+	ts := timeline.MakeTimeSlice(start.Add(90*time.Minute), 3*time.Hour)
+	window := series.Window(ts)
+	if len(window) != 4 || !window[0].From.Equal(start.Add(time.Hour)) || !window[3].From.Equal(start.Add(4*time.Hour)) {
+		t.Errorf("Window fails: want 4 items from 01:00, get %v", window)
+	}
+
+	// boundaries are included
+	ts = timeline.MakeTimeSlice(start.Add(2*time.Hour), time.Hour)
+	if window := series.Window(ts); len(window) != 3 {
+		t.Errorf("Window boundaries fails: want 3 items, get %v", window)
+	}
+
+	// out of the series
+	ts = timeline.MakeTimeSlice(start.Add(-5*time.Hour), time.Hour)
+	if window := series.Window(ts); window != nil {
+		t.Errorf("Window out fails: want nil, get %v", window)
+	}
+
+	// unsorted series are scanned
+	unsorted := testSeries(10)
+	unsorted.Head.Prev.From, unsorted.Head.Prev.To = unsorted.Tail.From, unsorted.Tail.To
+	unsorted.Reindex()
+	ts = timeline.MakeTimeSlice(start, 30*time.Minute)
+	if window := unsorted.Window(ts); len(window) != 2 || window[1] != unsorted.Head.Prev {
+		t.Errorf("Window unsorted fails: get %v", window)
+	}
+}
+
+func TestDataListStaleIndex(t *testing.T) {
+	series := testSeries(10)
+
+	// a copy shares the index, appending to the original one does not break the copy
+	copied := series
+	ds := new(DataStock)
+	ds.TimeSlice = timeline.MakeTimeSlice(series.Head.To, time.Hour)
+	series.Append(ds)
+	if series.Size() != 11 || copied.Size() != 10 {
+		t.Errorf("copy fails: want 11 and 10 items, get %d and %d", series.Size(), copied.Size())
+	}
+
+	// removing the head by hand
+	series.Head = series.Head.Prev
+	series.Head.Next = nil
+	if series.Size() != 10 || series.GetDataAt(ds.Middle()) != nil {
+		t.Errorf("stale index fails: get size %d", series.Size())
+	}
+	series.Reindex()
+	if !series.index.isValidFor(series) || series.Size() != 10 {
+		t.Errorf("Reindex fails")
+	}
+}
+
+func TestDataListDataRange(t *testing.T) {
+	series := testSeries(48)
+	start := series.Tail.From
+	ts := timeline.MakeTimeSlice(start.Add(3*time.Hour), 6*time.Hour)
+
+	var low, high, vlow, vhigh float64
+	for item := series.Tail; item != nil; item = item.Next {
+		if (ts.WhereIs(item.From)|ts.WhereIs(item.To))&timeline.TS_IN > 0 {
+			if low == 0 || item.Low < low {
+				low = item.Low
+			}
+			high = max(high, item.High)
+			if vlow == 0 || item.Volume < vlow {
+				vlow = item.Volume
+			}
+			vhigh = max(vhigh, item.Volume)
+		}
+	}
+	if dr := series.DataRange(&ts, 0); dr.Low() != low || dr.High() != high {
+		t.Errorf("DataRange fails: want %v-%v, get %s", low, high, dr)
+	}
+	if dr := series.VolumeDataRange(&ts, 0); dr.Low() != vlow || dr.High() != vhigh {
+		t.Errorf("VolumeDataRange fails: want %v-%v, get %s", vlow, vhigh, dr)
+	}
+}
+
+func TestDataListRangeTree(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newData := func(i int) *DataStock {
+		ds := new(DataStock)
+		ds.TimeSlice = timeline.MakeTimeSlice(start.Add(time.Duration(i)*time.Minute), time.Minute)
+		ds.Low = float64(rnd.Intn(100))
+		ds.High = ds.Low + float64(rnd.Intn(10))
+		ds.Volume = float64(rnd.Intn(1000))
+		return ds
+	}
+
+	// append odd minutes, then insert even minutes at random
+	var series DataList
+	for i := 1; i < 300; i += 2 {
+		series.Append(newData(i))
+	}
+	for _, i := range rnd.Perm(150) {
+		series.Insert(newData(2 * i))
+	}
+	if series.Size() != 300 || !series.index.sorted {
+		t.Fatalf("building fails: size %d, sorted:%v", series.Size(), series.index.sorted)
+	}
+
+	for n := 0; n < 200; n++ {
+		from := start.Add(time.Duration(rnd.Intn(320)-10) * time.Minute)
+		ts := timeline.MakeTimeSlice(from, time.Duration(rnd.Intn(60))*time.Minute)
+		want := noExtremes
+		for item := series.Tail; item != nil; item = item.Next {
+			if !item.To.Before(ts.From) && !item.From.After(ts.To) {
+				want = want.merge(makeExtremes(item))
+			}
+		}
+		if get := series.extremes(&ts); get != want {
+			t.Fatalf("extremes %s fails: want %v, get %v", ts, want, get)
+		}
+	}
+}
+
+func BenchmarkDataListDataRange(b *testing.B) {
+	series := DataList{Precision: time.Minute}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 500000; i++ {
+		ds := new(DataStock)
+		ds.TimeSlice = timeline.MakeTimeSlice(start.Add(time.Duration(i)*time.Minute), time.Minute)
+		ds.Low, ds.High = float64(i%1000+1), float64(i%1000+10)
+		series.Append(ds)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ts := timeline.MakeTimeSlice(start.Add(time.Duration(i%400000)*time.Minute), 24*time.Hour*30)
+		series.DataRange(&ts, 10)
+	}
+}
+
+func BenchmarkDataListGetDataAt(b *testing.B) {
+	series := DataList{Precision: time.Minute}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 500000; i++ {
+		ds := new(DataStock)
+		ds.TimeSlice = timeline.MakeTimeSlice(start.Add(time.Duration(i)*time.Minute), time.Minute)
+		series.Append(ds)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		series.GetDataAt(start.Add(time.Duration(i%500000) * time.Minute))
+	}
+}
@@ -0,0 +1,221 @@
+package stockchart
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// IssueKind is a kind of issue found in a DataList by Validate
+type IssueKind int
+
+const (
+	IssueBrokenLink     IssueKind = iota + 1 // the Prev and Next links are not symmetric, or do not lead from the Tail to the Head
+	IssueOutOfOrder                          // the data point starts before the previous one
+	IssueDuplicate                           // the data point starts at the same time as the previous one
+	IssueOverlap                             // the data point starts before the end of the previous one
+	IssueInvertedOHLC                        // the High is lower than the Open, the Close or the Low, or the Low is higher than the Open or the Close
+	IssueNegativeVolume                      // the volume is negative
+	IssueGap                                 // the data point starts after the end of the previous one, it's not an error
+)
+
+func (kind IssueKind) String() string {
+	switch kind {
+	case IssueBrokenLink:
+		return "broken link"
+	case IssueOutOfOrder:
+		return "out of order"
+	case IssueDuplicate:
+		return "duplicate"
+	case IssueOverlap:
+		return "overlap"
+	case IssueInvertedOHLC:
+		return "inverted OHLC"
+	case IssueNegativeVolume:
+		return "negative volume"
+	case IssueGap:
+		return "gap"
+	}
+	return "unknown"
+}
+
+// Issue is an issue found in a DataList by Validate
+type Issue struct {
+	Kind     IssueKind
+	Position int        // the position of the data point, from the tail, following the Next links
+	Data     *DataStock // the data point, nil if the issue concerns the list
+}
+
+func (issue Issue) String() string {
+	return fmt.Sprintf("%s at %d: %s", issue.Kind, issue.Position, issue.Data)
+}
+
+// ValidationReport lists the issues found in a DataList by Validate, in the order of the data points
+type ValidationReport struct {
+	Size   int     // the number of data points scanned
+	Issues []Issue // the issues found
+}
+
+// IsValid returns true if the report has no issue, gaps excepted
+func (report ValidationReport) IsValid() bool {
+	for _, issue := range report.Issues {
+		if issue.Kind != IssueGap {
+			return false
+		}
+	}
+	return true
+}
+
+// Count returns the number of issues of kind
+func (report ValidationReport) Count(kind IssueKind) (count int) {
+	for _, issue := range report.Issues {
+		if issue.Kind == kind {
+			count++
+		}
+	}
+	return count
+}
+
+func (report ValidationReport) String() string {
+	var str strings.Builder
+	fmt.Fprintf(&str, "%d data points, %d issues", report.Size, len(report.Issues))
+	for _, issue := range report.Issues {
+		str.WriteString("\n  " + issue.String())
+	}
+	return str.String()
+}
+
+// links returns the data points following the Next links from the Tail, then the ones only reachable from the Head with the Prev links.
+// Stops on cycles. brokenAt is the position of the first broken link, -1 if none.
+func (dl DataList) links() (items []*DataStock, brokenAt int) {
+	brokenAt = -1
+	visited := make(map[*DataStock]bool)
+	var prev *DataStock
+	for item := dl.Tail; item != nil && !visited[item]; item = item.Next {
+		if item.Prev != prev && brokenAt < 0 {
+			brokenAt = len(items)
+		}
+		visited[item] = true
+		items = append(items, item)
+		prev = item
+		if item == dl.Head {
+			break
+		}
+	}
+	if prev != dl.Head || (prev != nil && prev.Next != nil) {
+		if brokenAt < 0 {
+			brokenAt = len(items)
+		}
+		// collect the data points reachable from the head only
+		var tail []*DataStock
+		for item := dl.Head; item != nil && !visited[item]; item = item.Prev {
+			visited[item] = true
+			tail = append(tail, item)
+		}
+		for i := len(tail) - 1; i >= 0; i-- {
+			items = append(items, tail[i])
+		}
+	}
+	return items, brokenAt
+}
+
+// Validate checks the links and the data points of dl, and reports the issues found.
+// Gaps are reported but are not errors.
+func (dl DataList) Validate() (report ValidationReport) {
+	items, brokenAt := dl.links()
+	report.Size = len(items)
+	if brokenAt >= 0 {
+		var data *DataStock
+		if brokenAt < len(items) {
+			data = items[brokenAt]
+		}
+		report.Issues = append(report.Issues, Issue{Kind: IssueBrokenLink, Position: brokenAt, Data: data})
+	}
+
+	for i, item := range items {
+		if i > 0 {
+			prev := items[i-1]
+			switch {
+			case item.From.Before(prev.From):
+				report.Issues = append(report.Issues, Issue{Kind: IssueOutOfOrder, Position: i, Data: item})
+			case item.From.Equal(prev.From):
+				report.Issues = append(report.Issues, Issue{Kind: IssueDuplicate, Position: i, Data: item})
+			case item.From.Before(prev.To):
+				report.Issues = append(report.Issues, Issue{Kind: IssueOverlap, Position: i, Data: item})
+			case item.From.After(prev.To):
+				report.Issues = append(report.Issues, Issue{Kind: IssueGap, Position: i, Data: item})
+			}
+		}
+		if item.High < max(item.Open, item.Close, item.Low) || item.Low > min(item.Open, item.Close) {
+			report.Issues = append(report.Issues, Issue{Kind: IssueInvertedOHLC, Position: i, Data: item})
+		}
+		if item.Volume < 0 {
+			report.Issues = append(report.Issues, Issue{Kind: IssueNegativeVolume, Position: i, Data: item})
+		}
+	}
+	return report
+}
+
+// RepairPolicy defines how Repair fixes the issues of a DataList, policies can be combined
+type RepairPolicy uint
+
+const (
+	RepairOrder      RepairPolicy = 0b00000001 // sort the data points chronologically
+	RepairDuplicates RepairPolicy = 0b00000010 // keep only the last of data points starting at the same time, usually the most recent update
+	RepairOverlaps   RepairPolicy = 0b00000100 // drop the data points starting before the end of the previous one
+	RepairOHLC       RepairPolicy = 0b00001000 // extend the High and the Low to the Open and the Close
+	RepairVolume     RepairPolicy = 0b00010000 // set negative volumes to zero
+	DropInvalid      RepairPolicy = 0b00100000 // drop data points with inverted OHLC or negative volume, instead of fixing them
+
+	RepairAll = RepairOrder | RepairDuplicates | RepairOverlaps | RepairOHLC | RepairVolume
+)
+
+// Repair fixes the issues of dl according to policy, and returns the report of the issues found before repairing.
+//
+// The Prev and Next links are always rebuilt, from the data points reachable from the Tail and from the Head, and the index is rebuilt.
+// Issues not covered by policy remain, call Validate to check them.
+func (dl *DataList) Repair(policy RepairPolicy) ValidationReport {
+	report := dl.Validate()
+	items, _ := dl.links()
+
+	if policy&RepairOrder > 0 {
+		sort.SliceStable(items, func(i, j int) bool {
+			return items[i].From.Before(items[j].From)
+		})
+	}
+
+	repaired := make([]*DataStock, 0, len(items))
+	for _, item := range items {
+		inverted := item.High < max(item.Open, item.Close, item.Low) || item.Low > min(item.Open, item.Close)
+		if policy&DropInvalid > 0 && (inverted || item.Volume < 0) {
+			continue
+		}
+		if inverted && policy&RepairOHLC > 0 {
+			item.High = max(item.Open, item.Close, item.Low, item.High)
+			item.Low = min(item.Open, item.Close, item.Low, item.High)
+		}
+		if item.Volume < 0 && policy&RepairVolume > 0 {
+			item.Volume = 0
+		}
+
+		if n := len(repaired); n > 0 {
+			prev := repaired[n-1]
+			if item.From.Equal(prev.From) && policy&RepairDuplicates > 0 {
+				repaired[n-1] = item
+				continue
+			}
+			if item.From.After(prev.From) && item.From.Before(prev.To) && policy&RepairOverlaps > 0 {
+				continue
+			}
+		}
+		repaired = append(repaired, item)
+	}
+
+	// relink
+	*dl = DataList{Name: dl.Name, Precision: dl.Precision}
+	for _, item := range repaired {
+		item.Prev, item.Next = nil, nil
+		dl.Append(item)
+	}
+	return report
+}
@@ -0,0 +1,103 @@
+package stockchart
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	series := testSeries(10)
+	if report := series.Validate(); !report.IsValid() || len(report.Issues) != 0 || report.Size != 10 {
+		t.Fatalf("Validate fails: get %s", report)
+	}
+
+	items := series.window(nil)
+	items[1].High = items[1].Open - 1                    // inverted
+	items[2].Volume = -5                                 // negative volume
+	items[3].From = items[2].From                        // duplicate
+	items[4].From = items[4].From.Add(-30 * time.Minute) // overlap
+	items[6].From = items[6].From.Add(30 * time.Minute)  // gap
+	items[8].From = items[0].From                        // out of order
+	items[9].Prev = items[7]                             // broken link
+	report := series.Validate()
+	want := []IssueKind{IssueInvertedOHLC, IssueNegativeVolume, IssueDuplicate, IssueOverlap, IssueGap, IssueOutOfOrder, IssueBrokenLink}
+	for _, kind := range want {
+		if report.Count(kind) != 1 {
+			t.Errorf("Validate fails: want one %s, get %s", kind, report)
+		}
+	}
+	if len(report.Issues) != len(want) || report.IsValid() {
+		t.Errorf("Validate fails: get %s", report)
+	}
+
+	// gaps are not errors
+	if report := testSeries(3).Validate(); !report.IsValid() {
+		t.Errorf("Validate fails: get %s", report)
+	}
+}
+
+func TestValidateLinks(t *testing.T) {
+	// the links from the tail do not reach the head
+	series := testSeries(10)
+	items := series.window(nil)
+	items[4].Next = nil
+	report := series.Validate()
+	if report.Size != 10 || report.Count(IssueBrokenLink) != 1 || report.Issues[0].Position != 5 {
+		t.Errorf("Validate fails: get %s", report)
+	}
+
+	// a cycle
+	series = testSeries(10)
+	items = series.window(nil)
+	items[6].Next = items[2]
+	if report := series.Validate(); report.Count(IssueBrokenLink) != 1 || report.Size != 10 {
+		t.Errorf("Validate cycle fails: get %s", report)
+	}
+	series.Repair(0)
+	if report := series.Validate(); !report.IsValid() || series.Size() != 10 || !series.index.sorted {
+		t.Errorf("Repair links fails: get %s", report)
+	}
+}
+
+func TestRepair(t *testing.T) {
+	build := func() (DataList, []*DataStock) {
+		series := testSeries(10)
+		items := series.window(nil)
+		items[1].High = items[1].Open - 1
+		items[2].Volume = -5
+		items[3].From = items[2].From
+		items[4].From = items[4].From.Add(-30 * time.Minute)
+		items[8].From, items[8].To = items[0].From.Add(-time.Hour), items[0].From
+		return series, items
+	}
+
+	series, items := build()
+	before := series.Repair(RepairAll)
+	if len(before.Issues) != 6 {
+		t.Errorf("Repair fails: want the report before repairing, get %s", before)
+	}
+	if report := series.Validate(); !report.IsValid() {
+		t.Fatalf("Repair fails: get %s", report)
+	}
+	// the out of order one is the new tail, the first duplicate and the overlap are dropped
+	if series.Size() != 8 || series.Tail != items[8] || series.GetDataAt(items[3].Middle()) != items[3] {
+		t.Errorf("Repair fails: get %s", series)
+	}
+	if items[1].High != max(items[1].Open, items[1].Close) || items[2].Volume != 0 {
+		t.Errorf("Repair fails: OHLC %s, volume %s", items[1], items[2])
+	}
+
+	// drop invalid data points
+	series, items = build()
+	series.Repair(RepairAll | DropInvalid)
+	if series.Size() != 7 || series.GetDataAt(items[1].Middle()) != nil {
+		t.Errorf("Repair drop fails: get %s", series)
+	}
+
+	// without any policy, issues remain
+	series, _ = build()
+	series.Repair(0)
+	if report := series.Validate(); len(report.Issues) != 6 {
+		t.Errorf("Repair none fails: get %s", report)
+	}
+}